@@ -15,9 +15,23 @@ func newAddCmd(root *rootCmd) *addCmd {
 	cmd := &addCmd{}
 	cmd.Command = &cobra.Command{
 		Use:   "add path [path ...]",
-		Short: "Add one or more repositories to a local collection",
+		Short: "Add one or more repositories, projects or organizations to a local collection",
 		Example: `  # Add the repository https://github.com/myorg/repo1 to a local collection
-  ghere add myorg/repo1`,
+  ghere add myorg/repo1
+
+  # Add a repository hosted on a GitHub Enterprise Server instance. The host
+  # must be configured in the "hosts" section of the collection configuration.
+  ghere add github.example.com/myorg/repo1
+
+  # Add project number 3 of the organization myorg
+  ghere add myorg/projects/3
+
+  # Add project number 1, as linked to the repository myorg/repo1
+  ghere add myorg/repo1/projects/1
+
+  # Add the organization myorg, whose teams (and their membership) are to be
+  # fetched
+  ghere add myorg`,
 		Args: cobra.MinimumNArgs(1),
 		RunE: func(c *cobra.Command, args []string) error {
 			log := root.logger
@@ -28,6 +42,34 @@ func newAddCmd(root *rootCmd) *addCmd {
 				return err
 			}
 			for _, arg := range args {
+				if ghere.IsOrganizationPath(arg) {
+					_, err := coll.NewOrganization(arg)
+					if err != nil {
+						if e, ok := err.(*ghere.ErrOrganizationAlreadyExists); ok {
+							if !cmd.failOnExists {
+								log.Info("Organization already exists, skipping", "host", e.Host, "owner", e.Owner)
+								continue
+							}
+						}
+						log.Error("Failed to create organization", "err", err)
+						return err
+					}
+					continue
+				}
+				if ghere.IsProjectPath(arg) {
+					_, err := coll.NewProjectFromPath(arg)
+					if err != nil {
+						if e, ok := err.(*ghere.ErrProjectAlreadyExists); ok {
+							if !cmd.failOnExists {
+								log.Info("Project already exists, skipping", "path", e.Path)
+								continue
+							}
+						}
+						log.Error("Failed to create project", "err", err)
+						return err
+					}
+					continue
+				}
 				_, err := coll.NewFromPath(arg)
 				if err != nil {
 					if e, ok := err.(*ghere.ErrRepositoryAlreadyExists); ok {
@@ -48,6 +90,6 @@ func newAddCmd(root *rootCmd) *addCmd {
 			return nil
 		},
 	}
-	cmd.Flags().BoolVar(&cmd.failOnExists, "fail-on-exists", false, "exit with an error if a repository already exists instead of simply providing a warning")
+	cmd.Flags().BoolVar(&cmd.failOnExists, "fail-on-exists", false, "exit with an error if a repository, project or organization already exists instead of simply providing a warning")
 	return cmd
 }
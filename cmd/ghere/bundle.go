@@ -0,0 +1,64 @@
+package main
+
+import (
+	"github.com/informalsystems/ghere/pkg/ghere"
+	"github.com/spf13/cobra"
+)
+
+type bundleCmd struct {
+	*cobra.Command
+
+	outputDir    string
+	baseManifest string
+	pretty       bool
+}
+
+func newBundleCmd(root *rootCmd) *bundleCmd {
+	cmd := &bundleCmd{}
+	cmd.Command = &cobra.Command{
+		Use:   "bundle",
+		Short: "Export the code of a local collection's repositories as Git bundles",
+		Example: `  # Export all refs of all repositories' code as Git bundles, along with a
+  # manifest.json file describing the bundles
+  ghere bundle --output /path/to/bundles/full
+
+  # Only export objects not already contained in a previous export
+  ghere bundle --output /path/to/bundles/incr1 \
+    --since /path/to/bundles/full/manifest.json`,
+		RunE: func(c *cobra.Command, args []string) error {
+			log := root.logger
+			log.Info("Loading local collection", "path", root.configFile)
+			coll, err := ghere.LoadOrCreateLocalCollection(root.configFile)
+			if err != nil {
+				log.Error("Failed to load collection", "err", err)
+				return err
+			}
+			cfg := &ghere.BundleConfig{
+				OutputDir:  cmd.outputDir,
+				PrettyJSON: cmd.pretty,
+			}
+			if len(cmd.baseManifest) > 0 {
+				log.Info("Loading base bundle manifest", "path", cmd.baseManifest)
+				cfg.BaseManifest, err = ghere.LoadBundleManifest(cmd.baseManifest)
+				if err != nil {
+					log.Error("Failed to load base bundle manifest", "err", err)
+					return err
+				}
+			}
+			manifest, err := coll.Bundle(c.Context(), cfg, log)
+			if err != nil {
+				log.Error("Failed to export bundles", "err", err)
+				return err
+			}
+			log.Info("Success", "bundles", len(manifest.Bundles), "outputDir", cmd.outputDir)
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&cmd.outputDir, "output", "o", "", "directory into which to write the bundles and their manifest")
+	cmd.Flags().StringVar(&cmd.baseManifest, "since", "", "path to a previous bundle manifest, relative to which incremental bundles must be produced")
+	cmd.Flags().BoolVar(&cmd.pretty, "pretty", false, "output a pretty JSON manifest instead of compact JSON")
+	if err := cmd.MarkFlagRequired("output"); err != nil {
+		panic(err)
+	}
+	return cmd
+}
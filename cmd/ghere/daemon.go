@@ -0,0 +1,100 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/informalsystems/ghere/pkg/ghere"
+	"github.com/spf13/cobra"
+)
+
+type daemonCmd struct {
+	*cobra.Command
+	fetchOptions
+
+	interval       time.Duration
+	jitter         time.Duration
+	maxConcurrent  int
+	reloadInterval time.Duration
+	statusAddr     string
+}
+
+func newDaemonCmd(root *rootCmd) *daemonCmd {
+	cmd := &daemonCmd{}
+	cmd.Command = &cobra.Command{
+		Use:   "daemon",
+		Short: "Periodically fetch a local collection's repositories from GitHub",
+		Long: `Periodically fetch a local collection's repositories from GitHub.
+
+The interval between fetches (and a random jitter to add to each fetch's start
+time) can be configured for the whole collection and/or for individual
+repositories in the collection's configuration file, e.g.:
+
+  {
+    "schedule": { "interval": "6h", "jitter": "15m" },
+    "repositories": [
+      { "owner": "org", "name": "repo", "schedule": { "interval": "30m" } }
+    ]
+  }
+
+Changes to the configuration file (including to its GitHub hosts) are picked
+up without restarting the daemon.`,
+		Example: `  # Fetch all repositories hourly, serving the status of each repository's
+  # fetches at http://localhost:9090/status, along with Prometheus metrics at
+  # http://localhost:9090/metrics
+  ghere daemon --interval 1h --status-addr localhost:9090`,
+		RunE: func(c *cobra.Command, args []string) error {
+			log := root.logger
+
+			log.Info("Loading local collection", "path", root.configFile)
+			coll, err := ghere.LoadOrCreateLocalCollection(root.configFile)
+			if err != nil {
+				log.Error("Failed to load collection", "err", err)
+				return err
+			}
+			var metrics *ghere.Metrics
+			if len(cmd.statusAddr) > 0 {
+				metrics = ghere.NewMetrics()
+			}
+			cfg, err := cmd.fetchConfig(c.Context(), coll, metrics, log)
+			if err != nil {
+				return err
+			}
+			daemon := ghere.NewDaemon(root.configFile, &ghere.DaemonConfig{
+				Fetch: cfg,
+				Schedule: ghere.Schedule{
+					Interval: ghere.Duration(cmd.interval),
+					Jitter:   ghere.Duration(cmd.jitter),
+				},
+				MaxConcurrentFetches: cmd.maxConcurrent,
+				ReloadInterval:       cmd.reloadInterval,
+				NewFetchConfig: func(coll *ghere.LocalCollection) (*ghere.FetchConfig, error) {
+					return cmd.fetchConfig(c.Context(), coll, metrics, log)
+				},
+			}, log)
+			if len(cmd.statusAddr) > 0 {
+				mux := http.NewServeMux()
+				mux.Handle("/status", daemon)
+				mux.Handle("/metrics", metrics)
+				stopServer, err := serveHTTP(cmd.statusAddr, mux, log)
+				if err != nil {
+					log.Error("Failed to start status server", "addr", cmd.statusAddr, "err", err)
+					return err
+				}
+				defer stopServer()
+			}
+			if err := daemon.Run(c.Context()); err != nil {
+				log.Error("Daemon failed", "err", err)
+				return err
+			}
+			return nil
+		},
+	}
+	cmd.addFlags(cmd.Command)
+	cmd.Flags().DurationVar(&cmd.interval, "interval", ghere.DEFAULT_SYNC_INTERVAL, "default interval between fetches of each repository (overridden by the schedule in the collection configuration)")
+	cmd.Flags().DurationVar(&cmd.jitter, "jitter", 0, "default maximum random delay to add to each scheduled fetch")
+	cmd.Flags().IntVar(&cmd.maxConcurrent, "max-concurrent", 1, "maximum number of repositories to fetch concurrently")
+	cmd.Flags().DurationVar(&cmd.reloadInterval, "reload-interval", ghere.DEFAULT_CONFIG_RELOAD_INTERVAL, "how often to check the collection configuration file for changes")
+	cmd.Flags().StringVar(&cmd.statusAddr, "status-addr", "", "serve the status of each repository's fetches at /status, and Prometheus metrics at /metrics, on this address (e.g. localhost:9090)")
+	return cmd
+}
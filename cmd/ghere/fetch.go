@@ -1,11 +1,15 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/google/go-github/v48/github"
 	"github.com/informalsystems/ghere/pkg/ghere"
@@ -13,22 +17,64 @@ import (
 	"golang.org/x/oauth2"
 )
 
-type fetchCmd struct {
-	*cobra.Command
+const (
+	GITHUB_TOKEN_ENVVAR  string = "GITHUB_TOKEN"
+	GITHUB_TOKENS_ENVVAR string = "GITHUB_TOKENS"
+)
 
+// fetchOptions captures the options common to all commands that fetch
+// repositories from GitHub.
+type fetchOptions struct {
 	privKeyFile    string
+	ownerPrivKeys  map[string]string
+	sshAgent       bool
+	knownHosts     []string
 	githubUsername string
+	credHelper     bool
+	appID          int64
+	appInstallID   int64
+	appPrivKeyFile string
+	tokenFile      string
+	baseURL        string
+	uploadURL      string
 	reqRetries     uint
 	reqTimeout     uint
+	retryBackoff   float64
+	retryMaxWait   uint
 	gitTimeout     uint
 	pretty         bool
-	failFast       bool
+	submodules     bool
+	include        []string
+	only           []string
+	skip           []string
+	useEvents      bool
+	reactions      bool
+
+	commentReactionsInterval uint
+
+	actionsLogs             bool
+	actionsLogsMaxSize      int64
+	actionsArtifacts        bool
+	actionsArtifactsMaxSize int64
+}
+
+type fetchCmd struct {
+	*cobra.Command
+	fetchOptions
+
+	failFast    bool
+	restart     bool
+	dryRun      bool
+	reportFile  string
+	exitCodes   map[string]int
+	metricsAddr string
+	metricsFile string
 }
 
 func newFetchCmd(root *rootCmd) *fetchCmd {
 	cmd := &fetchCmd{}
 	cmd.Command = &cobra.Command{
-		Use:   "fetch",
+		Use:   "fetch [owner/name...]",
 		Short: "Fetch a local collection's repositories from GitHub",
 		Example: `  # Set your GitHub personal access token to raise rate limits
   # See https://docs.github.com/en/rest/overview/resources-in-the-rest-api#rate-limiting
@@ -40,14 +86,39 @@ func newFetchCmd(root *rootCmd) *fetchCmd {
    export SSH_PRIVKEY_PASSWORD="..."
 
   # Fetch all repositories
-  ghere fetch`,
+  ghere fetch
+
+  # Rotate between multiple personal access tokens to avoid hitting rate
+  # limits when fetching very large organizations (again, note the space
+  # before the command)
+   export GITHUB_TOKENS="token1,token2,token3"
+  ghere fetch
+
+  # Only fetch the issues and labels of specific repositories
+  ghere fetch --only issues,labels org/repo1 org/repo2
+
+  # Fetch everything except code
+  ghere fetch --skip code
+
+  # See what would be fetched, and roughly how many API calls it would take,
+  # without fetching anything
+  ghere fetch --dry-run
+
+  # Expose Prometheus metrics while fetching
+  ghere fetch --metrics-addr localhost:9090
+
+  # Fetch all repositories, authenticating as a GitHub App installation
+  ghere fetch --app-id 1234 --app-installation-id 5678 \
+    --app-private-key /path/to/app.private-key.pem
+
+  # Fetch all repositories, authenticating via your SSH agent, using a specific
+  # key for repositories belonging to myorg
+  ghere fetch --ssh-agent --owner-priv-key myorg=$HOME/.ssh/myorg_ed25519`,
 		RunE: func(c *cobra.Command, args []string) error {
 			log := root.logger
 
-			accessToken := os.Getenv("GITHUB_TOKEN")
-			if len(accessToken) == 0 {
-				log.Error("To fetch from GitHub, you must set the GITHUB_TOKEN environment variable")
-				return errors.New("missing GITHUB_TOKEN environment variable")
+			if err := cmd.validateExitCodes(); err != nil {
+				return err
 			}
 
 			log.Info("Loading local collection", "path", root.configFile)
@@ -56,41 +127,421 @@ func newFetchCmd(root *rootCmd) *fetchCmd {
 				log.Error("Failed to load collection", "err", err)
 				return err
 			}
-			ts := oauth2.StaticTokenSource(
-				&oauth2.Token{
-					AccessToken: accessToken,
-				},
-			)
-			tc := oauth2.NewClient(c.Context(), ts)
-			client := github.NewClient(tc)
-			reqRetries := int(cmd.reqRetries)
-			reqTimeout := time.Duration(cmd.reqTimeout) * time.Second
-			cfg := &ghere.FetchConfig{
-				Client:             ghere.NewGitHubClient(client, reqRetries, reqTimeout, log),
-				CredentialProvider: ghere.NewGitHubEnvVarCredentialProvider(cmd.privKeyFile, cmd.githubUsername),
-				RepoUpdater:        ghere.NewGitHubRepositoryUpdater(),
-				GitTimeout:         time.Duration(cmd.gitTimeout) * time.Second,
-				FailFast:           cmd.failFast,
-				PrettyJSON:         cmd.pretty,
+
+			var metrics *ghere.Metrics
+			if len(cmd.metricsAddr) > 0 || len(cmd.metricsFile) > 0 {
+				metrics = ghere.NewMetrics()
 			}
-			if err := coll.Fetch(c.Context(), cfg, log); err != nil {
-				log.Error("Failed to sync from GitHub", "err", err)
+			cfg, err := cmd.fetchConfig(c.Context(), coll, metrics, log)
+			if err != nil {
 				return err
 			}
+			cfg.FailFast = cmd.failFast
+			cfg.Restart = cmd.restart
+			cfg.Repositories = args
+			if cmd.dryRun {
+				return cmd.plan(c.Context(), coll, cfg, log)
+			}
+			if len(cmd.metricsAddr) > 0 {
+				stopMetricsServer, err := serveMetrics(cmd.metricsAddr, cfg.Metrics, log)
+				if err != nil {
+					log.Error("Failed to start metrics server", "addr", cmd.metricsAddr, "err", err)
+					return err
+				}
+				defer stopMetricsServer()
+			}
+			report, err := coll.Fetch(c.Context(), cfg, log)
+			if len(cmd.metricsFile) > 0 {
+				if e := cfg.Metrics.WriteTextfile(cmd.metricsFile); e != nil {
+					log.Error("Failed to write metrics file", "path", cmd.metricsFile, "err", e)
+					if err == nil {
+						return e
+					}
+				} else {
+					log.Info("Wrote metrics file", "path", cmd.metricsFile)
+				}
+			}
+			if len(cmd.reportFile) > 0 {
+				if e := writeFetchReport(cmd.reportFile, report); e != nil {
+					log.Error("Failed to write fetch report", "path", cmd.reportFile, "err", e)
+					if err == nil {
+						return e
+					}
+				} else {
+					log.Info("Wrote fetch report", "path", cmd.reportFile)
+				}
+			}
+			if err != nil {
+				var interrupted *ghere.ErrFetchInterrupted
+				if errors.As(err, &interrupted) {
+					log.Warn(
+						"Fetch interrupted; progress so far has been saved",
+						"completed", len(interrupted.Completed),
+						"partiallyFetched", interrupted.Interrupted,
+						"notFetched", strings.Join(interrupted.Remaining, ","),
+					)
+				} else {
+					log.Error("Failed to sync from GitHub", "err", err)
+				}
+				code := cmd.exitCode(report, err)
+				if code == 0 {
+					log.Warn("Ignoring fetch failures as per exit code policy")
+					return nil
+				}
+				return &exitError{code: code, err: err}
+			}
 			return nil
 		},
 	}
+	cmd.addFlags(cmd.Command)
+	cmd.Flags().BoolVar(&cmd.dryRun, "dry-run", false, "only report which resources would be fetched for each repository, along with estimated API calls and Git operations, without writing anything")
+	cmd.Flags().StringVar(&cmd.reportFile, "report", "", "write a machine-readable (JSON) report of the fetch to this file")
+	cmd.Flags().StringToIntVar(&cmd.exitCodes, "exit-codes", map[string]int{}, fmt.Sprintf("exit codes to use for specific types of repository fetch failures (any of: %s), e.g. not_found=0,rate_limit=3; the highest applicable exit code is used (defaults to 1, or 130 if interrupted)", strings.Join(ghere.FetchErrorTypes, ", ")))
+	cmd.Flags().StringVar(&cmd.metricsAddr, "metrics-addr", "", "serve Prometheus metrics at /metrics on this address (e.g. localhost:9090) for the duration of the fetch")
+	cmd.Flags().StringVar(&cmd.metricsFile, "metrics-textfile", "", "write Prometheus metrics to this file once the fetch completes (e.g. for the node exporter's textfile collector)")
+	cmd.Flags().BoolVar(&cmd.restart, "restart", false, "discard checkpoints left behind by interrupted fetches and fetch all listings from the start")
+	cmd.Flags().BoolVar(&cmd.failFast, "fail-fast", false, "fail the moment an error is encountered in fetching a repository instead of attempting to continue with the next one")
+	return cmd
+}
+
+// addFlags adds the flags for the common fetch options to the given command.
+func (o *fetchOptions) addFlags(cmd *cobra.Command) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		panic(fmt.Sprintf("failed to obtain user's home directory: %v", err))
 	}
 	defaultPrivKeyPath := filepath.Join(homeDir, ".ssh", "id_rsa")
-	cmd.Flags().StringVar(&cmd.privKeyFile, "priv-key", defaultPrivKeyPath, "path to the private key to use to clone Git repositories")
-	cmd.Flags().StringVar(&cmd.githubUsername, "login", "", "GitHub username to use when attempting to clone repositories via HTTPS")
-	cmd.Flags().UintVar(&cmd.reqRetries, "request-retries", 3, "how many times to retry requests to GitHub that timeout")
-	cmd.Flags().UintVar(&cmd.reqTimeout, "request-timeout", 20, "timeout, in seconds, for each HTTP request")
-	cmd.Flags().UintVar(&cmd.gitTimeout, "git-timeout", 120, "timeout, in seconds, for each Git repository clone/pull operation")
-	cmd.Flags().BoolVar(&cmd.pretty, "pretty", false, "output pretty JSON instead of compact JSON")
-	cmd.Flags().BoolVar(&cmd.failFast, "fail-fast", false, "fail the moment an error is encountered in fetching a repository instead of attempting to continue with the next one")
-	return cmd
+	cmd.Flags().StringVar(&o.privKeyFile, "priv-key", defaultPrivKeyPath, "path to the private key to use to clone Git repositories")
+	cmd.Flags().StringToStringVar(&o.ownerPrivKeys, "owner-priv-key", map[string]string{}, "path to the private key to use to clone Git repositories belonging to a specific user/organization (e.g. --owner-priv-key myorg=/path/to/id_ed25519)")
+	cmd.Flags().BoolVar(&o.sshAgent, "ssh-agent", false, "attempt to authenticate via the SSH agent specified by the SSH_AUTH_SOCK environment variable when cloning Git repositories")
+	cmd.Flags().StringSliceVar(&o.knownHosts, "known-hosts", []string{}, "known_hosts file(s) against which to verify SSH host keys (defaults to SSH_KNOWN_HOSTS or ~/.ssh/known_hosts)")
+	cmd.Flags().StringVar(&o.githubUsername, "login", "", "GitHub username to use when attempting to clone repositories via HTTPS (required when authenticating using GITHUB_PASSWORD, optional when authenticating using a token)")
+	cmd.Flags().BoolVar(&o.credHelper, "git-credential-helper", false, "fall back to obtaining HTTPS credentials from Git credential helpers if neither GITHUB_PASSWORD nor GITHUB_TOKEN are usable")
+	cmd.Flags().Int64Var(&o.appID, "app-id", 0, "authenticate as the GitHub App with this ID instead of using GITHUB_TOKEN (requires --app-installation-id and --app-private-key)")
+	cmd.Flags().Int64Var(&o.appInstallID, "app-installation-id", 0, "the ID of the GitHub App installation as which to authenticate")
+	cmd.Flags().StringVar(&o.appPrivKeyFile, "app-private-key", "", "path to the GitHub App's PEM-encoded private key")
+	cmd.Flags().StringVar(&o.tokenFile, "token-file", "", "path to a file containing multiple GitHub tokens (one per line) between which to rotate to avoid rate limiting")
+	cmd.Flags().StringVar(&o.baseURL, "base-url", "", "GitHub Enterprise Server API base URL (e.g. https://github.example.com/api/v3/) for repositories without a specific host; overrides base_url in the collection configuration")
+	cmd.Flags().StringVar(&o.uploadURL, "upload-url", "", "GitHub Enterprise Server upload URL (defaults to the base URL)")
+	cmd.Flags().UintVar(&o.reqRetries, "request-retries", 3, "maximum number of attempts (at least 1, including the initial attempt) for requests to GitHub that fail with retryable errors (timeouts, network errors, secondary rate limits and 502/503/504 responses)")
+	cmd.Flags().UintVar(&o.reqTimeout, "request-timeout", 20, "timeout, in seconds, for each HTTP request")
+	cmd.Flags().Float64Var(&o.retryBackoff, "retry-backoff", 1, "initial time, in seconds, to wait before retrying a failed request to GitHub, doubling with each subsequent retry")
+	cmd.Flags().UintVar(&o.retryMaxWait, "retry-max-backoff", 120, "maximum time, in seconds, to wait between retries of failed requests to GitHub")
+	cmd.Flags().UintVar(&o.gitTimeout, "git-timeout", 120, "timeout, in seconds, for each Git repository clone/pull operation")
+	cmd.Flags().BoolVar(&o.pretty, "pretty", false, "output pretty JSON instead of compact JSON")
+	cmd.Flags().BoolVar(&o.submodules, "submodules", false, "recursively initialize and update Git submodules when cloning/pulling repositories")
+	cmd.Flags().StringSliceVar(&o.include, "include", []string{}, "also fetch these types of resources, which are not fetched by default (e.g. discussions, actions, checks, timelines)")
+	cmd.Flags().StringSliceVar(&o.only, "only", []string{}, fmt.Sprintf("only fetch these types of resources (any of: %s)", strings.Join(ghere.AllResources, ", ")))
+	cmd.Flags().StringSliceVar(&o.skip, "skip", []string{}, "skip fetching these types of resources")
+	cmd.Flags().BoolVar(&o.useEvents, "events", false, "use each repository's events feed to only fetch the issues and pull requests that changed since the last fetch")
+	cmd.Flags().BoolVar(&o.reactions, "reactions", false, "fetch the individual reactions (and the users who reacted) to issues, issue comments, pull request review comments and releases (refreshing the reactions to comments requires listing all of each repository's comments, i.e. one API call per 100 comments, once per --comment-reactions-interval)")
+	cmd.Flags().UintVar(&o.commentReactionsInterval, "comment-reactions-interval", 24*60*60, "minimum time, in seconds, between refreshes of the reactions to each repository's comments when fetching reactions (0 refreshes them on every fetch)")
+	cmd.Flags().BoolVar(&o.actionsLogs, "actions-logs", false, "download and extract the logs of completed GitHub Actions workflow runs")
+	cmd.Flags().Int64Var(&o.actionsLogsMaxSize, "actions-logs-max-size", ghere.DEFAULT_ACTIONS_LOGS_MAX_SIZE, "maximum size, in bytes, of each workflow run's log archive to download")
+	cmd.Flags().BoolVar(&o.actionsArtifacts, "actions-artifacts", false, "download and extract the artifacts of completed GitHub Actions workflow runs")
+	cmd.Flags().Int64Var(&o.actionsArtifactsMaxSize, "actions-artifacts-max-size", ghere.DEFAULT_ACTIONS_ARTIFACTS_MAX_SIZE, "maximum size, in bytes, of each workflow run artifact to download")
+}
+
+// exitCode determines the exit code with which to terminate as a result of
+// the given fetch failure, according to the configured exit code policy.
+func (cmd *fetchCmd) exitCode(report *ghere.FetchReport, err error) int {
+	var interrupted *ghere.ErrFetchInterrupted
+	if errors.As(err, &interrupted) {
+		return cmd.exitCodeFor(ghere.FETCH_ERROR_INTERRUPTED)
+	}
+	failed := report.Failed()
+	if len(failed) == 0 {
+		return cmd.exitCodeFor(ghere.FetchErrorType(err))
+	}
+	code := 0
+	for _, repoReport := range failed {
+		if c := cmd.exitCodeFor(repoReport.ErrorType); c > code {
+			code = c
+		}
+	}
+	return code
+}
+
+func (cmd *fetchCmd) validateExitCodes() error {
+	for errorType := range cmd.exitCodes {
+		known := false
+		for _, t := range ghere.FetchErrorTypes {
+			known = known || t == errorType
+		}
+		if !known {
+			return fmt.Errorf("unknown failure type in --exit-codes: %s (must be one of: %s)", errorType, strings.Join(ghere.FetchErrorTypes, ", "))
+		}
+	}
+	return nil
+}
+
+func (cmd *fetchCmd) exitCodeFor(errorType string) int {
+	if code, exists := cmd.exitCodes[errorType]; exists {
+		return code
+	}
+	if errorType == ghere.FETCH_ERROR_INTERRUPTED {
+		return EXIT_CODE_INTERRUPTED
+	}
+	return EXIT_CODE_FAILURE
+}
+
+func writeFetchReport(path string, report *ghere.FetchReport) error {
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal fetch report to JSON: %v", err)
+	}
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		return fmt.Errorf("failed to write fetch report to %s: %v", path, err)
+	}
+	return nil
+}
+
+// plan logs the work that a fetch would do for each repository in the
+// collection.
+func (cmd *fetchCmd) plan(ctx context.Context, coll *ghere.LocalCollection, cfg *ghere.FetchConfig, log ghere.Logger) error {
+	plan, err := coll.Plan(ctx, cfg, log)
+	if err != nil {
+		log.Error("Failed to plan fetch", "err", err)
+		return err
+	}
+	totalAPICalls, totalGitOps := 0, 0
+	for _, repoPlan := range plan.Repositories {
+		if len(repoPlan.Error) > 0 {
+			continue
+		}
+		log.Info(
+			"Planned repository fetch",
+			"repo", repoPlan.Repository,
+			"fetchers", len(repoPlan.Fetchers),
+			"apiCalls", repoPlan.EstimatedAPICalls,
+			"gitOps", repoPlan.EstimatedGitOperations,
+		)
+		for _, fetcherPlan := range repoPlan.Fetchers {
+			log.Info(
+				"Planned fetcher",
+				"repo", repoPlan.Repository,
+				"resource", fetcherPlan.Resource,
+				"plan", fetcherPlan.Description,
+				"apiCalls", fetcherPlan.EstimatedAPICalls,
+			)
+		}
+		totalAPICalls += repoPlan.EstimatedAPICalls
+		totalGitOps += repoPlan.EstimatedGitOperations
+	}
+	log.Info("Dry run complete", "repos", len(plan.Repositories), "apiCalls", totalAPICalls, "gitOps", totalGitOps)
+	return nil
+}
+
+// fetchConfig constructs the configuration with which to fetch the given
+// collection's repositories. If metrics are supplied, the GitHub clients for
+// all hosts are registered with them.
+func (o *fetchOptions) fetchConfig(ctx context.Context, coll *ghere.LocalCollection, metrics *ghere.Metrics, log ghere.Logger) (*ghere.FetchConfig, error) {
+	if len(o.uploadURL) > 0 && len(o.baseURL) == 0 {
+		err := errors.New("--upload-url can only be used along with --base-url")
+		log.Error("Invalid GitHub Enterprise Server configuration", "err", err)
+		return nil, err
+	}
+	if o.reqRetries < 1 {
+		err := errors.New("--request-retries must be at least 1, since it includes the initial attempt")
+		log.Error("Invalid request retry configuration", "err", err)
+		return nil, err
+	}
+	if len(os.Getenv(ghere.GITHUB_PASSWORD_ENVVAR)) > 0 && len(o.githubUsername) == 0 {
+		err := fmt.Errorf("--login must be specified when authenticating using %s", ghere.GITHUB_PASSWORD_ENVVAR)
+		log.Error("Invalid HTTPS authentication configuration", "err", err)
+		return nil, err
+	}
+	baseURL, uploadURL := coll.BaseURL, coll.UploadURL
+	if len(o.baseURL) > 0 {
+		baseURL, uploadURL = o.baseURL, o.uploadURL
+	}
+	tokenSources, err := o.tokenSources(baseURL)
+	if err != nil {
+		log.Error("Failed to configure GitHub authentication", "err", err)
+		return nil, err
+	}
+	log.Debug("Configured GitHub authentication", "tokens", len(tokenSources))
+	defaultHost, err := o.hostFetchConfig(ctx, baseURL, uploadURL, tokenSources, log)
+	if err != nil {
+		log.Error("Failed to configure GitHub client", "err", err)
+		return nil, err
+	}
+	cfg := &ghere.FetchConfig{
+		Client:             defaultHost.Client,
+		CredentialProvider: defaultHost.CredentialProvider,
+		RepoUpdater:        ghere.NewGitHubRepositoryUpdater(o.submodules),
+		GitTimeout:         time.Duration(o.gitTimeout) * time.Second,
+		PrettyJSON:         o.pretty,
+		Resources: &ghere.ResourceSelection{
+			Include: o.include,
+			Only:    o.only,
+			Skip:    o.skip,
+		},
+		UseEvents:                o.useEvents,
+		FetchReactions:           o.reactions,
+		CommentReactionsInterval: time.Duration(o.commentReactionsInterval) * time.Second,
+		FetchActionsLogs:         o.actionsLogs,
+		ActionsLogsMaxSize:       o.actionsLogsMaxSize,
+		FetchActionsArtifacts:    o.actionsArtifacts,
+		ActionsArtifactsMaxSize:  o.actionsArtifactsMaxSize,
+		Hosts:                    make(map[string]*ghere.HostFetchConfig),
+		Metrics:                  metrics,
+	}
+	if metrics != nil {
+		metrics.RegisterClient(metricsHost(baseURL), cfg.Client)
+	}
+	for hostName, host := range coll.Hosts {
+		tokenEnvVar := host.TokenEnvVar
+		if len(tokenEnvVar) == 0 {
+			tokenEnvVar = GITHUB_TOKEN_ENVVAR
+		}
+		// As with GITHUB_TOKENS, multiple tokens for the host can be
+		// supplied, separated by commas or whitespace.
+		hostTokens := parseTokens(os.Getenv(tokenEnvVar))
+		if len(hostTokens) == 0 {
+			log.Error("Missing access token for GitHub host", "host", hostName, "envVar", tokenEnvVar)
+			return nil, fmt.Errorf("missing %s environment variable for host %s", tokenEnvVar, hostName)
+		}
+		hostTokenSources := make([]oauth2.TokenSource, 0, len(hostTokens))
+		for _, token := range hostTokens {
+			hostTokenSources = append(hostTokenSources, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token}))
+		}
+		cfg.Hosts[hostName], err = o.hostFetchConfig(ctx, host.BaseURL, host.UploadURL, hostTokenSources, log)
+		if err != nil {
+			log.Error("Failed to configure GitHub client for host", "host", hostName, "err", err)
+			return nil, err
+		}
+		if metrics != nil {
+			metrics.RegisterClient(hostName, cfg.Hosts[hostName].Client)
+		}
+	}
+	return cfg, nil
+}
+
+// tokenSources constructs the sources of tokens for authenticating against
+// GitHub. If authenticating as a GitHub App installation, a single,
+// automatically refreshing, token source is returned. Otherwise one token
+// source is returned for each token in the token file (if specified), the
+// GITHUB_TOKENS environment variable, or the GITHUB_TOKEN environment
+// variable, in that order of preference.
+func (o *fetchOptions) tokenSources(baseURL string) ([]oauth2.TokenSource, error) {
+	if o.appID != 0 {
+		if o.appInstallID == 0 || len(o.appPrivKeyFile) == 0 {
+			return nil, errors.New("both --app-installation-id and --app-private-key must be specified when authenticating as a GitHub App")
+		}
+		privKey, err := ghere.LoadGitHubAppPrivateKey(o.appPrivKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		ts, err := ghere.NewGitHubAppTokenSource(&ghere.GitHubAppConfig{
+			AppID:          o.appID,
+			InstallationID: o.appInstallID,
+			PrivateKeyPEM:  privKey,
+			BaseURL:        baseURL,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return []oauth2.TokenSource{ts}, nil
+	}
+	var tokens []string
+	switch {
+	case len(o.tokenFile) > 0:
+		b, err := os.ReadFile(o.tokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read token file %s: %v", o.tokenFile, err)
+		}
+		tokens = parseTokens(string(b))
+		if len(tokens) == 0 {
+			return nil, fmt.Errorf("no tokens found in token file %s", o.tokenFile)
+		}
+	case len(os.Getenv(GITHUB_TOKENS_ENVVAR)) > 0:
+		tokens = parseTokens(os.Getenv(GITHUB_TOKENS_ENVVAR))
+	case len(os.Getenv(GITHUB_TOKEN_ENVVAR)) > 0:
+		tokens = []string{os.Getenv(GITHUB_TOKEN_ENVVAR)}
+	}
+	if len(tokens) == 0 {
+		return nil, errors.New("to fetch from GitHub, you must either set the GITHUB_TOKEN (or GITHUB_TOKENS) environment variable, supply a token file, or authenticate as a GitHub App")
+	}
+	sources := make([]oauth2.TokenSource, 0, len(tokens))
+	for _, token := range tokens {
+		sources = append(sources, oauth2.StaticTokenSource(
+			&oauth2.Token{
+				AccessToken: token,
+			},
+		))
+	}
+	return sources, nil
+}
+
+// parseTokens extracts tokens separated by commas and/or whitespace, ignoring
+// lines starting with "#".
+func parseTokens(s string) []string {
+	tokens := []string{}
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, token := range strings.FieldsFunc(line, func(r rune) bool {
+			return r == ',' || unicode.IsSpace(r)
+		}) {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+// hostFetchConfig constructs a GitHub client and credential provider for the
+// GitHub instance at the given API base URL (or github.com if empty).
+func (o *fetchOptions) hostFetchConfig(ctx context.Context, baseURL, uploadURL string, tokenSources []oauth2.TokenSource, log ghere.Logger) (*ghere.HostFetchConfig, error) {
+	if len(uploadURL) > 0 && len(baseURL) == 0 {
+		return nil, fmt.Errorf("upload URL %s specified without a base URL", uploadURL)
+	}
+	host := ghere.DEFAULT_GITHUB_HOST
+	clients := make([]*github.Client, 0, len(tokenSources))
+	for _, ts := range tokenSources {
+		tc := oauth2.NewClient(ctx, ts)
+		client := github.NewClient(tc)
+		if len(baseURL) > 0 {
+			if len(uploadURL) == 0 {
+				uploadURL = baseURL
+			}
+			var err error
+			client, err = github.NewEnterpriseClient(baseURL, uploadURL, tc)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create GitHub Enterprise client for %s: %v", baseURL, err)
+			}
+			host = client.BaseURL.Hostname()
+		}
+		clients = append(clients, client)
+	}
+	retryPolicy := ghere.DefaultRetryPolicy()
+	retryPolicy.MaxAttempts = int(o.reqRetries)
+	retryPolicy.Timeout = time.Duration(o.reqTimeout) * time.Second
+	retryPolicy.InitialBackoff = time.Duration(o.retryBackoff * float64(time.Second))
+	retryPolicy.MaxBackoff = time.Duration(o.retryMaxWait) * time.Second
+	pool := ghere.NewGitHubClientPool(clients, retryPolicy, log)
+	credentialProvider, err := ghere.NewGitHubSSHCredentialProvider(
+		&ghere.SSHCredentialConfig{
+			UseAgent:           o.sshAgent,
+			DefaultPrivKeyFile: o.privKeyFile,
+			OwnerPrivKeyFiles:  o.ownerPrivKeys,
+			KnownHostsFiles:    o.knownHosts,
+		},
+		&ghere.HTTPCredentialConfig{
+			Host:                host,
+			Username:            o.githubUsername,
+			TokenSource:         ghere.NewGitHubClientPoolTokenSource(pool, tokenSources),
+			UseCredentialHelper: o.credHelper,
+			Log:                 log,
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure credentials: %v", err)
+	}
+	return &ghere.HostFetchConfig{
+		Client:             pool,
+		CredentialProvider: credentialProvider,
+	}, nil
 }
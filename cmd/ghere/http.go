@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/informalsystems/ghere/pkg/ghere"
+)
+
+const httpServerShutdownTimeout = 5 * time.Second
+
+// serveMetrics starts an HTTP server in the background that exposes the given
+// metrics at /metrics on the given address. The returned function stops the
+// server.
+func serveMetrics(addr string, metrics *ghere.Metrics, log ghere.Logger) (func(), error) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics)
+	return serveHTTP(addr, mux, log)
+}
+
+// serveHTTP starts an HTTP server in the background on the given address. The
+// returned function stops the server.
+func serveHTTP(addr string, handler http.Handler, log ghere.Logger) (func(), error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	srv := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error("HTTP server failed", "err", err)
+		}
+	}()
+	log.Info("Serving HTTP", "addr", listener.Addr().String())
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), httpServerShutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Error("Failed to shut down HTTP server", "err", err)
+		}
+	}, nil
+}
+
+// metricsHost returns the host name with which to label metrics pertaining to
+// the GitHub instance at the given API base URL (or github.com if empty).
+func metricsHost(baseURL string) string {
+	if len(baseURL) == 0 {
+		return ghere.DEFAULT_GITHUB_HOST
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil || len(u.Hostname()) == 0 {
+		return baseURL
+	}
+	return u.Hostname()
+}
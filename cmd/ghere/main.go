@@ -2,12 +2,52 @@ package main
 
 import (
 	"context"
+	"errors"
 	"os"
+	"os/signal"
+	"syscall"
+)
+
+const (
+	EXIT_CODE_FAILURE int = 1
+	// Conventional exit code for processes terminated by way of SIGINT.
+	EXIT_CODE_INTERRUPTED int = 130
 )
 
 func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		// Restore default signal handling once the first signal has been
+		// received, so that a second signal terminates the process
+		// immediately.
+		<-ctx.Done()
+		stop()
+	}()
+
 	rc := newRootCmd()
-	if err := rc.ExecuteContext(context.Background()); err != nil {
-		os.Exit(1)
+	if err := rc.ExecuteContext(ctx); err != nil {
+		var ee *exitError
+		if errors.As(err, &ee) {
+			os.Exit(ee.code)
+		}
+		if errors.Is(err, context.Canceled) {
+			os.Exit(EXIT_CODE_INTERRUPTED)
+		}
+		os.Exit(EXIT_CODE_FAILURE)
 	}
 }
+
+// exitError allows commands to control the exit code of the process.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
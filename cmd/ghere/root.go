@@ -12,6 +12,9 @@ type rootCmd struct {
 	verbose    bool
 	add        *addCmd
 	fetch      *fetchCmd
+	daemon     *daemonCmd
+	webhook    *webhookCmd
+	bundle     *bundleCmd
 
 	logger ghere.Logger
 }
@@ -56,6 +59,15 @@ machine. Pronounced "gear".`,
 	r.fetch = newFetchCmd(r)
 	r.AddCommand(r.fetch.Command)
 
+	r.daemon = newDaemonCmd(r)
+	r.AddCommand(r.daemon.Command)
+
+	r.webhook = newWebhookCmd(r)
+	r.AddCommand(r.webhook.Command)
+
+	r.bundle = newBundleCmd(r)
+	r.AddCommand(r.bundle.Command)
+
 	r.AddCommand(newVersionCmd())
 	return r
 }
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/informalsystems/ghere/pkg/ghere"
+	"github.com/spf13/cobra"
+)
+
+const GHERE_WEBHOOK_SECRET_ENVVAR string = "GHERE_WEBHOOK_SECRET"
+
+type webhookCmd struct {
+	*cobra.Command
+	fetchOptions
+
+	addr      string
+	path      string
+	noRefetch bool
+}
+
+func newWebhookCmd(root *rootCmd) *webhookCmd {
+	cmd := &webhookCmd{}
+	cmd.Command = &cobra.Command{
+		Use:   "webhook",
+		Short: "Apply GitHub webhook events to a local collection's repositories as they happen",
+		Long: `Listen for GitHub webhook events and apply them to a local collection's
+repositories as they happen.
+
+Issue, issue comment, pull request, pull request review, label and release
+events are written directly to the local store. Push events result in targeted
+refetches of the affected repository's code.
+
+Event payloads must be signed using the webhook secret supplied via the
+GHERE_WEBHOOK_SECRET environment variable (configure the webhook with the
+"application/json" content type).`,
+		Example: `  # Listen for webhook events at http://0.0.0.0:8080/webhook
+  export GHERE_WEBHOOK_SECRET="..."
+  ghere webhook --addr 0.0.0.0:8080`,
+		RunE: func(c *cobra.Command, args []string) error {
+			log := root.logger
+
+			secret := os.Getenv(GHERE_WEBHOOK_SECRET_ENVVAR)
+			if len(secret) == 0 {
+				return fmt.Errorf("the %s environment variable must be set", GHERE_WEBHOOK_SECRET_ENVVAR)
+			}
+			log.Info("Loading local collection", "path", root.configFile)
+			coll, err := ghere.LoadOrCreateLocalCollection(root.configFile)
+			if err != nil {
+				log.Error("Failed to load collection", "err", err)
+				return err
+			}
+			handler := ghere.NewWebhookHandler(coll, &ghere.WebhookConfig{
+				Secret:     []byte(secret),
+				PrettyJSON: cmd.pretty,
+			}, log)
+			refetchDone := make(chan error, 1)
+			if cmd.noRefetch {
+				refetchDone <- nil
+			} else {
+				cfg, err := cmd.fetchConfig(c.Context(), coll, nil, log)
+				if err != nil {
+					return err
+				}
+				go func() {
+					refetchDone <- handler.Refetch(c.Context(), cfg)
+				}()
+			}
+			mux := http.NewServeMux()
+			mux.Handle(cmd.path, handler)
+			stopServer, err := serveHTTP(cmd.addr, mux, log)
+			if err != nil {
+				log.Error("Failed to start webhook server", "addr", cmd.addr, "err", err)
+				return err
+			}
+			defer stopServer()
+			<-c.Context().Done()
+			log.Info("Stopping webhook server")
+			return <-refetchDone
+		},
+	}
+	cmd.addFlags(cmd.Command)
+	cmd.Flags().StringVar(&cmd.addr, "addr", "localhost:8080", "address on which to listen for webhook events")
+	cmd.Flags().StringVar(&cmd.path, "path", "/webhook", "HTTP path at which to receive webhook events")
+	cmd.Flags().BoolVar(&cmd.noRefetch, "no-refetch", false, "only apply events directly to the local store, ignoring events that require refetching resources from GitHub (e.g. pushes)")
+	return cmd
+}
@@ -0,0 +1,475 @@
+package ghere
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/google/go-github/v48/github"
+)
+
+const (
+	// The status of a workflow run that has finished (successfully or
+	// otherwise).
+	WORKFLOW_RUN_STATUS_COMPLETED string = "completed"
+	// GitHub only allows workflow runs to be re-run within this period after
+	// they were created.
+	WORKFLOW_RUN_RERUN_WINDOW time.Duration = 30 * 24 * time.Hour
+)
+
+type Workflow struct {
+	Workflow *github.Workflow `json:"workflow"`
+}
+
+func LoadWorkflow(rootPath string, repo *Repository, workflowID int64, mustExist bool) (*Workflow, error) {
+	var err error
+	workflow := &Workflow{}
+	path := workflowPath(rootPath, repo.GetOwner(), repo.GetName(), workflowID)
+	if mustExist {
+		err = readJSONFile(path, workflow)
+	} else {
+		err = readJSONFileOrEmpty(path, workflow)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workflow file: %v", err)
+	}
+	return workflow, nil
+}
+
+func (w *Workflow) Save(rootPath string, repo *Repository, prettyJSON bool) error {
+	path := workflowPath(rootPath, repo.GetOwner(), repo.GetName(), w.Workflow.GetID())
+	if err := writeJSONFile(path, w, prettyJSON); err != nil {
+		return fmt.Errorf("failed to write workflow file: %v", err)
+	}
+	return nil
+}
+
+type WorkflowRun struct {
+	Run *github.WorkflowRun `json:"run"`
+
+	// LastDetailFetch is the time at which the run's jobs (and, optionally,
+	// its logs and artifacts) were fetched. This only happens once the run
+	// has completed.
+	LastDetailFetch time.Time `json:"last_detail_fetch"`
+	// LogsSkipped, if not empty, explains why the run's logs were not stored
+	// despite having been requested.
+	LogsSkipped string `json:"logs_skipped,omitempty"`
+}
+
+func LoadWorkflowRun(rootPath string, repo *Repository, runID int64, mustExist bool) (*WorkflowRun, error) {
+	path := workflowRunDetailPath(rootPath, repo.GetOwner(), repo.GetName(), runID)
+	return LoadWorkflowRunDirect(path, mustExist)
+}
+
+func LoadWorkflowRunDirect(path string, mustExist bool) (*WorkflowRun, error) {
+	var err error
+	run := &WorkflowRun{}
+	if mustExist {
+		err = readJSONFile(path, run)
+	} else {
+		err = readJSONFileOrEmpty(path, run)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workflow run detail file: %v", err)
+	}
+	return run, nil
+}
+
+func (r *WorkflowRun) Save(rootPath string, repo *Repository, prettyJSON bool) error {
+	path := workflowRunDetailPath(rootPath, repo.GetOwner(), repo.GetName(), r.Run.GetID())
+	if err := writeJSONFile(path, r, prettyJSON); err != nil {
+		return fmt.Errorf("failed to write workflow run detail file: %v", err)
+	}
+	return nil
+}
+
+// IsCompleted returns whether the run has finished, after which its jobs,
+// logs and artifacts no longer change.
+func (r *WorkflowRun) IsCompleted() bool {
+	return r.Run.GetStatus() == WORKFLOW_RUN_STATUS_COMPLETED
+}
+
+func (r *WorkflowRun) MustFetchDetails() bool {
+	return r.IsCompleted() && r.LastDetailFetch.IsZero()
+}
+
+type WorkflowJob struct {
+	Job *github.WorkflowJob `json:"job"`
+}
+
+func (j *WorkflowJob) Save(rootPath string, repo *Repository, prettyJSON bool) error {
+	path := workflowJobPath(rootPath, repo.GetOwner(), repo.GetName(), j.Job.GetRunID(), j.Job.GetID())
+	if err := writeJSONFile(path, j, prettyJSON); err != nil {
+		return fmt.Errorf("failed to write workflow job file: %v", err)
+	}
+	return nil
+}
+
+type WorkflowArtifact struct {
+	Artifact *github.Artifact `json:"artifact"`
+
+	// Skipped, if not empty, explains why the artifact's contents were not
+	// stored despite having been requested.
+	Skipped string `json:"skipped,omitempty"`
+}
+
+func (a *WorkflowArtifact) Save(rootPath string, repo *Repository, runID int64, prettyJSON bool) error {
+	path := workflowArtifactPath(rootPath, repo.GetOwner(), repo.GetName(), runID, a.Artifact.GetID())
+	if err := writeJSONFile(path, a, prettyJSON); err != nil {
+		return fmt.Errorf("failed to write workflow artifact file: %v", err)
+	}
+	return nil
+}
+
+// actionsFetcher fetches a repository's workflows and any workflow runs
+// created since the last fetch, as well as refreshing runs that had not yet
+// completed at the time of the last fetch and runs that have since been
+// re-run.
+type actionsFetcher struct {
+	rootPath string
+	repo     *Repository
+}
+
+var _ fetcher = (*actionsFetcher)(nil)
+var _ planner = (*actionsFetcher)(nil)
+
+func newActionsFetcher(rootPath string, repo *Repository) *actionsFetcher {
+	return &actionsFetcher{
+		rootPath: rootPath,
+		repo:     repo,
+	}
+}
+
+func (f *actionsFetcher) resource() string {
+	return RESOURCE_ACTIONS
+}
+
+func (f *actionsFetcher) fetch(ctx context.Context, cfg *FetchConfig, log Logger) ([]fetcher, error) {
+	if err := f.fetchWorkflows(ctx, cfg); err != nil {
+		return nil, err
+	}
+
+	// Runs are listed from newest to oldest. Re-running a run does not change
+	// its ID or its position in the listing, so we stop at the first run that
+	// we have already fetched that is too old to have been re-run.
+	seen := make(map[int64]bool)
+	lastRunID := f.repo.LastWorkflowRunID
+	rerunCutoff := time.Now().Add(-WORKFLOW_RUN_RERUN_WINDOW)
+	pending := []int64{}
+	newRuns := 0
+	reruns := 0
+	done := false
+	for page := 1; !done; page++ {
+		var runs []*github.WorkflowRun
+		var err error
+		runs, done, err = cfg.Client.ListWorkflowRuns(ctx, f.repo.GetOwner(), f.repo.GetName(), page)
+		if err != nil {
+			return nil, err
+		}
+		for _, ghRun := range runs {
+			if seen[ghRun.GetID()] {
+				continue
+			}
+			known := ghRun.GetID() <= f.repo.LastWorkflowRunID
+			if known {
+				if ghRun.GetCreatedAt().Before(rerunCutoff) {
+					done = true
+					break
+				}
+				rerun, err := f.isRerun(ghRun)
+				if err != nil {
+					return nil, err
+				}
+				if !rerun {
+					continue
+				}
+				reruns++
+			}
+			seen[ghRun.GetID()] = true
+			run, err := f.saveRun(cfg, ghRun)
+			if err != nil {
+				return nil, err
+			}
+			if !run.IsCompleted() {
+				pending = append(pending, ghRun.GetID())
+			}
+			if ghRun.GetID() > lastRunID {
+				lastRunID = ghRun.GetID()
+			}
+			if !known {
+				newRuns++
+			}
+		}
+	}
+	for _, runID := range f.repo.PendingWorkflowRunIDs {
+		if seen[runID] {
+			continue
+		}
+		ghRun, err := cfg.Client.GetWorkflowRun(ctx, f.repo.GetOwner(), f.repo.GetName(), runID)
+		if err != nil {
+			return nil, err
+		}
+		run, err := f.saveRun(cfg, ghRun)
+		if err != nil {
+			return nil, err
+		}
+		if !run.IsCompleted() {
+			pending = append(pending, runID)
+		}
+	}
+	log.Info("Fetched workflow runs", "repo", f.repo.String(), "new", newRuns, "reruns", reruns, "pending", len(pending))
+
+	sort.Slice(pending, func(i, j int) bool { return pending[i] < pending[j] })
+	f.repo.LastWorkflowRunID = lastRunID
+	f.repo.PendingWorkflowRunIDs = pending
+	if err := f.repo.Save(f.rootPath, cfg.PrettyJSON); err != nil {
+		return nil, err
+	}
+
+	// Details are fetched for all completed runs that still need them (as
+	// opposed to only those fetched above), such that failures to fetch them
+	// are retried during subsequent fetches.
+	runs, err := f.runsWithStaleDetails()
+	if err != nil {
+		return nil, err
+	}
+	runFetchers := make([]fetcher, 0, len(runs))
+	for _, run := range runs {
+		runFetchers = append(runFetchers, newWorkflowRunFetcher(f.rootPath, f.repo, run))
+	}
+	return runFetchers, nil
+}
+
+func (f *actionsFetcher) fetchWorkflows(ctx context.Context, cfg *FetchConfig) error {
+	seen := make(map[int64]bool)
+	done := false
+	for page := 1; !done; page++ {
+		var workflows []*github.Workflow
+		var err error
+		workflows, done, err = cfg.Client.ListWorkflows(ctx, f.repo.GetOwner(), f.repo.GetName(), page)
+		if err != nil {
+			return err
+		}
+		for _, ghWorkflow := range workflows {
+			workflow, err := LoadWorkflow(f.rootPath, f.repo, ghWorkflow.GetID(), false)
+			if err != nil {
+				return err
+			}
+			seen[ghWorkflow.GetID()] = true
+			cfg.report.recordItem(RESOURCE_WORKFLOWS, workflow.Workflow != nil, workflow.Workflow, ghWorkflow)
+			workflow.Workflow = ghWorkflow
+			if err := workflow.Save(f.rootPath, f.repo, cfg.PrettyJSON); err != nil {
+				return err
+			}
+		}
+	}
+	workflowsPath := filepath.Dir(workflowPath(f.rootPath, f.repo.GetOwner(), f.repo.GetName(), 0))
+	deleted, err := countDeletedItems(filepath.Join(workflowsPath, "*.json"), seen, func(path string) string { return path })
+	if err != nil {
+		return err
+	}
+	cfg.report.recordDeleted(RESOURCE_WORKFLOWS, deleted)
+	return nil
+}
+
+func (f *actionsFetcher) saveRun(cfg *FetchConfig, ghRun *github.WorkflowRun) (*WorkflowRun, error) {
+	run, err := LoadWorkflowRun(f.rootPath, f.repo, ghRun.GetID(), false)
+	if err != nil {
+		return nil, err
+	}
+	cfg.report.recordItem(RESOURCE_WORKFLOW_RUNS, run.Run != nil, run.Run, ghRun)
+	if run.Run != nil && run.Run.GetRunAttempt() != ghRun.GetRunAttempt() {
+		// The jobs, logs and artifacts of the new attempt must be fetched.
+		run.LastDetailFetch = time.Time{}
+		run.LogsSkipped = ""
+	}
+	run.Run = ghRun
+	if err := run.Save(f.rootPath, f.repo, cfg.PrettyJSON); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// isRerun returns whether the given, previously fetched, run has been re-run
+// since it was last fetched. Runs that are not stored locally (e.g. because
+// an earlier fetch failed) are also refetched.
+func (f *actionsFetcher) isRerun(ghRun *github.WorkflowRun) (bool, error) {
+	run, err := LoadWorkflowRun(f.rootPath, f.repo, ghRun.GetID(), false)
+	if err != nil {
+		return false, err
+	}
+	return run.Run == nil || run.Run.GetRunAttempt() != ghRun.GetRunAttempt(), nil
+}
+
+func (f *actionsFetcher) runsWithStaleDetails() ([]*WorkflowRun, error) {
+	runs := []*WorkflowRun{}
+	runsPath := workflowRunsPath(f.rootPath, f.repo.GetOwner(), f.repo.GetName())
+	pattern := filepath.Join(runsPath, "*", DETAIL_FILENAME)
+	runDetailFiles, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workflow runs' detail files from pattern %s: %v", pattern, err)
+	}
+	for _, fn := range runDetailFiles {
+		run, err := LoadWorkflowRunDirect(fn, true)
+		if err != nil {
+			return nil, err
+		}
+		if run.MustFetchDetails() {
+			runs = append(runs, run)
+		}
+	}
+	return runs, nil
+}
+
+func (f *actionsFetcher) plan(cfg *FetchConfig) (*FetcherPlan, error) {
+	runs, err := f.runsWithStaleDetails()
+	if err != nil {
+		return nil, err
+	}
+	return &FetcherPlan{
+		Resource: RESOURCE_ACTIONS,
+		Description: fmt.Sprintf(
+			"list workflows and new (or recently re-run) workflow runs, refresh %d pending run(s), then fetch jobs for at least %d run(s)",
+			len(f.repo.PendingWorkflowRunIDs),
+			len(runs),
+		),
+		EstimatedAPICalls: 2 + len(f.repo.PendingWorkflowRunIDs) + len(runs),
+	}, nil
+}
+
+// workflowRunFetcher fetches the jobs of a completed workflow run and,
+// optionally, its logs and artifacts.
+type workflowRunFetcher struct {
+	rootPath string
+	repo     *Repository
+	run      *WorkflowRun
+}
+
+var _ fetcher = (*workflowRunFetcher)(nil)
+
+func newWorkflowRunFetcher(rootPath string, repo *Repository, run *WorkflowRun) *workflowRunFetcher {
+	return &workflowRunFetcher{
+		rootPath: rootPath,
+		repo:     repo,
+		run:      run,
+	}
+}
+
+func (f *workflowRunFetcher) resource() string {
+	return RESOURCE_WORKFLOW_JOBS
+}
+
+func (f *workflowRunFetcher) fetch(ctx context.Context, cfg *FetchConfig, log Logger) ([]fetcher, error) {
+	runID := f.run.Run.GetID()
+	done := false
+	for page := 1; !done; page++ {
+		var jobs []*github.WorkflowJob
+		var err error
+		jobs, done, err = cfg.Client.ListWorkflowJobs(ctx, f.repo.GetOwner(), f.repo.GetName(), runID, page)
+		if err != nil {
+			return nil, err
+		}
+		for _, ghJob := range jobs {
+			cfg.report.recordItem(RESOURCE_WORKFLOW_JOBS, false, nil, ghJob)
+			job := &WorkflowJob{Job: ghJob}
+			if err := job.Save(f.rootPath, f.repo, cfg.PrettyJSON); err != nil {
+				return nil, err
+			}
+		}
+	}
+	f.run.LogsSkipped = ""
+	if cfg.FetchActionsLogs {
+		if err := f.fetchLogs(ctx, cfg, log); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.FetchActionsArtifacts {
+		if err := f.fetchArtifacts(ctx, cfg, log); err != nil {
+			return nil, err
+		}
+	}
+	f.run.LastDetailFetch = time.Now()
+	if err := f.run.Save(f.rootPath, f.repo, cfg.PrettyJSON); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (f *workflowRunFetcher) fetchLogs(ctx context.Context, cfg *FetchConfig, log Logger) error {
+	runID := f.run.Run.GetID()
+	maxSize := cfg.ActionsLogsMaxSize
+	if maxSize <= 0 {
+		maxSize = DEFAULT_ACTIONS_LOGS_MAX_SIZE
+	}
+	data, err := cfg.Client.DownloadWorkflowRunLogs(ctx, f.repo.GetOwner(), f.repo.GetName(), runID, maxSize)
+	if err == nil {
+		err = extractZip(data, workflowRunLogsPath(f.rootPath, f.repo.GetOwner(), f.repo.GetName(), runID), maxSize*MAX_ZIP_EXPANSION_RATIO)
+	}
+	if err != nil {
+		if isSkippableDownloadError(err) {
+			log.Warn("Skipping workflow run logs", "repo", f.repo.String(), "run", runID, "reason", err.Error())
+			f.run.LogsSkipped = err.Error()
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+func (f *workflowRunFetcher) fetchArtifacts(ctx context.Context, cfg *FetchConfig, log Logger) error {
+	runID := f.run.Run.GetID()
+	maxSize := cfg.ActionsArtifactsMaxSize
+	if maxSize <= 0 {
+		maxSize = DEFAULT_ACTIONS_ARTIFACTS_MAX_SIZE
+	}
+	done := false
+	for page := 1; !done; page++ {
+		var artifacts []*github.Artifact
+		var err error
+		artifacts, done, err = cfg.Client.ListWorkflowRunArtifacts(ctx, f.repo.GetOwner(), f.repo.GetName(), runID, page)
+		if err != nil {
+			return err
+		}
+		for _, ghArtifact := range artifacts {
+			artifact := &WorkflowArtifact{Artifact: ghArtifact}
+			switch {
+			case ghArtifact.GetExpired():
+				artifact.Skipped = "artifact expired"
+			case ghArtifact.GetSizeInBytes() > maxSize:
+				artifact.Skipped = (&ErrDownloadTooLarge{Limit: maxSize}).Error()
+			default:
+				data, err := cfg.Client.DownloadArtifact(ctx, f.repo.GetOwner(), f.repo.GetName(), ghArtifact.GetID(), maxSize)
+				if err == nil {
+					err = extractZip(data, workflowArtifactContentsPath(f.rootPath, f.repo.GetOwner(), f.repo.GetName(), runID, ghArtifact.GetID()), maxSize*MAX_ZIP_EXPANSION_RATIO)
+				}
+				if err != nil {
+					if !isSkippableDownloadError(err) {
+						return err
+					}
+					artifact.Skipped = err.Error()
+				}
+			}
+			if len(artifact.Skipped) > 0 {
+				log.Debug("Skipping workflow run artifact contents", "repo", f.repo.String(), "run", runID, "artifact", ghArtifact.GetID(), "reason", artifact.Skipped)
+			}
+			cfg.report.recordItem(RESOURCE_WORKFLOW_ARTIFACTS, false, nil, ghArtifact)
+			if err := artifact.Save(f.rootPath, f.repo, runID, cfg.PrettyJSON); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// isSkippableDownloadError returns whether the given error only prevents a
+// specific download, as opposed to indicating a problem with the fetch as a
+// whole.
+func isSkippableDownloadError(err error) bool {
+	var tooLarge *ErrDownloadTooLarge
+	var unavailable *ErrDownloadUnavailable
+	var archiveTooLarge *ErrArchiveTooLarge
+	return errors.As(err, &tooLarge) || errors.As(err, &unavailable) || errors.As(err, &archiveTooLarge)
+}
@@ -0,0 +1,305 @@
+package ghere
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/packfile"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/revlist"
+)
+
+// The size of the sliding window used for delta compression when writing
+// bundles' packfiles.
+const bundlePackWindow uint = 10
+
+// BundleConfig provides configuration for exporting a collection's code as Git
+// bundles.
+type BundleConfig struct {
+	// OutputDir is the directory into which bundles and the bundle manifest
+	// will be written.
+	OutputDir string
+	// BaseManifest, if not nil, results in incremental bundles being produced
+	// that only contain objects not already reachable from the refs recorded
+	// in this manifest.
+	BaseManifest *BundleManifest
+	PrettyJSON   bool
+}
+
+// BundleManifest ties the Git bundles produced by a single export to the
+// metadata of the repositories from which they were produced.
+type BundleManifest struct {
+	CreatedAt   time.Time `json:"created_at"`
+	Incremental bool      `json:"incremental"`
+	// BaseCreatedAt is the creation time of the manifest relative to which
+	// this manifest's bundles were produced, if incremental.
+	BaseCreatedAt *time.Time          `json:"base_created_at,omitempty"`
+	Bundles       []*RepositoryBundle `json:"bundles"`
+}
+
+// RepositoryBundle describes the Git bundle produced for a single repository.
+type RepositoryBundle struct {
+	// Host is the GitHub Enterprise Server host of the repository, if any.
+	Host  string `json:"host,omitempty"`
+	Owner string `json:"owner"`
+	Name  string `json:"name"`
+	// File is the path to the bundle file, relative to the manifest. Empty if
+	// the repository was unchanged since the base manifest.
+	File string `json:"file,omitempty"`
+	// Refs maps the names of all references in the bundle to the hashes of
+	// the objects they point to.
+	Refs map[string]string `json:"refs"`
+	// Prerequisites lists the commits that must already be present in a
+	// repository into which this bundle is fetched.
+	Prerequisites []string `json:"prerequisites,omitempty"`
+	// Unchanged is true if the repository's refs are the same as those
+	// recorded in the base manifest, in which case no bundle file is written.
+	Unchanged bool `json:"unchanged,omitempty"`
+	// Repository contains the repository's metadata, including the times at
+	// which its various components were last fetched.
+	Repository *Repository `json:"repository"`
+}
+
+// LoadBundleManifest loads a bundle manifest previously written by
+// [LocalCollection.Bundle].
+func LoadBundleManifest(path string) (*BundleManifest, error) {
+	manifest := &BundleManifest{}
+	if err := readJSONFile(path, manifest); err != nil {
+		return nil, fmt.Errorf("failed to read bundle manifest: %v", err)
+	}
+	return manifest, nil
+}
+
+func (m *BundleManifest) Save(outputDir string, prettyJSON bool) error {
+	path := filepath.Join(outputDir, BUNDLE_MANIFEST_FILENAME)
+	if err := writeJSONFile(path, m, prettyJSON); err != nil {
+		return fmt.Errorf("failed to write bundle manifest: %v", err)
+	}
+	return nil
+}
+
+func (m *BundleManifest) find(host, owner, name string) *RepositoryBundle {
+	if m == nil {
+		return nil
+	}
+	for _, b := range m.Bundles {
+		if b.Host == host && b.Owner == owner && b.Name == name {
+			return b
+		}
+	}
+	return nil
+}
+
+// Bundle exports the code of each repository in the collection as a Git
+// bundle containing all of its refs, and writes a manifest describing the
+// bundles.
+func (c *LocalCollection) Bundle(ctx context.Context, cfg *BundleConfig, log Logger) (*BundleManifest, error) {
+	manifest := &BundleManifest{
+		CreatedAt:   time.Now(),
+		Incremental: cfg.BaseManifest != nil,
+		Bundles:     []*RepositoryBundle{},
+	}
+	if cfg.BaseManifest != nil {
+		manifest.BaseCreatedAt = &cfg.BaseManifest.CreatedAt
+	}
+	for _, localRepo := range c.Repositories {
+		select {
+		case <-ctx.Done():
+			return nil, errors.New("bundle export cancelled")
+		default:
+		}
+		repoID := localRepo.String()
+		rootPath := c.repoRootPath(localRepo)
+		codePath := repoCodePath(rootPath, localRepo.Owner, localRepo.Name)
+		exists, err := dirExists(filepath.Join(codePath, ".git"))
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			log.Warn("Repository code has not been fetched yet, skipping", "repo", repoID)
+			continue
+		}
+		repo, err := LoadRepository(rootPath, localRepo.Owner, localRepo.Name, true)
+		if err != nil {
+			return nil, err
+		}
+		base := cfg.BaseManifest.find(localRepo.Host, localRepo.Owner, localRepo.Name)
+		log.Info("Bundling repository", "repo", repoID, "incremental", base != nil)
+		bundle, err := writeRepositoryBundle(codePath, cfg.OutputDir, localRepo.Host, localRepo.Owner, localRepo.Name, base)
+		if err != nil {
+			return nil, fmt.Errorf("failed to bundle repository %s: %v", repoID, err)
+		}
+		bundle.Repository = repo
+		if bundle.Unchanged {
+			log.Info("Repository unchanged since previous bundle", "repo", repoID)
+		}
+		manifest.Bundles = append(manifest.Bundles, bundle)
+	}
+	if err := manifest.Save(cfg.OutputDir, cfg.PrettyJSON); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// writeRepositoryBundle writes a v2 Git bundle for the repository in repoDir.
+// If base is not nil, the commits referenced by base will be treated as
+// prerequisites, and their objects will be excluded from the bundle.
+func writeRepositoryBundle(repoDir, outputDir, host, owner, name string, base *RepositoryBundle) (*RepositoryBundle, error) {
+	repo, err := git.PlainOpen(repoDir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open Git repository %s: %v", repoDir, err)
+	}
+	shallow, err := repo.Storer.Shallow()
+	if err != nil {
+		return nil, fmt.Errorf("unable to determine whether Git repository %s is shallow: %v", repoDir, err)
+	}
+	if len(shallow) > 0 {
+		return nil, fmt.Errorf("cannot bundle shallow Git repository %s", repoDir)
+	}
+	refs, err := bundleRefs(repo)
+	if err != nil {
+		return nil, err
+	}
+	bundle := &RepositoryBundle{
+		Host:  host,
+		Owner: owner,
+		Name:  name,
+		Refs:  make(map[string]string),
+	}
+	wants := make([]plumbing.Hash, 0, len(refs))
+	for _, ref := range refs {
+		bundle.Refs[ref.Name().String()] = ref.Hash().String()
+		wants = append(wants, ref.Hash())
+	}
+	prereqs := []plumbing.Hash{}
+	if base != nil {
+		prereqs = bundlePrerequisites(repo, base)
+		if sameRefs(bundle.Refs, base.Refs) {
+			bundle.Unchanged = true
+			return bundle, nil
+		}
+	}
+	for _, h := range prereqs {
+		bundle.Prerequisites = append(bundle.Prerequisites, h.String())
+	}
+	objs, err := revlist.Objects(repo.Storer, wants, prereqs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute objects to bundle for %s: %v", repoDir, err)
+	}
+	path := bundleFilePath(outputDir, host, owner, name)
+	bundle.File, err = filepath.Rel(outputDir, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute relative path for bundle file %s: %v", path, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create parent directory for %s: %v", path, err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bundle file %s: %v", path, err)
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	if _, err := fmt.Fprintln(w, "# v2 git bundle"); err != nil {
+		return nil, fmt.Errorf("failed to write bundle header to %s: %v", path, err)
+	}
+	for _, h := range prereqs {
+		if _, err := fmt.Fprintf(w, "-%s\n", h); err != nil {
+			return nil, fmt.Errorf("failed to write bundle prerequisites to %s: %v", path, err)
+		}
+	}
+	for _, ref := range refs {
+		if _, err := fmt.Fprintf(w, "%s %s\n", ref.Hash(), ref.Name()); err != nil {
+			return nil, fmt.Errorf("failed to write bundle refs to %s: %v", path, err)
+		}
+	}
+	if _, err := fmt.Fprintln(w); err != nil {
+		return nil, fmt.Errorf("failed to write bundle header to %s: %v", path, err)
+	}
+	enc := packfile.NewEncoder(w, repo.Storer, false)
+	if _, err := enc.Encode(objs, bundlePackWindow); err != nil {
+		return nil, fmt.Errorf("failed to write bundle packfile to %s: %v", path, err)
+	}
+	if err := w.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to write bundle file %s: %v", path, err)
+	}
+	return bundle, nil
+}
+
+// bundleRefs returns all of the references in the given repository, with
+// symbolic references (e.g. HEAD) resolved, sorted by name.
+func bundleRefs(repo *git.Repository) ([]*plumbing.Reference, error) {
+	iter, err := repo.References()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Git references: %v", err)
+	}
+	refs := []*plumbing.Reference{}
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() == plumbing.SymbolicReference {
+			resolved, err := repo.Reference(ref.Name(), true)
+			if err != nil {
+				// Dangling symbolic references are ignored, like in Git
+				return nil
+			}
+			ref = plumbing.NewHashReference(ref.Name(), resolved.Hash())
+		}
+		refs = append(refs, ref)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Git references: %v", err)
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		return refs[i].Name().String() < refs[j].Name().String()
+	})
+	return refs, nil
+}
+
+// bundlePrerequisites resolves the refs recorded in the given base bundle into
+// the set of commits that exist in the given repository. Refs that no longer
+// exist locally (e.g. due to force pushes) are ignored.
+func bundlePrerequisites(repo *git.Repository, base *RepositoryBundle) []plumbing.Hash {
+	seen := make(map[plumbing.Hash]bool)
+	prereqs := []plumbing.Hash{}
+	for _, hash := range base.Refs {
+		h := plumbing.NewHash(hash)
+		if tag, err := repo.TagObject(h); err == nil {
+			commit, err := tag.Commit()
+			if err != nil {
+				continue
+			}
+			h = commit.Hash
+		}
+		if _, err := object.GetCommit(repo.Storer, h); err != nil {
+			continue
+		}
+		if !seen[h] {
+			seen[h] = true
+			prereqs = append(prereqs, h)
+		}
+	}
+	sort.Slice(prereqs, func(i, j int) bool {
+		return prereqs[i].String() < prereqs[j].String()
+	})
+	return prereqs
+}
+
+func sameRefs(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, hash := range a {
+		if b[name] != hash {
+			return false
+		}
+	}
+	return true
+}
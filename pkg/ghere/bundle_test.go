@@ -0,0 +1,105 @@
+package ghere_test
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/google/go-github/v48/github"
+	"github.com/informalsystems/ghere/pkg/ghere"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollectionBundle(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	log := ghere.NewNoopLogger()
+	tmpDir := t.TempDir()
+	coll, err := ghere.LoadOrCreateLocalCollection(filepath.Join(tmpDir, ghere.CONFIG_FILE_NAME))
+	require.NoError(t, err)
+	owner := "org"
+	name := "repo"
+	_, err = coll.NewFromPath(owner + "/" + name)
+	require.NoError(t, err)
+	repo := &ghere.Repository{
+		Repository: &github.Repository{Owner: &github.User{Login: &owner}, Name: &name},
+	}
+	require.NoError(t, repo.Save(tmpDir, false))
+
+	codePath := filepath.Join(tmpDir, owner, name, "code")
+	gitRepo, err := git.PlainInit(codePath, false)
+	require.NoError(t, err)
+	worktree, err := gitRepo.Worktree()
+	require.NoError(t, err)
+	commit := func(content string) string {
+		require.NoError(t, os.WriteFile(filepath.Join(codePath, "README.md"), []byte(content), 0o644))
+		_, err := worktree.Add("README.md")
+		require.NoError(t, err)
+		hash, err := worktree.Commit(content, &git.CommitOptions{
+			Author: &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()},
+		})
+		require.NoError(t, err)
+		return hash.String()
+	}
+	runGit := func(dir string, args ...string) string {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+		return strings.TrimSpace(string(out))
+	}
+	bundle := func(outputDir string, base *ghere.BundleManifest) *ghere.RepositoryBundle {
+		manifest, err := coll.Bundle(context.Background(), &ghere.BundleConfig{OutputDir: outputDir, BaseManifest: base}, log)
+		require.NoError(t, err)
+		require.Len(t, manifest.Bundles, 1)
+		loaded, err := ghere.LoadBundleManifest(filepath.Join(outputDir, ghere.BUNDLE_MANIFEST_FILENAME))
+		require.NoError(t, err)
+		assert.Equal(t, base != nil, loaded.Incremental)
+		return manifest.Bundles[0]
+	}
+
+	// A full bundle must be clonable as is.
+	first := commit("first")
+	fullDir := filepath.Join(tmpDir, "full")
+	full := bundle(fullDir, nil)
+	assert.Empty(t, full.Prerequisites)
+	assert.False(t, full.Unchanged)
+	fullManifest, err := ghere.LoadBundleManifest(filepath.Join(fullDir, ghere.BUNDLE_MANIFEST_FILENAME))
+	require.NoError(t, err)
+	clonePath := filepath.Join(tmpDir, "clone")
+	runGit(tmpDir, "clone", "--quiet", filepath.Join(fullDir, full.File), clonePath)
+	assert.Equal(t, first, runGit(clonePath, "rev-parse", "HEAD"))
+
+	// An incremental bundle must only be verifiable against a repository
+	// containing the previous bundle's commits, and must bring it up to date.
+	second := commit("second")
+	incDir := filepath.Join(tmpDir, "inc")
+	inc := bundle(incDir, fullManifest)
+	assert.Equal(t, []string{first}, inc.Prerequisites)
+	assert.False(t, inc.Unchanged)
+	incFile := filepath.Join(incDir, inc.File)
+	runGit(clonePath, "bundle", "verify", "--quiet", incFile)
+	emptyPath := filepath.Join(tmpDir, "empty")
+	runGit(tmpDir, "init", "--quiet", emptyPath)
+	cmd := exec.Command("git", "bundle", "verify", "--quiet", incFile)
+	cmd.Dir = emptyPath
+	assert.Error(t, cmd.Run())
+	runGit(clonePath, "fetch", "--quiet", incFile, "refs/heads/master:refs/remotes/inc/master")
+	assert.Equal(t, second, runGit(clonePath, "rev-parse", "refs/remotes/inc/master"))
+
+	// No bundle must be written for repositories whose refs have not changed.
+	incManifest, err := ghere.LoadBundleManifest(filepath.Join(incDir, ghere.BUNDLE_MANIFEST_FILENAME))
+	require.NoError(t, err)
+	unchanged := bundle(filepath.Join(tmpDir, "unchanged"), incManifest)
+	assert.True(t, unchanged.Unchanged)
+	assert.Empty(t, unchanged.File)
+	assert.Equal(t, inc.Refs, unchanged.Refs)
+}
@@ -0,0 +1,203 @@
+package ghere
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	CHECKPOINTS_FILENAME string = "checkpoints.json"
+	// Checkpoints older than this are discarded, since the items processed
+	// prior to the checkpoint are likely to be outdated by then.
+	checkpointMaxAge time.Duration = 24 * time.Hour
+)
+
+// FetchCheckpoint records how far a fetcher got through a paginated listing
+// of items, so that an interrupted fetch can resume exactly where it stopped.
+type FetchCheckpoint struct {
+	// Page is the next page of the listing to fetch.
+	Page int `json:"page"`
+	// PerPage is the page size with which the listing was being fetched.
+	PerPage int `json:"per_page"`
+	// LastItem is the key (e.g. issue number or review ID) of the last item
+	// that was completely processed.
+	LastItem  int64     `json:"last_item"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// FetchCheckpoints is a sidecar file stored alongside a repository's detail
+// file, tracking the checkpoints of all of that repository's fetchers that
+// have not yet completed.
+type FetchCheckpoints struct {
+	// Phases maps the phase of the fetch (e.g. "issues") to its checkpoint.
+	Phases map[string]*FetchCheckpoint `json:"phases"`
+}
+
+func LoadFetchCheckpoints(rootPath, owner, name string) (*FetchCheckpoints, error) {
+	cps := &FetchCheckpoints{}
+	path := repoCheckpointsPath(rootPath, owner, name)
+	if err := readJSONFileOrEmpty(path, cps); err != nil {
+		return nil, fmt.Errorf("failed to read fetch checkpoints file: %v", err)
+	}
+	if cps.Phases == nil {
+		cps.Phases = make(map[string]*FetchCheckpoint)
+	}
+	return cps, nil
+}
+
+// Save writes the checkpoints to the repository's checkpoints file, removing
+// the file if there are no outstanding checkpoints.
+func (cps *FetchCheckpoints) Save(rootPath, owner, name string, prettyJSON bool) error {
+	path := repoCheckpointsPath(rootPath, owner, name)
+	if len(cps.Phases) == 0 {
+		return removeFetchCheckpoints(path)
+	}
+	if err := writeJSONFile(path, cps, prettyJSON); err != nil {
+		return fmt.Errorf("failed to write fetch checkpoints file: %v", err)
+	}
+	return nil
+}
+
+func removeFetchCheckpoints(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove fetch checkpoints file %s: %v", path, err)
+	}
+	return nil
+}
+
+// fresh returns the checkpoint for the given phase, if one exists and it is
+// not outdated.
+func (cps *FetchCheckpoints) fresh(phase string) *FetchCheckpoint {
+	cp, exists := cps.Phases[phase]
+	if !exists || time.Since(cp.UpdatedAt) > checkpointMaxAge {
+		return nil
+	}
+	return cp
+}
+
+// resumePage computes the page from which to resume fetching, accounting for
+// changes in page size since the checkpoint was saved.
+func (cp *FetchCheckpoint) resumePage() int {
+	if cp.Page < 1 {
+		return 1
+	}
+	if cp.PerPage <= 0 || cp.PerPage == DEFAULT_PER_PAGE {
+		return cp.Page
+	}
+	return ((cp.Page-1)*cp.PerPage)/DEFAULT_PER_PAGE + 1
+}
+
+// fetchPaginated iterates through all of the pages of a listing of items,
+// calling process for each item. Listings must be in a stable order in which
+// the keys of items increase (e.g. issue numbers or review IDs, listed by
+// creation time), since resuming from a checkpoint relies on comparing keys to
+// locate the last processed item when it is no longer listed.
+//
+// Progress is checkpointed after each page under the given phase, and if a
+// checkpoint exists from an interrupted prior fetch, fetching resumes from
+// that checkpoint. The checkpoint is removed once the listing has been
+// completely processed. Returns true if the entire listing was processed from
+// the start (as opposed to having been resumed from a checkpoint).
+func fetchPaginated[T any](
+	rootPath string,
+	repo *Repository,
+	phase string,
+	cfg *FetchConfig,
+	log Logger,
+	list func(page int) ([]T, bool, error),
+	key func(T) int64,
+	process func(T) error,
+) (bool, error) {
+	owner, name := repo.GetOwner(), repo.GetName()
+	cps, err := LoadFetchCheckpoints(rootPath, owner, name)
+	if err != nil {
+		return false, err
+	}
+	page := 1
+	lastItem := int64(0)
+	// resumeAfter is the key of the last item processed prior to the
+	// checkpoint, which is only relevant to the first page read after
+	// resuming.
+	resumeAfter := int64(0)
+	resuming := false
+	fromStart := true
+	if cp, exists := cps.Phases[phase]; exists {
+		if time.Since(cp.UpdatedAt) > checkpointMaxAge {
+			log.Info("Discarding outdated fetch checkpoint", "repo", repo.String(), "phase", phase, "updated", cp.UpdatedAt.Local().String())
+		} else {
+			page = cp.resumePage()
+			resumeAfter = cp.LastItem
+			lastItem = cp.LastItem
+			resuming = page > 1
+			fromStart = !resuming
+			log.Info("Resuming fetch from checkpoint", "repo", repo.String(), "phase", phase, "page", page, "lastItem", resumeAfter)
+		}
+	}
+	done := false
+	for !done {
+		var items []T
+		items, done, err = list(page)
+		if err != nil {
+			return false, err
+		}
+		resumeIdx := -1
+		if resuming {
+			resumeIdx = indexOfKey(items, key, resumeAfter)
+			// Items deleted upstream since the checkpoint was saved shift
+			// subsequent items onto earlier pages, so step back until we find
+			// the page containing the last item we processed.
+			if resumeIdx < 0 && page > 1 && (len(items) == 0 || key(items[0]) > resumeAfter) {
+				page--
+				done = false
+				continue
+			}
+		}
+		for i, item := range items {
+			if resuming {
+				// Skip the items processed prior to the checkpoint, falling
+				// back to comparing keys if the last processed item itself
+				// is no longer listed.
+				if (resumeIdx >= 0 && i <= resumeIdx) || (resumeIdx < 0 && key(item) <= resumeAfter) {
+					continue
+				}
+			}
+			if err := process(item); err != nil {
+				return false, err
+			}
+			lastItem = key(item)
+		}
+		resuming = false
+		page++
+		if !done {
+			cps.Phases[phase] = &FetchCheckpoint{
+				Page:      page,
+				PerPage:   DEFAULT_PER_PAGE,
+				LastItem:  lastItem,
+				UpdatedAt: time.Now(),
+			}
+			if err := cps.Save(rootPath, owner, name, cfg.PrettyJSON); err != nil {
+				return false, err
+			}
+		}
+	}
+	if _, exists := cps.Phases[phase]; exists {
+		delete(cps.Phases, phase)
+		if err := cps.Save(rootPath, owner, name, cfg.PrettyJSON); err != nil {
+			return false, err
+		}
+	}
+	return fromStart, nil
+}
+
+// indexOfKey returns the index of the item with the given key, or -1 if there
+// is no such item.
+func indexOfKey[T any](items []T, key func(T) int64, k int64) int {
+	for i, item := range items {
+		if key(item) == k {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,373 @@
+package ghere
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/google/go-github/v48/github"
+)
+
+const (
+	// CHECKS_COMMITS_LOOKBACK is how far before the last fetch (or, for the
+	// first fetch, before the current time) to look for commits to the default
+	// branch whose statuses and checks must be fetched. Commit dates can
+	// precede the time at which commits were pushed, so this overlaps with
+	// the previous fetch.
+	CHECKS_COMMITS_LOOKBACK time.Duration = 7 * 24 * time.Hour
+	// CHECKS_PENDING_REFETCH_PERIOD is how long after first fetching a
+	// commit's statuses and checks we keep refetching them while some of
+	// them are still pending.
+	CHECKS_PENDING_REFETCH_PERIOD time.Duration = 7 * 24 * time.Hour
+)
+
+// CommitChecks captures the CI state of a specific commit: its combined
+// status (including the individual statuses reported for it), as well as its
+// check suites and check runs (including their conclusions and outputs).
+type CommitChecks struct {
+	SHA            string                 `json:"sha"`
+	CombinedStatus *github.CombinedStatus `json:"combined_status"`
+	CheckSuites    []*github.CheckSuite   `json:"check_suites"`
+	CheckRuns      []*github.CheckRun     `json:"check_runs"`
+
+	FirstFetch time.Time `json:"first_fetch"`
+	LastFetch  time.Time `json:"last_fetch"`
+}
+
+func LoadCommitChecks(rootPath string, repo *Repository, sha string, mustExist bool) (*CommitChecks, error) {
+	path := commitChecksPath(rootPath, repo.GetOwner(), repo.GetName(), sha)
+	return LoadCommitChecksDirect(path, mustExist)
+}
+
+func LoadCommitChecksDirect(path string, mustExist bool) (*CommitChecks, error) {
+	var err error
+	checks := &CommitChecks{}
+	if mustExist {
+		err = readJSONFile(path, checks)
+	} else {
+		err = readJSONFileOrEmpty(path, checks)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commit checks file: %v", err)
+	}
+	return checks, nil
+}
+
+func (c *CommitChecks) Save(rootPath string, repo *Repository, prettyJSON bool) error {
+	path := commitChecksPath(rootPath, repo.GetOwner(), repo.GetName(), c.SHA)
+	if err := writeJSONFile(path, c, prettyJSON); err != nil {
+		return fmt.Errorf("failed to write commit checks file: %v", err)
+	}
+	return nil
+}
+
+// IsComplete returns whether none of the commit's statuses are pending and
+// all of its check runs have completed. Check suites are not considered, as
+// GitHub creates (and never completes) suites for apps that do not end up
+// running any checks.
+func (c *CommitChecks) IsComplete() bool {
+	if c.CombinedStatus == nil {
+		return false
+	}
+	for _, status := range c.CombinedStatus.Statuses {
+		if status.GetState() == "pending" {
+			return false
+		}
+	}
+	for _, run := range c.CheckRuns {
+		if run.GetStatus() != "completed" {
+			return false
+		}
+	}
+	return true
+}
+
+// MustFetch returns whether the commit's statuses and checks have never been
+// fetched, or were still pending when last fetched (for a limited time).
+func (c *CommitChecks) MustFetch(now time.Time) bool {
+	if c.FirstFetch.IsZero() {
+		return true
+	}
+	return !c.IsComplete() && now.Sub(c.FirstFetch) < CHECKS_PENDING_REFETCH_PERIOD
+}
+
+// results returns the parts of the commit's checks that are fetched from
+// GitHub, for comparison between fetches.
+func (c *CommitChecks) results() interface{} {
+	return []interface{}{c.CombinedStatus, c.CheckSuites, c.CheckRuns}
+}
+
+// checksFetcher determines which commits' statuses and checks must be
+// fetched: those of the heads of all pull requests, as well as those of the
+// commits to the default branch since the last fetch.
+type checksFetcher struct {
+	rootPath string
+	repo     *Repository
+}
+
+var _ fetcher = (*checksFetcher)(nil)
+var _ planner = (*checksFetcher)(nil)
+
+func newChecksFetcher(rootPath string, repo *Repository) *checksFetcher {
+	return &checksFetcher{
+		rootPath: rootPath,
+		repo:     repo,
+	}
+}
+
+func (f *checksFetcher) resource() string {
+	return RESOURCE_CHECKS
+}
+
+func (f *checksFetcher) fetch(ctx context.Context, cfg *FetchConfig, log Logger) ([]fetcher, error) {
+	fetchTime := time.Now()
+	since := f.repo.LastChecksFetch
+	if since.IsZero() {
+		since = fetchTime
+	}
+	since = since.Add(-CHECKS_COMMITS_LOOKBACK)
+
+	shas := []string{}
+	seen := make(map[string]bool)
+	addSHA := func(sha string) error {
+		if len(sha) == 0 || seen[sha] {
+			return nil
+		}
+		seen[sha] = true
+		checks, err := LoadCommitChecks(f.rootPath, f.repo, sha, false)
+		if err != nil {
+			return err
+		}
+		if checks.MustFetch(fetchTime) {
+			shas = append(shas, sha)
+		}
+		return nil
+	}
+	if branch := f.repo.Repository.GetDefaultBranch(); len(branch) > 0 {
+		done := false
+		for page := 1; !done; page++ {
+			var commits []*github.RepositoryCommit
+			var err error
+			commits, done, err = cfg.Client.ListCommits(ctx, f.repo.GetOwner(), f.repo.GetName(), branch, since, page)
+			if err != nil {
+				return nil, err
+			}
+			for _, commit := range commits {
+				if err := addSHA(commit.GetSHA()); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	prHeads, err := f.pullRequestHeads()
+	if err != nil {
+		return nil, err
+	}
+	for _, sha := range prHeads {
+		if err := addSHA(sha); err != nil {
+			return nil, err
+		}
+	}
+	log.Info("Computed which commits' statuses and checks should be fetched", "repo", f.repo.String(), "commits", len(shas))
+
+	if len(shas) == 0 {
+		return nil, saveChecksCursor(f.rootPath, f.repo, fetchTime, cfg)
+	}
+	return []fetcher{newCommitChecksFetcher(f.rootPath, f.repo, shas, fetchTime)}, nil
+}
+
+// saveChecksCursor records the time from which commits to the default branch
+// were considered by a fetch of the repository's statuses and checks. It must
+// only be called once all of the fetch's commits' statuses and checks have
+// been fetched, otherwise commits whose fetches failed would be skipped by
+// subsequent fetches.
+func saveChecksCursor(rootPath string, repo *Repository, fetchTime time.Time, cfg *FetchConfig) error {
+	repo.LastChecksFetch = fetchTime
+	return repo.Save(rootPath, cfg.PrettyJSON)
+}
+
+// pullRequestHeads returns the head commit SHAs of all locally stored pull
+// requests.
+func (f *checksFetcher) pullRequestHeads() ([]string, error) {
+	pattern := filepath.Join(repoPullRequestsPath(f.rootPath, f.repo.GetOwner(), f.repo.GetName()), "*", DETAIL_FILENAME)
+	prDetailFiles, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pull requests' detail files from pattern %s: %v", pattern, err)
+	}
+	heads := make([]string, 0, len(prDetailFiles))
+	for _, fn := range prDetailFiles {
+		pr, err := LoadPullRequestDirect(fn, true)
+		if err != nil {
+			return nil, err
+		}
+		heads = append(heads, pr.PullRequest.GetHead().GetSHA())
+	}
+	return heads, nil
+}
+
+func (f *checksFetcher) plan(cfg *FetchConfig) (*FetcherPlan, error) {
+	prHeads, err := f.pullRequestHeads()
+	if err != nil {
+		return nil, err
+	}
+	// The default branch's new commits can only be determined by listing
+	// them, but those of its known commits whose statuses and checks were
+	// still pending will be refetched.
+	pattern := filepath.Join(repoChecksPath(f.rootPath, f.repo.GetOwner(), f.repo.GetName()), "*.json")
+	checksFiles, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list commit checks files from pattern %s: %v", pattern, err)
+	}
+	now := time.Now()
+	count := 0
+	seen := make(map[string]bool)
+	for _, sha := range prHeads {
+		if len(sha) == 0 || seen[sha] {
+			continue
+		}
+		seen[sha] = true
+		checks, err := LoadCommitChecks(f.rootPath, f.repo, sha, false)
+		if err != nil {
+			return nil, err
+		}
+		if checks.MustFetch(now) {
+			count++
+		}
+	}
+	for _, fn := range checksFiles {
+		checks, err := LoadCommitChecksDirect(fn, true)
+		if err != nil {
+			return nil, err
+		}
+		if seen[checks.SHA] {
+			continue
+		}
+		seen[checks.SHA] = true
+		if checks.MustFetch(now) {
+			count++
+		}
+	}
+	return &FetcherPlan{
+		Resource:    RESOURCE_CHECKS,
+		Description: fmt.Sprintf("list recent default branch commits, then fetch statuses and checks for %d known commit(s) and any new default branch commits", count),
+		// Each commit requires at least one call each for its combined
+		// status, check suites and check runs.
+		EstimatedAPICalls: 1 + 3*count,
+	}, nil
+}
+
+// commitChecksFetcher fetches the statuses and checks of specific commits,
+// after which it advances the repository's checks cursor.
+type commitChecksFetcher struct {
+	rootPath string
+	repo     *Repository
+	shas     []string
+	// The time at which the commits were determined.
+	fetchTime time.Time
+}
+
+var _ fetcher = (*commitChecksFetcher)(nil)
+
+func newCommitChecksFetcher(rootPath string, repo *Repository, shas []string, fetchTime time.Time) *commitChecksFetcher {
+	return &commitChecksFetcher{
+		rootPath:  rootPath,
+		repo:      repo,
+		shas:      shas,
+		fetchTime: fetchTime,
+	}
+}
+
+func (f *commitChecksFetcher) resource() string {
+	return RESOURCE_CHECKS
+}
+
+func (f *commitChecksFetcher) fetch(ctx context.Context, cfg *FetchConfig, log Logger) ([]fetcher, error) {
+	for _, sha := range f.shas {
+		latest, err := f.fetchCommitChecks(ctx, cfg, sha)
+		if isCommitUnavailable(err) {
+			log.Warn("Failed to fetch commit statuses and checks; skipping commit", "repo", f.repo.String(), "sha", sha, "err", err)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		checks, err := LoadCommitChecks(f.rootPath, f.repo, sha, false)
+		if err != nil {
+			return nil, err
+		}
+		existed := !checks.FirstFetch.IsZero()
+		cfg.report.recordItem(RESOURCE_CHECKS, existed, checks.results(), latest.results())
+		latest.FirstFetch = checks.FirstFetch
+		if !existed {
+			latest.FirstFetch = latest.LastFetch
+		}
+		if err := latest.Save(f.rootPath, f.repo, cfg.PrettyJSON); err != nil {
+			return nil, err
+		}
+		log.Debug("Fetched commit statuses and checks", "repo", f.repo.String(), "sha", sha, "complete", latest.IsComplete())
+	}
+	return nil, saveChecksCursor(f.rootPath, f.repo, f.fetchTime, cfg)
+}
+
+// isCommitUnavailable returns whether the given error, resulting from a
+// request for a specific commit's statuses or checks, only pertains to that
+// commit (e.g. because it was garbage collected after a force push, or its
+// fork was deleted), as opposed to the fetch as a whole.
+func isCommitUnavailable(err error) bool {
+	var errRes *github.ErrorResponse
+	if !errors.As(err, &errRes) || errRes.Response == nil {
+		return false
+	}
+	switch errRes.Response.StatusCode {
+	case http.StatusNotFound, http.StatusConflict, http.StatusGone, http.StatusUnprocessableEntity:
+		return true
+	}
+	return false
+}
+
+func (f *commitChecksFetcher) fetchCommitChecks(ctx context.Context, cfg *FetchConfig, sha string) (*CommitChecks, error) {
+	checks := &CommitChecks{
+		SHA:         sha,
+		CheckSuites: []*github.CheckSuite{},
+		CheckRuns:   []*github.CheckRun{},
+		LastFetch:   time.Now(),
+	}
+	done := false
+	for page := 1; !done; page++ {
+		var status *github.CombinedStatus
+		var err error
+		status, done, err = cfg.Client.GetCombinedStatus(ctx, f.repo.GetOwner(), f.repo.GetName(), sha, page)
+		if err != nil {
+			return nil, err
+		}
+		if checks.CombinedStatus == nil {
+			checks.CombinedStatus = status
+		} else {
+			checks.CombinedStatus.Statuses = append(checks.CombinedStatus.Statuses, status.Statuses...)
+		}
+	}
+	done = false
+	for page := 1; !done; page++ {
+		var suites []*github.CheckSuite
+		var err error
+		suites, done, err = cfg.Client.ListCheckSuites(ctx, f.repo.GetOwner(), f.repo.GetName(), sha, page)
+		if err != nil {
+			return nil, err
+		}
+		checks.CheckSuites = append(checks.CheckSuites, suites...)
+	}
+	done = false
+	for page := 1; !done; page++ {
+		var runs []*github.CheckRun
+		var err error
+		runs, done, err = cfg.Client.ListCheckRuns(ctx, f.repo.GetOwner(), f.repo.GetName(), sha, page)
+		if err != nil {
+			return nil, err
+		}
+		checks.CheckRuns = append(checks.CheckRuns, runs...)
+	}
+	return checks, nil
+}
@@ -2,26 +2,63 @@ package ghere
 
 import (
 	"context"
+	"fmt"
+	"path/filepath"
 )
 
 type codeFetcher struct {
 	rootPath string
 	repo     *Repository
+	gitOpts  *GitOptions
 }
 
 var _ fetcher = (*codeFetcher)(nil)
+var _ planner = (*codeFetcher)(nil)
 
-func newCodeFetcher(rootPath string, repo *Repository) *codeFetcher {
+func newCodeFetcher(rootPath string, repo *Repository, gitOpts *GitOptions) *codeFetcher {
 	return &codeFetcher{
 		rootPath: rootPath,
 		repo:     repo,
+		gitOpts:  gitOpts,
 	}
 }
 
+func (cf *codeFetcher) resource() string {
+	return RESOURCE_CODE
+}
+
 func (cf *codeFetcher) fetch(ctx context.Context, cfg *FetchConfig, log Logger) ([]fetcher, error) {
 	codePath := repoCodePath(cf.rootPath, cf.repo.GetOwner(), cf.repo.GetName())
-	if err := cfg.RepoUpdater.CloneOrUpdateRepository(ctx, codePath, cf.repo.Repository, cfg.CredentialProvider, log); err != nil {
+	result, err := cfg.RepoUpdater.CloneOrUpdateRepository(ctx, codePath, cf.repo.Repository, cf.gitOpts, cfg.CredentialProvider, log)
+	if err != nil {
 		return nil, err
 	}
+	if result != nil {
+		cfg.report.recordGitRefs(result.UpdatedRefs)
+		cf.repo.MissingSubmodules = result.MissingSubmodules
+		if err := cf.repo.Save(cf.rootPath, cfg.PrettyJSON); err != nil {
+			return nil, err
+		}
+	}
 	return nil, nil
 }
+
+func (cf *codeFetcher) plan(cfg *FetchConfig) (*FetcherPlan, error) {
+	codePath := repoCodePath(cf.rootPath, cf.repo.GetOwner(), cf.repo.GetName())
+	exists, err := dirExists(filepath.Join(codePath, ".git"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to access Git repository in %s: %v", codePath, err)
+	}
+	ops := []string{"clone"}
+	if exists {
+		ops = []string{"pull"}
+	}
+	if cf.gitOpts != nil && len(cf.gitOpts.Branches) > 0 {
+		ops = append(ops, "fetch branches")
+	}
+	return &FetcherPlan{
+		Resource:      RESOURCE_CODE,
+		Description:   fmt.Sprintf("%s Git repository into %s", ops[0], codePath),
+		GitOperations: ops,
+	}, nil
+}
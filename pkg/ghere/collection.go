@@ -4,14 +4,34 @@ import (
 	"context"
 	"fmt"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // LocalCollection captures information about, and facilitates access to, local
 // copies of GitHub repositories.
 type LocalCollection struct {
+	// BaseURL optionally overrides the GitHub API base URL for repositories
+	// that do not specify a host (e.g. if the entire collection is hosted on a
+	// GitHub Enterprise Server instance).
+	BaseURL string `json:"base_url,omitempty"`
+	// UploadURL optionally overrides the GitHub upload URL for repositories
+	// that do not specify a host.
+	UploadURL string `json:"upload_url,omitempty"`
+	// Hosts configures the GitHub Enterprise Server instances to which
+	// repositories can belong, keyed by host name.
+	Hosts map[string]*GitHubHost `json:"hosts,omitempty"`
+	// Schedule optionally configures how often repositories are fetched when
+	// running as a daemon.
+	Schedule *Schedule `json:"schedule,omitempty"`
 	// Repositories is a list of specific repositories to fetch locally.
 	Repositories []*LocalRepository `json:"repositories"`
+	// Projects is a list of specific (v2) projects to fetch locally.
+	Projects []*LocalProject `json:"projects,omitempty"`
+	// Organizations is a list of organizations whose teams are to be fetched
+	// locally.
+	Organizations []*LocalOrganization `json:"organizations,omitempty"`
 
 	configFile string `json:"-"`
 	rootPath   string `json:"-"`
@@ -37,29 +57,37 @@ func (c *LocalCollection) Save() error {
 	return nil
 }
 
+// NewFromPath adds a new repository to the collection from the given path,
+// which must either be of the form "owner/name" or, for repositories hosted
+// on a GitHub Enterprise Server instance, "host/owner/name".
 func (c *LocalCollection) NewFromPath(path string) (*LocalRepository, error) {
 	parts := strings.Split(strings.TrimSpace(path), "/")
-	if len(parts) == 0 || len(parts) > 2 {
+	if len(parts) < 2 || len(parts) > 3 {
 		return nil, fmt.Errorf("invalid GitHub repository path: %s", parts)
 	}
+	host := ""
+	if len(parts) == 3 {
+		host = parts[0]
+		parts = parts[1:]
+		if err := c.validateHost(path, host); err != nil {
+			return nil, err
+		}
+		if strings.EqualFold(host, DEFAULT_GITHUB_HOST) {
+			host = ""
+		}
+	}
 	for _, part := range parts {
-		for _, r := range part {
-			switch {
-			case r == ' ' || r == '-' || r == '_' || r == '.':
-			case r >= '0' && r <= '9':
-			case r >= 'A' && r <= 'Z':
-			case r >= 'a' && r <= 'z':
-			default:
-				return nil, fmt.Errorf("invalid character in path %s: %c", path, r)
-			}
+		if err := validatePathSegment(path, part); err != nil {
+			return nil, err
 		}
 	}
 	for _, repo := range c.Repositories {
-		if repo.Owner == parts[0] && repo.Name == parts[1] {
-			return nil, &ErrRepositoryAlreadyExists{Owner: repo.Owner, Name: repo.Name}
+		if repo.Host == host && repo.Owner == parts[0] && repo.Name == parts[1] {
+			return nil, &ErrRepositoryAlreadyExists{Host: repo.Host, Owner: repo.Owner, Name: repo.Name}
 		}
 	}
 	repo := &LocalRepository{
+		Host:  host,
 		Owner: parts[0],
 		Name:  parts[1],
 	}
@@ -67,22 +95,535 @@ func (c *LocalCollection) NewFromPath(path string) (*LocalRepository, error) {
 	return repo, nil
 }
 
-func (c *LocalCollection) Fetch(ctx context.Context, cfg *FetchConfig, log Logger) error {
-	var err error
-	for _, repo := range c.Repositories {
-		f := newRepoFetcher(c.rootPath, repo.Owner, repo.Name)
-		if e := fetchRecursively(ctx, cfg, []fetcher{f}, log); e != nil {
+// validatePathSegment checks that the given segment of the given path is a
+// valid GitHub owner or repository name. Since segments are used to construct
+// local paths, "." and ".." are never valid.
+func validatePathSegment(path, segment string) error {
+	if len(segment) == 0 || segment == "." || segment == ".." {
+		return fmt.Errorf("invalid path %s: %q is not a valid owner or name", path, segment)
+	}
+	for _, r := range segment {
+		switch {
+		case r == ' ' || r == '-' || r == '_' || r == '.':
+		case r >= '0' && r <= '9':
+		case r >= 'A' && r <= 'Z':
+		case r >= 'a' && r <= 'z':
+		default:
+			return fmt.Errorf("invalid character in path %s: %c", path, r)
+		}
+	}
+	return nil
+}
+
+// validateHostSegment checks that the given segment of the given path is a
+// valid host name, optionally including a port.
+func validateHostSegment(path, host string) error {
+	if len(host) == 0 || host == "." || host == ".." {
+		return fmt.Errorf("invalid path %s: %q is not a valid host name", path, host)
+	}
+	for _, r := range host {
+		switch {
+		case r == '-' || r == '.' || r == ':':
+		case r >= '0' && r <= '9':
+		case r >= 'A' && r <= 'Z':
+		case r >= 'a' && r <= 'z':
+		default:
+			return fmt.Errorf("invalid character in host name in path %s: %c", path, r)
+		}
+	}
+	return nil
+}
+
+// validateHost checks that the given host (from the given path) is either
+// github.com or a GitHub Enterprise Server instance configured in the
+// collection's hosts, since repositories on any other host could never be
+// fetched.
+func (c *LocalCollection) validateHost(path, host string) error {
+	if err := validateHostSegment(path, host); err != nil {
+		return err
+	}
+	if strings.EqualFold(host, DEFAULT_GITHUB_HOST) {
+		return nil
+	}
+	if _, exists := c.Hosts[host]; !exists {
+		return fmt.Errorf("invalid path %s: host %s is not configured in the collection's hosts", path, host)
+	}
+	return nil
+}
+
+// NewOrganization adds a new organization, whose teams are to be fetched, to
+// the collection. The given path must either be of the form "owner" or, for
+// organizations on a GitHub Enterprise Server instance, "host/owner".
+func (c *LocalCollection) NewOrganization(path string) (*LocalOrganization, error) {
+	org, err := parseOrganizationPath(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(org.Host) > 0 {
+		if err := c.validateHost(path, org.Host); err != nil {
+			return nil, err
+		}
+	}
+	if existing := c.findOrganization(org.String()); existing != nil {
+		return nil, &ErrOrganizationAlreadyExists{Host: existing.Host, Owner: existing.Owner}
+	}
+	c.Organizations = append(c.Organizations, org)
+	return org, nil
+}
+
+// IsOrganizationPath returns whether the given path refers to an
+// organization (as opposed to a repository or project). GitHub owner names
+// cannot contain dots, which distinguishes "host/owner" from "owner/name".
+func IsOrganizationPath(path string) bool {
+	parts := strings.Split(strings.TrimSpace(path), "/")
+	return len(parts) == 1 || (len(parts) == 2 && strings.Contains(parts[0], "."))
+}
+
+func parseOrganizationPath(path string) (*LocalOrganization, error) {
+	parts := strings.Split(strings.TrimSpace(path), "/")
+	if !IsOrganizationPath(path) || len(parts[len(parts)-1]) == 0 {
+		return nil, fmt.Errorf("invalid GitHub organization path: %s", path)
+	}
+	org := &LocalOrganization{Owner: parts[len(parts)-1]}
+	if err := validatePathSegment(path, org.Owner); err != nil {
+		return nil, err
+	}
+	if len(parts) == 2 {
+		if err := validateHostSegment(path, parts[0]); err != nil {
+			return nil, err
+		}
+		if !strings.EqualFold(parts[0], DEFAULT_GITHUB_HOST) {
+			org.Host = parts[0]
+		}
+	}
+	return org, nil
+}
+
+// findOrganization finds the organization in the collection with the given
+// path, which is of the same form as accepted by
+// [LocalCollection.NewOrganization]. Returns nil if no such organization
+// exists.
+func (c *LocalCollection) findOrganization(path string) *LocalOrganization {
+	target, err := parseOrganizationPath(path)
+	if err != nil {
+		return nil
+	}
+	for _, org := range c.Organizations {
+		if strings.EqualFold(org.String(), target.String()) {
+			return org
+		}
+	}
+	return nil
+}
+
+// selectOrganizations returns the organizations in the collection to which
+// the given fetch configuration applies. Organizations explicitly specified in
+// the fetch configuration must be part of the collection, even if teams are
+// not being fetched, so that mistyped repository paths are not silently
+// ignored.
+func (c *LocalCollection) selectOrganizations(cfg *FetchConfig) ([]*LocalOrganization, error) {
+	orgs := c.Organizations
+	if len(cfg.Repositories) > 0 {
+		orgs = []*LocalOrganization{}
+		for _, path := range cfg.Repositories {
+			if !IsOrganizationPath(path) {
+				continue
+			}
+			org := c.findOrganization(path)
+			if org == nil {
+				return nil, fmt.Errorf("organization %s is not part of the collection (repositories must be specified as owner/name)", path)
+			}
+			orgs = append(orgs, org)
+		}
+	}
+	if !selectResources(cfg.Resources)[RESOURCE_TEAMS] {
+		return nil, nil
+	}
+	return orgs, nil
+}
+
+// fetchTarget is a repository, project or organization in the collection that
+// can be fetched.
+type fetchTarget interface {
+	String() string
+	// hostName returns the GitHub Enterprise Server host of the target, or an
+	// empty string for github.com.
+	hostName() string
+	// rootFetcher returns the fetcher from which fetching the target starts,
+	// along with the type of resource reported if the target could not be
+	// fetched at all.
+	rootFetcher(c *LocalCollection, cfg *FetchConfig) (fetcher, string)
+}
+
+// NewProjectFromPath adds a new (v2) project to the collection from the given
+// path, which must either be of the form "owner/projects/number" for
+// organization-level projects or "owner/name/projects/number" for projects
+// linked to a repository, optionally prefixed by the host name for projects on
+// a GitHub Enterprise Server instance.
+func (c *LocalCollection) NewProjectFromPath(path string) (*LocalProject, error) {
+	project, err := parseProjectPath(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(project.Host) > 0 {
+		if err := c.validateHost(path, project.Host); err != nil {
+			return nil, err
+		}
+	}
+	if existing := c.findProject(project.String()); existing != nil {
+		return nil, &ErrProjectAlreadyExists{Path: existing.String()}
+	}
+	c.Projects = append(c.Projects, project)
+	return project, nil
+}
+
+// IsProjectPath returns whether the given path refers to a project (as
+// opposed to a repository).
+func IsProjectPath(path string) bool {
+	parts := strings.Split(strings.TrimSpace(path), "/")
+	return len(parts) >= 3 && parts[len(parts)-2] == "projects"
+}
+
+func parseProjectPath(path string) (*LocalProject, error) {
+	parts := strings.Split(strings.TrimSpace(path), "/")
+	if !IsProjectPath(path) || len(parts) > 5 {
+		return nil, fmt.Errorf("invalid GitHub project path: %s", path)
+	}
+	number, err := strconv.Atoi(parts[len(parts)-1])
+	if err != nil || number <= 0 {
+		return nil, fmt.Errorf("invalid project number in path %s", path)
+	}
+	project := &LocalProject{Number: number}
+	parts = parts[:len(parts)-2]
+	// We need to distinguish between "host/owner" and "owner/name".
+	switch {
+	case len(parts) == 3:
+		project.Host, project.Owner, project.Repository = parts[0], parts[1], parts[2]
+	case len(parts) == 2 && strings.Contains(parts[0], "."):
+		project.Host, project.Owner = parts[0], parts[1]
+	case len(parts) == 2:
+		project.Owner, project.Repository = parts[0], parts[1]
+	default:
+		project.Owner = parts[0]
+	}
+	if len(project.Host) > 0 {
+		if err := validateHostSegment(path, project.Host); err != nil {
+			return nil, err
+		}
+	}
+	if err := validatePathSegment(path, project.Owner); err != nil {
+		return nil, err
+	}
+	if len(project.Repository) > 0 {
+		if err := validatePathSegment(path, project.Repository); err != nil {
+			return nil, err
+		}
+	}
+	if strings.EqualFold(project.Host, DEFAULT_GITHUB_HOST) {
+		project.Host = ""
+	}
+	return project, nil
+}
+
+// findProject finds the project in the collection with the given path, which
+// is of the same form as accepted by [LocalCollection.NewProjectFromPath].
+// Returns nil if no such project exists.
+func (c *LocalCollection) findProject(path string) *LocalProject {
+	target, err := parseProjectPath(path)
+	if err != nil {
+		return nil
+	}
+	for _, project := range c.Projects {
+		if strings.EqualFold(project.String(), target.String()) {
+			return project
+		}
+	}
+	return nil
+}
+
+// selectProjects returns the projects in the collection to which the given
+// fetch configuration applies.
+func (c *LocalCollection) selectProjects(cfg *FetchConfig) ([]*LocalProject, error) {
+	projects := c.Projects
+	if len(cfg.Repositories) > 0 {
+		projects = []*LocalProject{}
+		for _, path := range cfg.Repositories {
+			if !IsProjectPath(path) {
+				continue
+			}
+			project := c.findProject(path)
+			if project == nil {
+				return nil, fmt.Errorf("project %s is not part of the collection", path)
+			}
+			projects = append(projects, project)
+		}
+	}
+	if !selectResources(cfg.Resources)[RESOURCE_PROJECTS] {
+		return nil, nil
+	}
+	return projects, nil
+}
+
+// Fetch fetches all of the selected repositories (followed by all of the
+// selected projects and organizations) in the collection, returning a report
+// summarizing the outcome of the fetch for each repository/project/
+// organization. A report is returned even if an error occurs.
+func (c *LocalCollection) Fetch(ctx context.Context, cfg *FetchConfig, log Logger) (*FetchReport, error) {
+	report := &FetchReport{
+		StartedAt:    time.Now(),
+		Repositories: []*RepositoryReport{},
+	}
+	defer func() {
+		report.FinishedAt = time.Now()
+		report.DurationSeconds = report.FinishedAt.Sub(report.StartedAt).Seconds()
+	}()
+	targets, err := c.selectTargets(cfg)
+	if err != nil {
+		return report, err
+	}
+	completed := []string{}
+	for i, target := range targets {
+		if ctx.Err() != nil {
+			return report, fetchInterrupted(ctx, completed, "", targets[i:])
+		}
+		targetReport := newRepositoryReport(target.String())
+		report.Repositories = append(report.Repositories, targetReport)
+		f, rootResource := target.rootFetcher(c, cfg)
+		hostCfg, e := cfg.forHost(target.hostName())
+		if e == nil {
+			targetCfg := *hostCfg
+			targetCfg.report = targetReport
+			targetCtx, counter := withRequestCounter(ctx)
+			e = fetchRecursively(targetCtx, &targetCfg, []fetcher{f}, log)
+			targetReport.finish(targetCfg.Client, counter)
+		} else {
+			targetReport.recordError(rootResource, e)
+			targetReport.finish(nil, nil)
+		}
+		cfg.Metrics.observeRepository(targetReport)
+		if ctx.Err() != nil {
+			return report, fetchInterrupted(ctx, completed, target.String(), targets[i+1:])
+		}
+		if e == nil {
+			completed = append(completed, target.String())
+		}
+		if e != nil {
 			if cfg.FailFast {
-				return e
+				return report, e
 			}
-			log.Error("Failed to completely fetch repository", "repo", repo.Owner+"/"+repo.Name, "err", e)
+			log.Error("Failed to completely fetch "+rootResource, "path", target.String(), "err", e)
 			err = e
 		}
 	}
-	return err
+	return report, err
+}
+
+// selectTargets returns the repositories, followed by the projects and
+// organizations, in the collection to which the given fetch configuration
+// applies.
+func (c *LocalCollection) selectTargets(cfg *FetchConfig) ([]fetchTarget, error) {
+	repos, err := c.selectRepositories(cfg)
+	if err != nil {
+		return nil, err
+	}
+	projects, err := c.selectProjects(cfg)
+	if err != nil {
+		return nil, err
+	}
+	orgs, err := c.selectOrganizations(cfg)
+	if err != nil {
+		return nil, err
+	}
+	targets := make([]fetchTarget, 0, len(repos)+len(projects)+len(orgs))
+	for _, repo := range repos {
+		targets = append(targets, repo)
+	}
+	for _, project := range projects {
+		targets = append(targets, project)
+	}
+	for _, org := range orgs {
+		targets = append(targets, org)
+	}
+	return targets, nil
+}
+
+func fetchInterrupted(ctx context.Context, completed []string, interrupted string, remainingTargets []fetchTarget) error {
+	remaining := []string{}
+	for _, target := range remainingTargets {
+		remaining = append(remaining, target.String())
+	}
+	return &ErrFetchInterrupted{
+		Cause:       ctx.Err(),
+		Completed:   completed,
+		Interrupted: interrupted,
+		Remaining:   remaining,
+	}
+}
+
+// selectRepositories returns the repositories in the collection to which the
+// given fetch configuration applies.
+func (c *LocalCollection) selectRepositories(cfg *FetchConfig) ([]*LocalRepository, error) {
+	if err := cfg.Resources.Validate(); err != nil {
+		return nil, err
+	}
+	for _, repo := range c.Repositories {
+		if err := repo.ResourceSelection.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid configuration for repository %s: %v", repo.String(), err)
+		}
+	}
+	if len(cfg.Repositories) == 0 {
+		return c.Repositories, nil
+	}
+	repos := make([]*LocalRepository, 0, len(cfg.Repositories))
+	for _, path := range cfg.Repositories {
+		if IsProjectPath(path) || IsOrganizationPath(path) {
+			continue
+		}
+		repo := c.findRepository(path)
+		if repo == nil {
+			return nil, fmt.Errorf("repository %s is not part of the collection", path)
+		}
+		repos = append(repos, repo)
+	}
+	return repos, nil
+}
+
+// findRepository finds the repository in the collection with the given path,
+// which is of the same form as accepted by [LocalCollection.NewFromPath].
+// Returns nil if no such repository exists.
+func (c *LocalCollection) findRepository(path string) *LocalRepository {
+	path = strings.TrimSpace(path)
+	if strings.HasPrefix(strings.ToLower(path), DEFAULT_GITHUB_HOST+"/") {
+		path = path[len(DEFAULT_GITHUB_HOST)+1:]
+	}
+	for _, repo := range c.Repositories {
+		// GitHub owner and repository names are case-insensitive.
+		if strings.EqualFold(repo.String(), path) {
+			return repo
+		}
+	}
+	return nil
+}
+
+// repoRootPath returns the root path relative to which the given repository's
+// data is stored. Repositories hosted on GitHub Enterprise Server instances are
+// namespaced by host.
+func (c *LocalCollection) repoRootPath(repo *LocalRepository) string {
+	return c.hostRootPath(repo.Host)
+}
+
+// hostRootPath returns the root path relative to which data from the given
+// GitHub Enterprise Server host (or github.com, if empty) is stored.
+func (c *LocalCollection) hostRootPath(host string) string {
+	if len(host) == 0 {
+		return c.rootPath
+	}
+	return hostRootPath(c.rootPath, host)
+}
+
+// LocalProject is a (v2) project to be fetched locally. Projects belong to an
+// organization, and are either addressed at the organization level or via a
+// repository to which they are linked.
+type LocalProject struct {
+	// Host is the host name of the GitHub Enterprise Server instance on which
+	// this project is hosted. Empty for projects hosted on github.com.
+	Host  string `json:"host,omitempty"`
+	Owner string `json:"owner"`
+	// Repository is the name of the repository via which the project is
+	// addressed, if any. Such projects are stored along with the repository.
+	Repository string `json:"repository,omitempty"`
+	Number     int    `json:"number"`
+}
+
+func (p *LocalProject) String() string {
+	path := p.Owner
+	if len(p.Repository) > 0 {
+		path += "/" + p.Repository
+	}
+	path += fmt.Sprintf("/projects/%d", p.Number)
+	if len(p.Host) > 0 {
+		return p.Host + "/" + path
+	}
+	return path
+}
+
+func (p *LocalProject) hostName() string {
+	return p.Host
+}
+
+func (p *LocalProject) rootFetcher(c *LocalCollection, cfg *FetchConfig) (fetcher, string) {
+	return newProjectFetcher(c.hostRootPath(p.Host), p.Owner, p.Repository, p.Number), RESOURCE_PROJECTS
+}
+
+// LocalOrganization is an organization whose organization-level resources
+// (i.e. its teams) are to be fetched locally.
+type LocalOrganization struct {
+	// Host is the host name of the GitHub Enterprise Server instance on which
+	// this organization is hosted. Empty for organizations hosted on
+	// github.com.
+	Host  string `json:"host,omitempty"`
+	Owner string `json:"owner"`
+}
+
+func (o *LocalOrganization) String() string {
+	if len(o.Host) > 0 {
+		return o.Host + "/" + o.Owner
+	}
+	return o.Owner
+}
+
+func (o *LocalOrganization) hostName() string {
+	return o.Host
+}
+
+func (o *LocalOrganization) rootFetcher(c *LocalCollection, cfg *FetchConfig) (fetcher, string) {
+	return newTeamsFetcher(c.hostRootPath(o.Host), o.Owner), RESOURCE_TEAMS
+}
+
+// GitHubHost describes how to access a GitHub Enterprise Server instance.
+type GitHubHost struct {
+	// BaseURL is the API base URL for the instance, e.g.
+	// "https://github.example.com/api/v3/".
+	BaseURL string `json:"base_url"`
+	// UploadURL is the upload URL for the instance. Defaults to BaseURL if not
+	// specified.
+	UploadURL string `json:"upload_url,omitempty"`
+	// TokenEnvVar is the name of the environment variable from which to obtain
+	// the access token(s) for this instance. Multiple tokens, between which
+	// requests are rotated, can be separated by commas or whitespace. Defaults
+	// to GITHUB_TOKEN.
+	TokenEnvVar string `json:"token_env_var,omitempty"`
 }
 
 type LocalRepository struct {
+	// Host is the host name of the GitHub Enterprise Server instance on which
+	// this repository is hosted. Empty for repositories hosted on github.com
+	// (or on the instance configured by the collection's BaseURL).
+	Host  string `json:"host,omitempty"`
 	Owner string `json:"owner"`
 	Name  string `json:"name"`
+	// Git optionally customizes how this repository's code is cloned/updated
+	// (e.g. shallow or single-branch clones).
+	Git *GitOptions `json:"git,omitempty"`
+	// Schedule optionally overrides the collection's schedule for this
+	// repository when running as a daemon.
+	Schedule *Schedule `json:"schedule,omitempty"`
+	// ResourceSelection optionally restricts which of this repository's
+	// resources are fetched by default (e.g. to never fetch its code).
+	ResourceSelection
+}
+
+func (r *LocalRepository) String() string {
+	if len(r.Host) > 0 {
+		return r.Host + "/" + r.Owner + "/" + r.Name
+	}
+	return r.Owner + "/" + r.Name
+}
+
+func (r *LocalRepository) hostName() string {
+	return r.Host
+}
+
+func (r *LocalRepository) rootFetcher(c *LocalCollection, cfg *FetchConfig) (fetcher, string) {
+	return newRepoFetcher(c.repoRootPath(r), r.Owner, r.Name, r.Git, selectResources(&r.ResourceSelection, cfg.Resources)), RESOURCE_REPOSITORY
 }
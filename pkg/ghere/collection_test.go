@@ -1,13 +1,26 @@
 package ghere_test
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/go-github/v48/github"
 	"github.com/informalsystems/ghere/pkg/ghere"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCollectionFetching(t *testing.T) {
@@ -46,7 +59,7 @@ func TestCollectionFetching(t *testing.T) {
 		PrettyJSON:         true,
 	}
 
-	err = coll.Fetch(context.Background(), cfg, log)
+	_, err = coll.Fetch(context.Background(), cfg, log)
 	assert.NoError(t, err)
 
 	localRepoDir := filepath.Join(tmpDir, owner, name)
@@ -59,3 +72,1558 @@ func TestCollectionFetching(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, mockClient.Repositories[repoID], repo.Repository)
 }
+
+func TestCollectionFetchingWithHosts(t *testing.T) {
+	log := ghere.NewNoopLogger()
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, ghere.CONFIG_FILE_NAME)
+	coll, err := ghere.LoadOrCreateLocalCollection(configFile)
+	assert.NoError(t, err)
+
+	host := "github.example.com"
+	owner := "org"
+	name := "repo"
+	coll.Hosts = map[string]*ghere.GitHubHost{
+		host: {BaseURL: "https://github.example.com/api/v3/"},
+	}
+	_, err = coll.NewFromPath(owner + "/" + name)
+	assert.NoError(t, err)
+	_, err = coll.NewFromPath(host + "/" + owner + "/" + name)
+	assert.NoError(t, err)
+	_, err = coll.NewFromPath(host + "/" + owner + "/" + name)
+	assert.IsType(t, &ghere.ErrRepositoryAlreadyExists{}, err)
+	// Hosts must be valid, and must have been configured.
+	for _, path := range []string{"../org/repo", "gh*e.example.com/org/repo", "ghe.example.com/org/repo"} {
+		_, err = coll.NewFromPath(path)
+		assert.Error(t, err, path)
+	}
+	_, err = coll.NewProjectFromPath("ghe.example.com/org/projects/1")
+	assert.Error(t, err)
+	_, err = coll.NewOrganization("ghe.example.com/org")
+	assert.Error(t, err)
+	require.Len(t, coll.Repositories, 2)
+
+	repoID := owner + "/" + name
+	newMockClient := func(description string) *MockGitHubClient {
+		return &MockGitHubClient{
+			Repositories: map[string]*github.Repository{
+				repoID: {
+					Owner: &github.User{
+						Login: &owner,
+					},
+					Name:        &name,
+					Description: &description,
+				},
+			},
+		}
+	}
+	defaultClient := newMockClient("github.com")
+	hostClient := newMockClient(host)
+
+	cfg := &ghere.FetchConfig{
+		Client:             defaultClient,
+		CredentialProvider: &MockGitHubCredentialProvider{},
+		RepoUpdater:        &MockGitHubRepositoryUpdater{},
+		Hosts: map[string]*ghere.HostFetchConfig{
+			host: {
+				Client:             hostClient,
+				CredentialProvider: &MockGitHubCredentialProvider{},
+			},
+		},
+	}
+
+	_, err = coll.Fetch(context.Background(), cfg, log)
+	assert.NoError(t, err)
+
+	for _, tc := range []struct {
+		detailFile string
+		expected   *github.Repository
+	}{
+		{filepath.Join(tmpDir, owner, name, ghere.DETAIL_FILENAME), defaultClient.Repositories[repoID]},
+		{filepath.Join(tmpDir, host, owner, name, ghere.DETAIL_FILENAME), hostClient.Repositories[repoID]},
+	} {
+		repo := &ghere.Repository{}
+		err = ghere.ReadJSONFile(tc.detailFile, repo)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expected, repo.Repository)
+	}
+}
+
+// cancellingGitHubClient cancels the fetch the moment a repository's details
+// have been fetched.
+type cancellingGitHubClient struct {
+	*MockGitHubClient
+	cancel context.CancelFunc
+}
+
+func (c *cancellingGitHubClient) GetRepository(ctx context.Context, owner string, name string) (*github.Repository, error) {
+	defer c.cancel()
+	return c.MockGitHubClient.GetRepository(ctx, owner, name)
+}
+
+func TestCollectionFetchingInterrupted(t *testing.T) {
+	log := ghere.NewNoopLogger()
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, ghere.CONFIG_FILE_NAME)
+	coll, err := ghere.LoadOrCreateLocalCollection(configFile)
+	assert.NoError(t, err)
+
+	owner := "org"
+	mockClient := &MockGitHubClient{Repositories: map[string]*github.Repository{}}
+	for _, name := range []string{"repo1", "repo2"} {
+		name := name
+		_, err = coll.NewFromPath(owner + "/" + name)
+		assert.NoError(t, err)
+		mockClient.Repositories[owner+"/"+name] = &github.Repository{
+			Owner: &github.User{Login: &owner},
+			Name:  &name,
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cfg := &ghere.FetchConfig{
+		Client:             &cancellingGitHubClient{MockGitHubClient: mockClient, cancel: cancel},
+		CredentialProvider: &MockGitHubCredentialProvider{},
+		RepoUpdater:        &MockGitHubRepositoryUpdater{},
+	}
+
+	_, err = coll.Fetch(ctx, cfg, log)
+	require.ErrorIs(t, err, context.Canceled)
+	interrupted := &ghere.ErrFetchInterrupted{}
+	require.ErrorAs(t, err, &interrupted)
+	assert.Empty(t, interrupted.Completed)
+	assert.Equal(t, "org/repo1", interrupted.Interrupted)
+	assert.Equal(t, []string{"org/repo2"}, interrupted.Remaining)
+
+	// Progress made before the interruption must have been persisted.
+	repo := &ghere.Repository{}
+	err = ghere.ReadJSONFile(filepath.Join(tmpDir, owner, "repo1", ghere.DETAIL_FILENAME), repo)
+	assert.NoError(t, err)
+	assert.Equal(t, "repo1", repo.GetName())
+}
+
+// flakyIssuesGitHubClient fails to list a specific page of issues once, and
+// records the pages of issues requested.
+type flakyIssuesGitHubClient struct {
+	*MockGitHubClient
+	failPage       int
+	requestedPages []int
+}
+
+func (c *flakyIssuesGitHubClient) ListRepositoryIssues(ctx context.Context, owner string, name string, page int) ([]*github.Issue, bool, error) {
+	c.requestedPages = append(c.requestedPages, page)
+	if page == c.failPage {
+		c.failPage = 0
+		return nil, false, errors.New("connection reset by peer")
+	}
+	return c.MockGitHubClient.ListRepositoryIssues(ctx, owner, name, page)
+}
+
+func TestCollectionFetchingResumesFromCheckpoint(t *testing.T) {
+	log := ghere.NewNoopLogger()
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, ghere.CONFIG_FILE_NAME)
+	coll, err := ghere.LoadOrCreateLocalCollection(configFile)
+	assert.NoError(t, err)
+
+	owner := "org"
+	name := "repo"
+	repoID := owner + "/" + name
+	_, err = coll.NewFromPath(repoID)
+	assert.NoError(t, err)
+
+	issues := []*github.Issue{}
+	for i := 1; i <= 250; i++ {
+		issues = append(issues, &github.Issue{Number: github.Int(i)})
+	}
+	client := &flakyIssuesGitHubClient{
+		MockGitHubClient: &MockGitHubClient{
+			Repositories: map[string]*github.Repository{
+				repoID: {
+					Owner:     &github.User{Login: &owner},
+					Name:      &name,
+					UpdatedAt: &github.Timestamp{Time: time.Now()},
+				},
+			},
+			Labels:       map[string][]*github.Label{repoID: {}},
+			PullRequests: map[string][]*github.PullRequest{repoID: {}},
+			Issues:       map[string][]*github.Issue{repoID: issues},
+		},
+		failPage: 3,
+	}
+	cfg := &ghere.FetchConfig{
+		Client:             client,
+		CredentialProvider: &MockGitHubCredentialProvider{},
+		RepoUpdater:        &MockGitHubRepositoryUpdater{},
+	}
+
+	_, err = coll.Fetch(context.Background(), cfg, log)
+	require.Error(t, err)
+	assert.Equal(t, []int{1, 2, 3}, client.requestedPages)
+
+	checkpointsFile := filepath.Join(tmpDir, owner, name, ghere.CHECKPOINTS_FILENAME)
+	cps := &ghere.FetchCheckpoints{}
+	require.NoError(t, ghere.ReadJSONFile(checkpointsFile, cps))
+	require.Contains(t, cps.Phases, "issues")
+	assert.Equal(t, 3, cps.Phases["issues"].Page)
+	assert.Equal(t, int64(200), cps.Phases["issues"].LastItem)
+
+	// Deleting issues upstream shifts the remaining issues onto earlier pages,
+	// so we expect the fetcher to step back a page to find where it left off.
+	client.Issues[repoID] = issues[50:]
+	client.requestedPages = nil
+	_, err = coll.Fetch(context.Background(), cfg, log)
+	require.NoError(t, err)
+	assert.Equal(t, []int{3, 2, 3}, client.requestedPages)
+	assert.NoFileExists(t, checkpointsFile)
+	assert.FileExists(t, filepath.Join(tmpDir, owner, name, "issues", "000250", ghere.DETAIL_FILENAME))
+}
+
+// Transferred issues keep their original creation time but receive new
+// (higher) numbers, so listings ordered by creation time do not have monotonic
+// keys.
+func TestCollectionFetchingNonMonotonicKeys(t *testing.T) {
+	log := ghere.NewNoopLogger()
+	tmpDir := t.TempDir()
+	coll, err := ghere.LoadOrCreateLocalCollection(filepath.Join(tmpDir, ghere.CONFIG_FILE_NAME))
+	require.NoError(t, err)
+
+	owner := "org"
+	name := "repo"
+	repoID := owner + "/" + name
+	_, err = coll.NewFromPath(repoID)
+	require.NoError(t, err)
+
+	issues := []*github.Issue{{Number: github.Int(1000)}}
+	for i := 1; i < 200; i++ {
+		issues = append(issues, &github.Issue{Number: github.Int(i)})
+	}
+	issues = append(issues, &github.Issue{Number: github.Int(500)})
+	for i := 200; i < 249; i++ {
+		issues = append(issues, &github.Issue{Number: github.Int(i)})
+	}
+	client := &flakyIssuesGitHubClient{
+		MockGitHubClient: &MockGitHubClient{
+			Repositories: map[string]*github.Repository{
+				repoID: {
+					Owner:     &github.User{Login: &owner},
+					Name:      &name,
+					UpdatedAt: &github.Timestamp{Time: time.Now()},
+				},
+			},
+			Labels:       map[string][]*github.Label{repoID: {}},
+			PullRequests: map[string][]*github.PullRequest{repoID: {}},
+			Issues:       map[string][]*github.Issue{repoID: issues},
+		},
+	}
+	cfg := &ghere.FetchConfig{
+		Client:             client,
+		CredentialProvider: &MockGitHubCredentialProvider{},
+		RepoUpdater:        &MockGitHubRepositoryUpdater{},
+	}
+	issuesPath := filepath.Join(tmpDir, owner, name, "issues")
+
+	// A fresh fetch must not skip items whose keys are lower than those of
+	// items listed before them.
+	_, err = coll.Fetch(context.Background(), cfg, log)
+	require.NoError(t, err)
+	for _, issue := range issues {
+		assert.FileExists(t, filepath.Join(issuesPath, fmt.Sprintf("%06d", issue.GetNumber()), ghere.DETAIL_FILENAME))
+	}
+
+	// Neither must a resumed fetch.
+	require.NoError(t, os.RemoveAll(issuesPath))
+	client.failPage = 3
+	client.requestedPages = nil
+	_, err = coll.Fetch(context.Background(), cfg, log)
+	require.Error(t, err)
+	cps := &ghere.FetchCheckpoints{}
+	require.NoError(t, ghere.ReadJSONFile(filepath.Join(tmpDir, owner, name, ghere.CHECKPOINTS_FILENAME), cps))
+	assert.Equal(t, int64(199), cps.Phases["issues"].LastItem)
+	client.requestedPages = nil
+	_, err = coll.Fetch(context.Background(), cfg, log)
+	require.NoError(t, err)
+	// The first issue on page 3 has a higher number than the last one
+	// processed, which is indistinguishable from issues having been deleted,
+	// so the fetcher steps back a page to look for the last processed issue.
+	assert.Equal(t, []int{3, 2, 3}, client.requestedPages)
+	for _, issue := range issues {
+		assert.FileExists(t, filepath.Join(issuesPath, fmt.Sprintf("%06d", issue.GetNumber()), ghere.DETAIL_FILENAME))
+	}
+}
+
+func TestCollectionPlan(t *testing.T) {
+	log := ghere.NewNoopLogger()
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, ghere.CONFIG_FILE_NAME)
+	coll, err := ghere.LoadOrCreateLocalCollection(configFile)
+	assert.NoError(t, err)
+
+	owner := "org"
+	name := "repo"
+	repoID := owner + "/" + name
+	_, err = coll.NewFromPath(repoID)
+	assert.NoError(t, err)
+
+	// Only repository details are available, so planning will fail if any
+	// list endpoints are called.
+	cfg := &ghere.FetchConfig{
+		Client: &MockGitHubClient{
+			Repositories: map[string]*github.Repository{
+				repoID: {
+					Owner:     &github.User{Login: &owner},
+					Name:      &name,
+					UpdatedAt: &github.Timestamp{Time: time.Now()},
+				},
+			},
+		},
+		CredentialProvider: &MockGitHubCredentialProvider{},
+		RepoUpdater:        &MockGitHubRepositoryUpdater{},
+	}
+
+	planResources := func() ([]string, *ghere.RepositoryFetchPlan) {
+		plan, err := coll.Plan(context.Background(), cfg, log)
+		require.NoError(t, err)
+		require.Len(t, plan.Repositories, 1)
+		repoPlan := plan.Repositories[0]
+		assert.Empty(t, repoPlan.Error)
+		assert.Equal(t, repoID, repoPlan.Repository)
+		resources := []string{}
+		for _, fetcherPlan := range repoPlan.Fetchers {
+			resources = append(resources, fetcherPlan.Resource)
+		}
+		return resources, repoPlan
+	}
+
+	resources, repoPlan := planResources()
+	assert.Equal(t, []string{"code", "labels", "pull_requests", "issues", "releases"}, resources)
+	assert.Equal(t, 5, repoPlan.EstimatedAPICalls)
+	assert.Equal(t, 1, repoPlan.EstimatedGitOperations)
+	assert.Equal(t, []string{"clone"}, repoPlan.Fetchers[0].GitOperations)
+
+	// Resources that are not fetched by default must be explicitly included.
+	cfg.Resources = &ghere.ResourceSelection{Include: []string{ghere.RESOURCE_DISCUSSIONS, ghere.RESOURCE_ACTIONS, ghere.RESOURCE_CHECKS}}
+	resources, repoPlan = planResources()
+	assert.Equal(t, []string{"code", "labels", "pull_requests", "issues", "discussions", "actions", "checks", "releases"}, resources)
+	assert.Equal(t, 10, repoPlan.EstimatedAPICalls)
+
+	// When using the events feed, reading it must be planned for, along with
+	// the full listings to which fetches fall back.
+	cfg.Resources = nil
+	cfg.UseEvents = true
+	resources, repoPlan = planResources()
+	assert.Equal(t, []string{"repository", "code", "labels", "pull_requests", "issues", "releases"}, resources)
+	assert.Equal(t, 6, repoPlan.EstimatedAPICalls)
+	assert.Contains(t, repoPlan.Fetchers[0].Description, "not estimated")
+
+	// Nothing must have been written
+	assert.NoDirExists(t, filepath.Join(tmpDir, owner))
+}
+
+func TestCollectionSelectiveFetching(t *testing.T) {
+	log := ghere.NewNoopLogger()
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, ghere.CONFIG_FILE_NAME)
+	coll, err := ghere.LoadOrCreateLocalCollection(configFile)
+	assert.NoError(t, err)
+
+	owner := "org"
+	mockClient := &MockGitHubClient{Repositories: map[string]*github.Repository{}}
+	for _, name := range []string{"repo1", "repo2"} {
+		name := name
+		repo, err := coll.NewFromPath(owner + "/" + name)
+		assert.NoError(t, err)
+		// We never want repo1's code
+		if name == "repo1" {
+			repo.Skip = []string{ghere.RESOURCE_CODE}
+		}
+		mockClient.Repositories[owner+"/"+name] = &github.Repository{
+			Owner:     &github.User{Login: &owner},
+			Name:      &name,
+			UpdatedAt: &github.Timestamp{Time: time.Now()},
+		}
+	}
+
+	cfg := &ghere.FetchConfig{
+		Client:             mockClient,
+		CredentialProvider: &MockGitHubCredentialProvider{},
+		RepoUpdater:        &MockGitHubRepositoryUpdater{},
+		Repositories:       []string{"github.com/Org/Repo1"},
+		Resources: &ghere.ResourceSelection{
+			Only: []string{ghere.RESOURCE_CODE, ghere.RESOURCE_LABELS, ghere.RESOURCE_ISSUES},
+		},
+	}
+	plan, err := coll.Plan(context.Background(), cfg, log)
+	require.NoError(t, err)
+	require.Len(t, plan.Repositories, 1)
+	assert.Equal(t, "org/repo1", plan.Repositories[0].Repository)
+	resources := []string{}
+	for _, fetcherPlan := range plan.Repositories[0].Fetchers {
+		resources = append(resources, fetcherPlan.Resource)
+	}
+	assert.Equal(t, []string{ghere.RESOURCE_LABELS, ghere.RESOURCE_ISSUES}, resources)
+
+	cfg.Repositories = []string{"org/nonexistent"}
+	_, err = coll.Plan(context.Background(), cfg, log)
+	assert.Error(t, err)
+
+	cfg.Repositories = nil
+	cfg.Resources = &ghere.ResourceSelection{Skip: []string{"wikis"}}
+	_, err = coll.Plan(context.Background(), cfg, log)
+	assert.Error(t, err)
+}
+
+func TestCollectionFetchReport(t *testing.T) {
+	log := ghere.NewNoopLogger()
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, ghere.CONFIG_FILE_NAME)
+	coll, err := ghere.LoadOrCreateLocalCollection(configFile)
+	assert.NoError(t, err)
+
+	owner := "org"
+	name := "repo"
+	repoID := owner + "/" + name
+	_, err = coll.NewFromPath(repoID)
+	assert.NoError(t, err)
+	_, err = coll.NewFromPath(owner + "/missing")
+	assert.NoError(t, err)
+
+	newLabel := func(id int64, labelName string) *github.Label {
+		return &github.Label{ID: github.Int64(id), Name: github.String(labelName)}
+	}
+	mockClient := &MockGitHubClient{
+		Repositories: map[string]*github.Repository{
+			repoID: {
+				Owner:     &github.User{Login: &owner},
+				Name:      &name,
+				UpdatedAt: &github.Timestamp{Time: time.Now().Add(-time.Minute)},
+			},
+		},
+		Labels:       map[string][]*github.Label{repoID: {newLabel(1, "bug"), newLabel(2, "feature")}},
+		PullRequests: map[string][]*github.PullRequest{repoID: {}},
+		Issues:       map[string][]*github.Issue{repoID: {}},
+	}
+	cfg := &ghere.FetchConfig{
+		Client:             mockClient,
+		CredentialProvider: &MockGitHubCredentialProvider{},
+		RepoUpdater:        &MockGitHubRepositoryUpdater{},
+	}
+
+	report, err := coll.Fetch(context.Background(), cfg, log)
+	require.Error(t, err)
+	require.Len(t, report.Repositories, 2)
+	repoReport := report.Repositories[0]
+	assert.Empty(t, repoReport.Error)
+	assert.Equal(t, 1, repoReport.Resources[ghere.RESOURCE_REPOSITORY].Created)
+	assert.Equal(t, 2, repoReport.Resources[ghere.RESOURCE_LABELS].Created)
+	require.Len(t, report.Failed(), 1)
+	assert.Equal(t, "org/missing", report.Failed()[0].Repository)
+	assert.Equal(t, ghere.RESOURCE_REPOSITORY, report.Failed()[0].ErrorResource)
+	assert.Equal(t, ghere.FETCH_ERROR_OTHER, report.Failed()[0].ErrorType)
+
+	// One label updated, one deleted and one created upstream.
+	mockClient.Repositories[repoID].UpdatedAt = &github.Timestamp{Time: time.Now()}
+	mockClient.Labels[repoID] = []*github.Label{newLabel(1, "defect"), newLabel(3, "docs")}
+	cfg.Repositories = []string{repoID}
+	report, err = coll.Fetch(context.Background(), cfg, log)
+	require.NoError(t, err)
+	require.Len(t, report.Repositories, 1)
+	labels := report.Repositories[0].Resources[ghere.RESOURCE_LABELS]
+	assert.Equal(t, &ghere.ResourceReport{
+		Created:         1,
+		Updated:         1,
+		Deleted:         1,
+		DurationSeconds: labels.DurationSeconds,
+	}, labels)
+	assert.Equal(t, 1, report.Repositories[0].Resources[ghere.RESOURCE_REPOSITORY].Updated)
+}
+
+func TestCollectionFetchReportConcurrentAPICalls(t *testing.T) {
+	log := ghere.NewNoopLogger()
+	// Requests are counted per repository, and slowed down so that concurrent
+	// fetches overlap.
+	var mtx sync.Mutex
+	requests := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+		require.GreaterOrEqual(t, len(parts), 3)
+		mtx.Lock()
+		requests[parts[1]+"/"+parts[2]]++
+		mtx.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		if len(parts) == 3 {
+			fmt.Fprintf(w, `{"name": %q, "owner": {"login": %q}, "updated_at": %q}`, parts[2], parts[1], time.Now().Format(time.RFC3339))
+			return
+		}
+		fmt.Fprint(w, `[]`)
+	}))
+	defer srv.Close()
+	baseURL, err := url.Parse(srv.URL + "/")
+	require.NoError(t, err)
+	githubClient := github.NewClient(nil)
+	githubClient.BaseURL = baseURL
+	client := ghere.NewGitHubClient(githubClient, nil, log)
+
+	// Fetches sharing a client must only report their own API calls.
+	repoIDs := []string{"org/repo1", "org/repo2"}
+	reports := make([]*ghere.FetchReport, len(repoIDs))
+	var wg sync.WaitGroup
+	for i, repoID := range repoIDs {
+		tmpDir := t.TempDir()
+		coll, err := ghere.LoadOrCreateLocalCollection(filepath.Join(tmpDir, ghere.CONFIG_FILE_NAME))
+		require.NoError(t, err)
+		_, err = coll.NewFromPath(repoID)
+		require.NoError(t, err)
+		cfg := &ghere.FetchConfig{
+			Client:             client,
+			CredentialProvider: &MockGitHubCredentialProvider{},
+			RepoUpdater:        &MockGitHubRepositoryUpdater{},
+			Resources:          &ghere.ResourceSelection{Only: []string{ghere.RESOURCE_LABELS, ghere.RESOURCE_ISSUES}},
+		}
+		wg.Add(1)
+		go func(i int, coll *ghere.LocalCollection, cfg *ghere.FetchConfig) {
+			defer wg.Done()
+			report, err := coll.Fetch(context.Background(), cfg, log)
+			assert.NoError(t, err)
+			reports[i] = report
+		}(i, coll, cfg)
+	}
+	wg.Wait()
+	for i, repoID := range repoIDs {
+		require.Len(t, reports[i].Repositories, 1)
+		assert.Greater(t, requests[repoID], 1)
+		assert.Equal(t, requests[repoID], reports[i].Repositories[0].APICalls, repoID)
+		assert.Zero(t, reports[i].Repositories[0].Retries)
+	}
+}
+
+func TestCollectionFetchMetrics(t *testing.T) {
+	log := ghere.NewNoopLogger()
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, ghere.CONFIG_FILE_NAME)
+	coll, err := ghere.LoadOrCreateLocalCollection(configFile)
+	assert.NoError(t, err)
+
+	owner := "org"
+	name := "repo"
+	repoID := owner + "/" + name
+	_, err = coll.NewFromPath(repoID)
+	assert.NoError(t, err)
+	_, err = coll.NewFromPath(owner + "/missing")
+	assert.NoError(t, err)
+
+	mockClient := &MockGitHubClient{
+		Repositories: map[string]*github.Repository{
+			repoID: {
+				Owner:     &github.User{Login: &owner},
+				Name:      &name,
+				UpdatedAt: &github.Timestamp{Time: time.Now()},
+			},
+		},
+		Labels: map[string][]*github.Label{repoID: {
+			{ID: github.Int64(1), Name: github.String("bug")},
+			{ID: github.Int64(2), Name: github.String("feature")},
+		}},
+		PullRequests: map[string][]*github.PullRequest{repoID: {}},
+		Issues:       map[string][]*github.Issue{repoID: {}},
+	}
+	metrics := ghere.NewMetrics()
+	cfg := &ghere.FetchConfig{
+		Client:             mockClient,
+		CredentialProvider: &MockGitHubCredentialProvider{},
+		RepoUpdater:        &MockGitHubRepositoryUpdater{},
+		Metrics:            metrics,
+	}
+	_, err = coll.Fetch(context.Background(), cfg, log)
+	require.Error(t, err)
+
+	metricsFile := filepath.Join(tmpDir, "ghere.prom")
+	require.NoError(t, metrics.WriteTextfile(metricsFile))
+	b, err := os.ReadFile(metricsFile)
+	require.NoError(t, err)
+	exposition := string(b)
+	assert.Contains(t, exposition, `ghere_items_written_total{repo="org/repo",resource="labels"} 2`)
+	assert.Contains(t, exposition, `ghere_repository_fetch_duration_seconds_count{repo="org/repo"} 1`)
+	assert.Contains(t, exposition, `ghere_repository_fetch_duration_seconds_count{repo="org/missing"} 1`)
+	assert.Contains(t, exposition, `ghere_repository_fetch_failures_total{repo="org/missing",error_type="other"} 1`)
+	assert.Contains(t, exposition, `ghere_repository_last_successful_fetch_timestamp_seconds{repo="org/repo"}`)
+	assert.NotContains(t, exposition, `ghere_repository_last_successful_fetch_timestamp_seconds{repo="org/missing"}`)
+}
+
+// countingGitHubClient counts the calls made to list and get issues.
+type countingGitHubClient struct {
+	*MockGitHubClient
+	issueListings int
+	issueGets     []int
+}
+
+func (c *countingGitHubClient) ListRepositoryIssues(ctx context.Context, owner string, name string, page int) ([]*github.Issue, bool, error) {
+	c.issueListings++
+	return c.MockGitHubClient.ListRepositoryIssues(ctx, owner, name, page)
+}
+
+func (c *countingGitHubClient) GetIssue(ctx context.Context, owner string, name string, issueNum int) (*github.Issue, error) {
+	c.issueGets = append(c.issueGets, issueNum)
+	return c.MockGitHubClient.GetIssue(ctx, owner, name, issueNum)
+}
+
+func TestCollectionFetchingUsingEvents(t *testing.T) {
+	log := ghere.NewNoopLogger()
+	tmpDir := t.TempDir()
+	coll, err := ghere.LoadOrCreateLocalCollection(filepath.Join(tmpDir, ghere.CONFIG_FILE_NAME))
+	require.NoError(t, err)
+
+	owner := "org"
+	name := "repo"
+	repoID := owner + "/" + name
+	_, err = coll.NewFromPath(repoID)
+	require.NoError(t, err)
+
+	newEvent := func(id, eventType, payload string) *github.Event {
+		raw := json.RawMessage(payload)
+		return &github.Event{ID: github.String(id), Type: github.String(eventType), RawPayload: &raw}
+	}
+	newIssue := func(num int, title string) *github.Issue {
+		return &github.Issue{Number: github.Int(num), Title: github.String(title)}
+	}
+	client := &countingGitHubClient{
+		MockGitHubClient: &MockGitHubClient{
+			Repositories: map[string]*github.Repository{
+				repoID: {Owner: &github.User{Login: &owner}, Name: &name, UpdatedAt: &github.Timestamp{Time: time.Now()}},
+			},
+			Issues:       map[string][]*github.Issue{repoID: {newIssue(1, "First"), newIssue(2, "Second")}},
+			PullRequests: map[string][]*github.PullRequest{repoID: {}},
+			Labels:       map[string][]*github.Label{repoID: {}},
+			Events: map[string][]*github.Event{repoID: {
+				newEvent("10", "IssuesEvent", `{"action":"opened","issue":{"number":2}}`),
+			}},
+		},
+	}
+	cfg := &ghere.FetchConfig{
+		Client:             client,
+		CredentialProvider: &MockGitHubCredentialProvider{},
+		RepoUpdater:        &MockGitHubRepositoryUpdater{},
+		UseEvents:          true,
+	}
+
+	// Without a previously processed event, all issues must be listed.
+	_, err = coll.Fetch(context.Background(), cfg, log)
+	require.NoError(t, err)
+	assert.Equal(t, 1, client.issueListings)
+	assert.Empty(t, client.issueGets)
+	repo, err := ghere.LoadRepository(tmpDir, owner, name, true)
+	require.NoError(t, err)
+	assert.Equal(t, "10", repo.LastEventID)
+
+	// Only the issue that changed since the last processed event must be
+	// fetched.
+	client.Issues[repoID][1] = newIssue(2, "Second (edited)")
+	client.Events[repoID] = []*github.Event{
+		newEvent("11", "IssueCommentEvent", `{"action":"created","issue":{"number":2},"comment":{"id":1}}`),
+		newEvent("10", "IssuesEvent", `{"action":"opened","issue":{"number":2}}`),
+	}
+	_, err = coll.Fetch(context.Background(), cfg, log)
+	require.NoError(t, err)
+	assert.Equal(t, 1, client.issueListings)
+	assert.Equal(t, []int{2}, client.issueGets)
+	issue, err := ghere.LoadIssueDirect(filepath.Join(tmpDir, owner, name, "issues", "000002", ghere.DETAIL_FILENAME), true)
+	require.NoError(t, err)
+	assert.Equal(t, "Second (edited)", issue.Issue.GetTitle())
+	repo, err = ghere.LoadRepository(tmpDir, owner, name, true)
+	require.NoError(t, err)
+	assert.Equal(t, "11", repo.LastEventID)
+
+	// If we reach the end of the feed without finding the last processed
+	// event, the feed nevertheless contains all changes since then.
+	client.Events[repoID] = []*github.Event{
+		newEvent("13", "IssuesEvent", `{"action":"closed","issue":{"number":1}}`),
+		newEvent("12", "IssuesEvent", `{"action":"closed","issue":{"number":2}}`),
+	}
+	_, err = coll.Fetch(context.Background(), cfg, log)
+	require.NoError(t, err)
+	assert.Equal(t, 1, client.issueListings)
+	assert.Equal(t, []int{2, 1, 2}, client.issueGets)
+	repo, err = ghere.LoadRepository(tmpDir, owner, name, true)
+	require.NoError(t, err)
+	assert.Equal(t, "13", repo.LastEventID)
+
+	// As is the case for an empty feed.
+	client.Events[repoID] = []*github.Event{}
+	_, err = coll.Fetch(context.Background(), cfg, log)
+	require.NoError(t, err)
+	assert.Equal(t, 1, client.issueListings)
+	assert.Equal(t, []int{2, 1, 2}, client.issueGets)
+	repo, err = ghere.LoadRepository(tmpDir, owner, name, true)
+	require.NoError(t, err)
+	assert.Equal(t, "13", repo.LastEventID)
+
+	// If the last processed event is no longer in a full feed, we must fall
+	// back to listing all issues, even if the repository does not appear to
+	// be stale, lest the changes in the missed events be lost once the cursor
+	// is advanced.
+	repo.LastIssueCommentsFetch = time.Now()
+	require.NoError(t, repo.Save(tmpDir, false))
+	watchEvents := func(latest, after int) []*github.Event {
+		events := []*github.Event{}
+		for id := latest; id > after; id-- {
+			events = append(events, newEvent(fmt.Sprint(id), "WatchEvent", `{"action":"started"}`))
+		}
+		return events
+	}
+	client.Events[repoID] = watchEvents(313, 13)
+	_, err = coll.Fetch(context.Background(), cfg, log)
+	require.NoError(t, err)
+	assert.Equal(t, 2, client.issueListings)
+	repo, err = ghere.LoadRepository(tmpDir, owner, name, true)
+	require.NoError(t, err)
+	assert.Equal(t, "313", repo.LastEventID)
+
+	// The same applies if an event cannot be parsed.
+	client.Events[repoID] = []*github.Event{
+		newEvent("315", "IssuesEvent", `{"action":`),
+		newEvent("314", "IssuesEvent", `{"action":"closed","issue":{"number":1}}`),
+	}
+	_, err = coll.Fetch(context.Background(), cfg, log)
+	require.NoError(t, err)
+	assert.Equal(t, 3, client.issueListings)
+	repo, err = ghere.LoadRepository(tmpDir, owner, name, true)
+	require.NoError(t, err)
+	assert.Equal(t, "315", repo.LastEventID)
+
+	client.Events[repoID] = watchEvents(615, 315)
+	_, err = coll.Fetch(context.Background(), cfg, log)
+	require.NoError(t, err)
+	assert.Equal(t, 4, client.issueListings)
+	assert.Equal(t, []int{2, 1, 2}, client.issueGets)
+	repo, err = ghere.LoadRepository(tmpDir, owner, name, true)
+	require.NoError(t, err)
+	assert.Equal(t, "615", repo.LastEventID)
+}
+
+func TestCollectionFetchingUsingEventsMissingIssue(t *testing.T) {
+	log := ghere.NewNoopLogger()
+	tmpDir := t.TempDir()
+	coll, err := ghere.LoadOrCreateLocalCollection(filepath.Join(tmpDir, ghere.CONFIG_FILE_NAME))
+	require.NoError(t, err)
+
+	owner := "org"
+	name := "repo"
+	repoID := owner + "/" + name
+	_, err = coll.NewFromPath(repoID)
+	require.NoError(t, err)
+
+	newEvent := func(id, eventType, payload string) *github.Event {
+		raw := json.RawMessage(payload)
+		return &github.Event{ID: github.String(id), Type: github.String(eventType), RawPayload: &raw}
+	}
+	newIssue := func(num int, title string) *github.Issue {
+		return &github.Issue{Number: github.Int(num), Title: github.String(title)}
+	}
+	client := &countingGitHubClient{
+		MockGitHubClient: &MockGitHubClient{
+			Repositories: map[string]*github.Repository{
+				repoID: {Owner: &github.User{Login: &owner}, Name: &name, UpdatedAt: &github.Timestamp{Time: time.Now()}},
+			},
+			Issues:       map[string][]*github.Issue{repoID: {newIssue(1, "First"), newIssue(2, "Second")}},
+			PullRequests: map[string][]*github.PullRequest{repoID: {}},
+			Labels:       map[string][]*github.Label{repoID: {}},
+			Events: map[string][]*github.Event{repoID: {
+				newEvent("10", "IssuesEvent", `{"action":"opened","issue":{"number":2}}`),
+			}},
+		},
+	}
+	cfg := &ghere.FetchConfig{
+		Client:             client,
+		CredentialProvider: &MockGitHubCredentialProvider{},
+		RepoUpdater:        &MockGitHubRepositoryUpdater{},
+		UseEvents:          true,
+	}
+	_, err = coll.Fetch(context.Background(), cfg, log)
+	require.NoError(t, err)
+
+	// Issue 2 was transferred to another repository after being changed, and
+	// an event refers to issue 3, which has since been deleted. Neither may
+	// prevent the remaining changes from being fetched, nor the cursor from
+	// advancing.
+	client.Issues[repoID] = []*github.Issue{newIssue(1, "First (edited)")}
+	client.Events[repoID] = []*github.Event{
+		newEvent("13", "IssuesEvent", `{"action":"edited","issue":{"number":1}}`),
+		newEvent("12", "IssuesEvent", `{"action":"opened","issue":{"number":3}}`),
+		newEvent("11", "IssuesEvent", `{"action":"edited","issue":{"number":2}}`),
+		newEvent("10", "IssuesEvent", `{"action":"opened","issue":{"number":2}}`),
+	}
+	report, err := coll.Fetch(context.Background(), cfg, log)
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3}, client.issueGets)
+	issue, err := ghere.LoadIssueDirect(filepath.Join(tmpDir, owner, name, "issues", "000001", ghere.DETAIL_FILENAME), true)
+	require.NoError(t, err)
+	assert.Equal(t, "First (edited)", issue.Issue.GetTitle())
+	repo, err := ghere.LoadRepository(tmpDir, owner, name, true)
+	require.NoError(t, err)
+	assert.Equal(t, "13", repo.LastEventID)
+	require.Len(t, report.Repositories, 1)
+	issuesReport := report.Repositories[0].Resources[ghere.RESOURCE_ISSUES]
+	assert.Equal(t, 1, issuesReport.Updated)
+	assert.Equal(t, 1, issuesReport.Deleted)
+
+	// If only issues are selected, changes to pull requests would be lost if
+	// the cursor were advanced.
+	client.Events[repoID] = []*github.Event{
+		newEvent("14", "IssuesEvent", `{"action":"edited","issue":{"number":1}}`),
+	}
+	cfg.Resources = &ghere.ResourceSelection{Only: []string{ghere.RESOURCE_ISSUES}}
+	_, err = coll.Fetch(context.Background(), cfg, log)
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3, 1}, client.issueGets)
+	repo, err = ghere.LoadRepository(tmpDir, owner, name, true)
+	require.NoError(t, err)
+	assert.Equal(t, "13", repo.LastEventID)
+}
+
+func TestCollectionFetchTimelineEvents(t *testing.T) {
+	log := ghere.NewNoopLogger()
+	tmpDir := t.TempDir()
+	coll, err := ghere.LoadOrCreateLocalCollection(filepath.Join(tmpDir, ghere.CONFIG_FILE_NAME))
+	require.NoError(t, err)
+
+	owner := "org"
+	name := "repo"
+	repoID := owner + "/" + name
+	_, err = coll.NewFromPath(repoID)
+	require.NoError(t, err)
+
+	updatedAt := time.Now()
+	createdAt := time.Date(2022, 11, 1, 12, 0, 0, 0, time.UTC)
+	client := &MockGitHubClient{
+		Repositories: map[string]*github.Repository{
+			repoID: {Owner: &github.User{Login: &owner}, Name: &name, UpdatedAt: &github.Timestamp{Time: updatedAt}},
+		},
+		Labels: map[string][]*github.Label{repoID: {}},
+		Issues: map[string][]*github.Issue{repoID: {
+			{Number: github.Int(1), UpdatedAt: &updatedAt},
+		}},
+		IssueComments: map[string]map[int][]*github.IssueComment{repoID: {1: {}}},
+		PullRequests: map[string][]*github.PullRequest{repoID: {
+			{Number: github.Int(2), UpdatedAt: &updatedAt},
+		}},
+		PullRequestReviews:  map[string]map[int][]*github.PullRequestReview{repoID: {2: {}}},
+		PullRequestComments: map[string]map[int][]*github.PullRequestComment{repoID: {2: {}}},
+		Timelines: map[string]map[int][]*github.Timeline{repoID: {
+			1: {
+				{ID: github.Int64(101), Event: github.String("labeled"), CreatedAt: &createdAt},
+				{
+					Event:     github.String("cross-referenced"),
+					CreatedAt: &createdAt,
+					Actor:     &github.User{ID: github.Int64(7)},
+					Source:    &github.Source{Issue: &github.Issue{ID: github.Int64(9001)}},
+				},
+				// Events without IDs, of the same type and created at the
+				// same time, by different users.
+				{Event: github.String("unknown"), CreatedAt: &createdAt, Actor: &github.User{ID: github.Int64(7)}},
+				{Event: github.String("unknown"), CreatedAt: &createdAt, Actor: &github.User{ID: github.Int64(8)}},
+			},
+			2: {
+				{SHA: github.String("abc123"), Event: github.String("committed")},
+			},
+		}},
+	}
+	cfg := &ghere.FetchConfig{
+		Client:             client,
+		CredentialProvider: &MockGitHubCredentialProvider{},
+		RepoUpdater:        &MockGitHubRepositoryUpdater{},
+	}
+
+	// Timelines must not be fetched unless explicitly selected.
+	_, err = coll.Fetch(context.Background(), cfg, log)
+	require.NoError(t, err)
+	repoPath := filepath.Join(tmpDir, owner, name)
+	assert.FileExists(t, filepath.Join(repoPath, "issues", "000001", ghere.DETAIL_FILENAME))
+	assert.NoDirExists(t, filepath.Join(repoPath, "issues", "000001", "events"))
+	assert.NoDirExists(t, filepath.Join(repoPath, "pull-requests", "000002", "events"))
+
+	// Otherwise, the timelines of issues and pull requests whose timelines
+	// have never been fetched must be fetched.
+	cfg.Resources = &ghere.ResourceSelection{Include: []string{ghere.RESOURCE_TIMELINES}}
+	_, err = coll.Fetch(context.Background(), cfg, log)
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(repoPath, "issues", "000001", "events", "101.json"))
+	assert.FileExists(t, filepath.Join(repoPath, "issues", "000001", "events", fmt.Sprintf("cross-referenced-%d-7-9001.json", createdAt.Unix())))
+	assert.FileExists(t, filepath.Join(repoPath, "issues", "000001", "events", fmt.Sprintf("unknown-%d-7.json", createdAt.Unix())))
+	assert.FileExists(t, filepath.Join(repoPath, "issues", "000001", "events", fmt.Sprintf("unknown-%d-8.json", createdAt.Unix())))
+	assert.FileExists(t, filepath.Join(repoPath, "pull-requests", "000002", "events", "abc123.json"))
+
+	// Timelines must not be refetched for issues and pull requests that have
+	// not been updated since.
+	client.Timelines = nil
+	client.Repositories[repoID].UpdatedAt = &github.Timestamp{Time: time.Now()}
+	_, err = coll.Fetch(context.Background(), cfg, log)
+	require.NoError(t, err)
+}
+
+func TestCollectionFetchReactions(t *testing.T) {
+	log := ghere.NewNoopLogger()
+	tmpDir := t.TempDir()
+	coll, err := ghere.LoadOrCreateLocalCollection(filepath.Join(tmpDir, ghere.CONFIG_FILE_NAME))
+	require.NoError(t, err)
+
+	owner := "org"
+	name := "repo"
+	repoID := owner + "/" + name
+	_, err = coll.NewFromPath(repoID)
+	require.NoError(t, err)
+
+	ghRepo := &github.Repository{Owner: &github.User{Login: &owner}, Name: &name, UpdatedAt: &github.Timestamp{Time: time.Now()}}
+	updatedAt := time.Now()
+	newReaction := func(id int64, login, content string) *github.Reaction {
+		return &github.Reaction{ID: github.Int64(id), User: &github.User{Login: github.String(login)}, Content: github.String(content)}
+	}
+	client := &MockGitHubClient{
+		Repositories: map[string]*github.Repository{repoID: ghRepo},
+		Labels:       map[string][]*github.Label{repoID: {}},
+		PullRequests: map[string][]*github.PullRequest{repoID: {}},
+		Issues: map[string][]*github.Issue{repoID: {
+			{
+				Number:    github.Int(1),
+				UpdatedAt: &updatedAt,
+				Reactions: &github.Reactions{TotalCount: github.Int(2), PlusOne: github.Int(1), Heart: github.Int(1)},
+			},
+			{Number: github.Int(2), UpdatedAt: &updatedAt},
+		}},
+		IssueComments: map[string]map[int][]*github.IssueComment{repoID: {
+			1: {{
+				ID:        github.Int64(11),
+				IssueURL:  github.String("https://api.github.com/repos/org/repo/issues/1"),
+				Reactions: &github.Reactions{TotalCount: github.Int(1), MinusOne: github.Int(1)},
+			}},
+			2: {},
+		}},
+		Timelines: map[string]map[int][]*github.Timeline{repoID: {1: {}, 2: {}}},
+		Reactions: map[string]map[string][]*github.Reaction{repoID: {
+			"issue/1":          {newReaction(1, "alice", "+1"), newReaction(2, "bob", "heart")},
+			"issue_comment/11": {newReaction(3, "carol", "-1")},
+			"release/4001":     {newReaction(4, "dave", "rocket")},
+		}},
+		// Release 4002 has no reactions, so none must be fetched for it.
+		Releases: map[string][]*ghere.GitHubRelease{repoID: {
+			{
+				RepositoryRelease: &github.RepositoryRelease{ID: github.Int64(4002), TagName: github.String("v0.2.0")},
+				Reactions:         &github.Reactions{TotalCount: github.Int(0)},
+			},
+			{
+				RepositoryRelease: &github.RepositoryRelease{ID: github.Int64(4001), TagName: github.String("v0.1.0")},
+				Reactions:         &github.Reactions{TotalCount: github.Int(1), Rocket: github.Int(1)},
+			},
+		}},
+	}
+	cfg := &ghere.FetchConfig{
+		Client:             client,
+		CredentialProvider: &MockGitHubCredentialProvider{},
+		RepoUpdater:        &MockGitHubRepositoryUpdater{},
+		FetchReactions:     true,
+	}
+
+	_, err = coll.Fetch(context.Background(), cfg, log)
+	require.NoError(t, err)
+	repoPath := filepath.Join(tmpDir, owner, name)
+	issue, err := ghere.LoadIssueDirect(filepath.Join(repoPath, "issues", "000001", ghere.DETAIL_FILENAME), true)
+	require.NoError(t, err)
+	assert.Equal(t, client.Reactions[repoID]["issue/1"], issue.Reactions)
+	comment, err := ghere.LoadIssueCommentDirect(filepath.Join(repoPath, "issues", "000001", "comments", "11.json"), true)
+	require.NoError(t, err)
+	assert.Equal(t, client.Reactions[repoID]["issue_comment/11"], comment.Reactions)
+	release, err := ghere.LoadReleaseDirect(filepath.Join(repoPath, "releases", "4001.json"), true)
+	require.NoError(t, err)
+	assert.Equal(t, "v0.1.0", release.Release.GetTagName())
+	assert.Equal(t, client.Reactions[repoID]["release/4001"], release.Reactions)
+	release, err = ghere.LoadReleaseDirect(filepath.Join(repoPath, "releases", "4002.json"), true)
+	require.NoError(t, err)
+	assert.Equal(t, "v0.2.0", release.Release.GetTagName())
+	assert.Empty(t, release.Reactions)
+
+	// Reactions to issues, comments and releases must only be refetched when
+	// their summaries change.
+	client.Repositories[repoID].UpdatedAt = &github.Timestamp{Time: time.Now()}
+	delete(client.Reactions[repoID], "issue/1")
+	delete(client.Reactions[repoID], "issue_comment/11")
+	delete(client.Reactions[repoID], "release/4001")
+	_, err = coll.Fetch(context.Background(), cfg, log)
+	require.NoError(t, err)
+	issue, err = ghere.LoadIssueDirect(filepath.Join(repoPath, "issues", "000001", ghere.DETAIL_FILENAME), true)
+	require.NoError(t, err)
+	assert.Len(t, issue.Reactions, 2)
+	release, err = ghere.LoadReleaseDirect(filepath.Join(repoPath, "releases", "4001.json"), true)
+	require.NoError(t, err)
+	assert.Len(t, release.Reactions, 1)
+
+	// Reactions to comments must be refreshed even when neither the comment
+	// nor its issue changed otherwise.
+	client.IssueComments[repoID][1][0].Reactions = &github.Reactions{TotalCount: github.Int(2), MinusOne: github.Int(1), Eyes: github.Int(1)}
+	client.Reactions[repoID]["issue_comment/11"] = []*github.Reaction{newReaction(3, "carol", "-1"), newReaction(5, "erin", "eyes")}
+	_, err = coll.Fetch(context.Background(), cfg, log)
+	require.NoError(t, err)
+	comment, err = ghere.LoadIssueCommentDirect(filepath.Join(repoPath, "issues", "000001", "comments", "11.json"), true)
+	require.NoError(t, err)
+	assert.Equal(t, client.Reactions[repoID]["issue_comment/11"], comment.Reactions)
+	assert.Equal(t, 2, comment.Comment.GetReactions().GetTotalCount())
+
+	// Reactions to comments must not be refreshed more often than the
+	// configured interval.
+	cfg.CommentReactionsInterval = time.Hour
+	client.IssueComments[repoID][1][0].Reactions = &github.Reactions{TotalCount: github.Int(1), Eyes: github.Int(1)}
+	client.Reactions[repoID]["issue_comment/11"] = []*github.Reaction{newReaction(5, "erin", "eyes")}
+	_, err = coll.Fetch(context.Background(), cfg, log)
+	require.NoError(t, err)
+	comment, err = ghere.LoadIssueCommentDirect(filepath.Join(repoPath, "issues", "000001", "comments", "11.json"), true)
+	require.NoError(t, err)
+	assert.Len(t, comment.Reactions, 2)
+	repo, err := ghere.LoadRepository(tmpDir, owner, name, true)
+	require.NoError(t, err)
+	repo.LastCommentReactionsFetch = time.Now().Add(-2 * time.Hour)
+	require.NoError(t, repo.Save(tmpDir, false))
+	_, err = coll.Fetch(context.Background(), cfg, log)
+	require.NoError(t, err)
+	comment, err = ghere.LoadIssueCommentDirect(filepath.Join(repoPath, "issues", "000001", "comments", "11.json"), true)
+	require.NoError(t, err)
+	assert.Equal(t, client.Reactions[repoID]["issue_comment/11"], comment.Reactions)
+}
+
+func TestCollectionFetchProjects(t *testing.T) {
+	log := ghere.NewNoopLogger()
+	tmpDir := t.TempDir()
+	coll, err := ghere.LoadOrCreateLocalCollection(filepath.Join(tmpDir, ghere.CONFIG_FILE_NAME))
+	require.NoError(t, err)
+	_, err = coll.NewProjectFromPath("org/projects/1")
+	require.NoError(t, err)
+	_, err = coll.NewProjectFromPath("org/repo/projects/2")
+	require.NoError(t, err)
+	_, err = coll.NewProjectFromPath("github.com/org/projects/1")
+	require.IsType(t, &ghere.ErrProjectAlreadyExists{}, err)
+	for _, path := range []string{"../projects/1", "org/../projects/1", "../org/projects/1", "org/re*po/projects/1"} {
+		_, err = coll.NewProjectFromPath(path)
+		assert.Error(t, err, path)
+	}
+	require.Len(t, coll.Projects, 2)
+
+	updatedAt := time.Now().UTC()
+	status := "Done"
+	client := &MockGitHubClient{
+		Projects: map[string]*ghere.GitHubProjectV2{
+			"org/projects/1":      {ID: "PVT_1", Number: 1, Title: "Roadmap", UpdatedAt: updatedAt},
+			"org/repo/projects/2": {ID: "PVT_2", Number: 2, Title: "Triage", UpdatedAt: updatedAt},
+		},
+		ProjectFields: map[string][]*ghere.GitHubProjectV2Field{
+			"PVT_1": {{
+				Typename: "ProjectV2SingleSelectField",
+				ID:       "PVTSSF_1",
+				Name:     "Status",
+				DataType: "SINGLE_SELECT",
+				Options:  []*ghere.GitHubProjectV2FieldOption{{ID: "opt1", Name: status}},
+			}},
+		},
+		ProjectItems: map[string][]*ghere.GitHubProjectV2Item{
+			"PVT_1": {{
+				ID:   "PVTI_1",
+				Type: "ISSUE",
+				Content: &ghere.GitHubProjectV2ItemContent{
+					Typename:   "Issue",
+					Number:     12,
+					Repository: &ghere.GitHubRepositoryRef{NameWithOwner: "org/repo"},
+				},
+				FieldValues: []*ghere.GitHubProjectV2ItemFieldValue{{
+					Typename: "ProjectV2ItemFieldSingleSelectValue",
+					Field:    &ghere.GitHubProjectV2FieldRef{ID: "PVTSSF_1", Name: "Status"},
+					Name:     &status,
+				}},
+			}},
+			"PVT_2": {},
+		},
+	}
+	cfg := &ghere.FetchConfig{
+		Client:             client,
+		CredentialProvider: &MockGitHubCredentialProvider{},
+		RepoUpdater:        &MockGitHubRepositoryUpdater{},
+	}
+
+	planItemFetches := func() map[string][]string {
+		plan, err := coll.Plan(context.Background(), cfg, log)
+		require.NoError(t, err)
+		fetches := map[string][]string{}
+		for _, projectPlan := range plan.Repositories {
+			assert.Empty(t, projectPlan.Error)
+			fetches[projectPlan.Repository] = []string{}
+			for _, fetcherPlan := range projectPlan.Fetchers {
+				fetches[projectPlan.Repository] = append(fetches[projectPlan.Repository], fetcherPlan.Resource)
+			}
+		}
+		return fetches
+	}
+	assert.Equal(t, map[string][]string{
+		"org/projects/1":      {ghere.RESOURCE_PROJECT_ITEMS},
+		"org/repo/projects/2": {ghere.RESOURCE_PROJECT_ITEMS},
+	}, planItemFetches())
+	assert.NoDirExists(t, filepath.Join(tmpDir, "org"))
+
+	_, err = coll.Fetch(context.Background(), cfg, log)
+	require.NoError(t, err)
+	orgProjectPath := filepath.Join(tmpDir, "org", "_org", "projects", "000001")
+	project, err := ghere.LoadProject(tmpDir, "org", "", 1, true)
+	require.NoError(t, err)
+	assert.Equal(t, "Roadmap", project.Project.Title)
+	assert.False(t, project.LastItemsFetch.IsZero())
+	assert.FileExists(t, filepath.Join(orgProjectPath, "fields", "PVTSSF_1.json"))
+	item := &ghere.ProjectItem{}
+	require.NoError(t, ghere.ReadJSONFile(filepath.Join(orgProjectPath, "items", "PVTI_1.json"), item))
+	assert.Equal(t, "org/repo/issues/000012", item.LocalPath())
+	require.Len(t, item.Item.FieldValues, 1)
+	assert.Equal(t, status, *item.Item.FieldValues[0].Name)
+	assert.FileExists(t, filepath.Join(tmpDir, "org", "repo", "projects", "000002", ghere.DETAIL_FILENAME))
+
+	// Items must not be refetched for projects that have not been updated
+	// since.
+	assert.Equal(t, map[string][]string{
+		"org/projects/1":      {},
+		"org/repo/projects/2": {},
+	}, planItemFetches())
+	client.ProjectItems = nil
+	_, err = coll.Fetch(context.Background(), cfg, log)
+	require.NoError(t, err)
+}
+
+func TestCollectionFetchTeams(t *testing.T) {
+	log := ghere.NewNoopLogger()
+	tmpDir := t.TempDir()
+	coll, err := ghere.LoadOrCreateLocalCollection(filepath.Join(tmpDir, ghere.CONFIG_FILE_NAME))
+	require.NoError(t, err)
+	_, err = coll.NewOrganization("org")
+	require.NoError(t, err)
+	_, err = coll.NewOrganization("github.com/org")
+	require.IsType(t, &ghere.ErrOrganizationAlreadyExists{}, err)
+	for _, path := range []string{"..", "../org", "ghe.example.com/..", "or*g"} {
+		_, err = coll.NewOrganization(path)
+		assert.Error(t, err, path)
+	}
+	require.Len(t, coll.Organizations, 1)
+
+	parent := &github.Team{ID: github.Int64(1), Slug: github.String("core"), Name: github.String("Core")}
+	child := &github.Team{ID: github.Int64(2), Slug: github.String("infra"), Name: github.String("Infra"), Parent: parent}
+	client := &MockGitHubClient{
+		Teams: map[string][]*github.Team{"org": {parent, child}},
+		TeamMembers: map[string]map[string][]*github.User{
+			"org/core": {
+				ghere.TEAM_ROLE_MAINTAINER: {{Login: github.String("alice"), ID: github.Int64(10)}},
+				ghere.TEAM_ROLE_MEMBER:     {{Login: github.String("bob"), ID: github.Int64(11)}},
+			},
+			"org/infra": {},
+		},
+		TeamRepositories: map[string][]*github.Repository{
+			"org/core": {{
+				FullName:    github.String("org/repo"),
+				ID:          github.Int64(100),
+				Permissions: map[string]bool{"pull": true, "triage": true, "push": true},
+			}},
+			"org/infra": {},
+		},
+	}
+	cfg := &ghere.FetchConfig{
+		Client:             client,
+		CredentialProvider: &MockGitHubCredentialProvider{},
+		RepoUpdater:        &MockGitHubRepositoryUpdater{},
+	}
+
+	// Unknown organizations (e.g. mistyped repository paths) must not be
+	// silently ignored, even if teams are not being fetched.
+	_, err = coll.Fetch(context.Background(), &ghere.FetchConfig{
+		Client:       client,
+		Repositories: []string{"repo"},
+		Resources:    &ghere.ResourceSelection{Skip: []string{ghere.RESOURCE_TEAMS}},
+	}, log)
+	require.ErrorContains(t, err, "organization repo is not part of the collection")
+
+	_, err = coll.Fetch(context.Background(), cfg, log)
+	require.NoError(t, err)
+	teamsPath := filepath.Join(tmpDir, "org", "_org", "teams")
+	core, err := ghere.LoadTeam(tmpDir, "org", "core", true)
+	require.NoError(t, err)
+	require.Len(t, core.Members, 2)
+	assert.Equal(t, "alice", core.Members[0].Login)
+	assert.Equal(t, ghere.TEAM_ROLE_MAINTAINER, core.Members[0].Role)
+	require.Len(t, core.Repositories, 1)
+	assert.Equal(t, "push", core.Repositories[0].Permission)
+	infra, err := ghere.LoadTeam(tmpDir, "org", "infra", true)
+	require.NoError(t, err)
+	assert.Equal(t, "core", infra.Team.GetParent().GetSlug())
+	history := func(slug string) []string {
+		files, err := filepath.Glob(filepath.Join(teamsPath, slug, "history", "*.json"))
+		require.NoError(t, err)
+		return files
+	}
+	assert.Len(t, history("core"), 1)
+
+	// Organizations' teams must be planned from the teams stored locally.
+	plan, err := coll.Plan(context.Background(), cfg, log)
+	require.NoError(t, err)
+	require.Len(t, plan.Repositories, 1)
+	assert.Equal(t, "org", plan.Repositories[0].Repository)
+	require.Len(t, plan.Repositories[0].Fetchers, 1)
+	assert.Equal(t, ghere.RESOURCE_TEAMS, plan.Repositories[0].Fetchers[0].Resource)
+	assert.Equal(t, 7, plan.Repositories[0].EstimatedAPICalls)
+
+	// Unchanged teams must not gain new snapshots, whereas membership changes
+	// and deleted teams must.
+	time.Sleep(time.Second)
+	_, err = coll.Fetch(context.Background(), cfg, log)
+	require.NoError(t, err)
+	assert.Len(t, history("core"), 1)
+
+	time.Sleep(time.Second)
+	client.Teams["org"] = []*github.Team{parent}
+	client.TeamMembers["org/core"][ghere.TEAM_ROLE_MEMBER] = nil
+	report, err := coll.Fetch(context.Background(), cfg, log)
+	require.NoError(t, err)
+	assert.Len(t, history("core"), 2)
+	core, err = ghere.LoadTeam(tmpDir, "org", "core", true)
+	require.NoError(t, err)
+	assert.Len(t, core.Members, 1)
+	infra, err = ghere.LoadTeam(tmpDir, "org", "infra", true)
+	require.NoError(t, err)
+	assert.True(t, infra.Deleted)
+	assert.Len(t, history("infra"), 2)
+	require.Len(t, report.Repositories, 1)
+	assert.Equal(t, 1, report.Repositories[0].Resources[ghere.RESOURCE_TEAMS].Deleted)
+	assert.Equal(t, 1, report.Repositories[0].Resources[ghere.RESOURCE_TEAMS].Updated)
+}
+
+func TestCollectionFetchActions(t *testing.T) {
+	log := ghere.NewNoopLogger()
+	tmpDir := t.TempDir()
+	coll, err := ghere.LoadOrCreateLocalCollection(filepath.Join(tmpDir, ghere.CONFIG_FILE_NAME))
+	require.NoError(t, err)
+
+	owner := "org"
+	name := "repo"
+	repoID := owner + "/" + name
+	_, err = coll.NewFromPath(repoID)
+	require.NoError(t, err)
+
+	newRun := func(id int64, status string) *github.WorkflowRun {
+		return &github.WorkflowRun{ID: github.Int64(id), WorkflowID: github.Int64(10), Status: github.String(status)}
+	}
+	newZip := func(files map[string]string) []byte {
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		for fn, contents := range files {
+			w, err := zw.Create(fn)
+			require.NoError(t, err)
+			_, err = w.Write([]byte(contents))
+			require.NoError(t, err)
+		}
+		require.NoError(t, zw.Close())
+		return buf.Bytes()
+	}
+	// newZipBomb creates an archive with a single entry of the given size
+	// that compresses extremely well.
+	newZipBomb := func(size int) []byte {
+		return newZip(map[string]string{"zeros.txt": string(bytes.Repeat([]byte("0"), size))})
+	}
+	ghRepo := &github.Repository{Owner: &github.User{Login: &owner}, Name: &name, UpdatedAt: &github.Timestamp{Time: time.Now()}}
+	client := &MockGitHubClient{
+		Repositories: map[string]*github.Repository{repoID: ghRepo},
+		Labels:       map[string][]*github.Label{repoID: {}},
+		PullRequests: map[string][]*github.PullRequest{repoID: {}},
+		Issues:       map[string][]*github.Issue{repoID: {}},
+		Workflows: map[string][]*github.Workflow{repoID: {
+			{ID: github.Int64(10), Name: github.String("CI"), Path: github.String(".github/workflows/ci.yml")},
+		}},
+		// Runs are listed from newest to oldest.
+		WorkflowRuns: map[string][]*github.WorkflowRun{repoID: {
+			newRun(3, "in_progress"),
+			newRun(2, "completed"),
+			newRun(1, "completed"),
+		}},
+		WorkflowJobs: map[string]map[int64][]*github.WorkflowJob{repoID: {
+			1: {{ID: github.Int64(101), RunID: github.Int64(1)}},
+			2: {{ID: github.Int64(201), RunID: github.Int64(2)}},
+			3: {{ID: github.Int64(301), RunID: github.Int64(3)}},
+		}},
+		WorkflowArtifacts: map[string]map[int64][]*github.Artifact{repoID: {
+			1: {
+				{ID: github.Int64(501), SizeInBytes: github.Int64(100)},
+				{ID: github.Int64(502), SizeInBytes: github.Int64(1 << 20)},
+				{ID: github.Int64(503), SizeInBytes: github.Int64(100)},
+			},
+		}},
+		WorkflowRunLogs: map[int64][]byte{
+			1: newZip(map[string]string{"build/1_Set up job.txt": "Hello"}),
+		},
+		ArtifactArchives: map[int64][]byte{
+			501: newZip(map[string]string{"coverage.txt": "100%"}),
+			503: newZipBomb(1 << 17),
+		},
+	}
+	cfg := &ghere.FetchConfig{
+		Client:                  client,
+		CredentialProvider:      &MockGitHubCredentialProvider{},
+		RepoUpdater:             &MockGitHubRepositoryUpdater{},
+		Resources:               &ghere.ResourceSelection{Include: []string{ghere.RESOURCE_ACTIONS}},
+		FetchActionsLogs:        true,
+		FetchActionsArtifacts:   true,
+		ActionsArtifactsMaxSize: 1024,
+	}
+
+	_, err = coll.Fetch(context.Background(), cfg, log)
+	require.NoError(t, err)
+	actionsPath := filepath.Join(tmpDir, owner, name, "actions")
+	runsPath := filepath.Join(actionsPath, "runs")
+	assert.FileExists(t, filepath.Join(actionsPath, "workflows", "10.json"))
+	assert.FileExists(t, filepath.Join(runsPath, "1", "jobs", "101.json"))
+	assert.FileExists(t, filepath.Join(runsPath, "2", "jobs", "201.json"))
+	// Jobs are only fetched once runs have completed.
+	assert.NoFileExists(t, filepath.Join(runsPath, "3", "jobs", "301.json"))
+	logs, err := os.ReadFile(filepath.Join(runsPath, "1", "logs", "build", "1_Set up job.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "Hello", string(logs))
+	run2, err := ghere.LoadWorkflowRunDirect(filepath.Join(runsPath, "2", ghere.DETAIL_FILENAME), true)
+	require.NoError(t, err)
+	assert.NotEmpty(t, run2.LogsSkipped)
+	contents, err := os.ReadFile(filepath.Join(runsPath, "1", "artifacts", "501", "coverage.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "100%", string(contents))
+	var tooLarge ghere.WorkflowArtifact
+	require.NoError(t, ghere.ReadJSONFile(filepath.Join(runsPath, "1", "artifacts", "502.json"), &tooLarge))
+	assert.NotEmpty(t, tooLarge.Skipped)
+	assert.NoDirExists(t, filepath.Join(runsPath, "1", "artifacts", "502"))
+	// Archives that expand to more than the permitted size are not extracted.
+	var bomb ghere.WorkflowArtifact
+	require.NoError(t, ghere.ReadJSONFile(filepath.Join(runsPath, "1", "artifacts", "503.json"), &bomb))
+	assert.Contains(t, bomb.Skipped, "extracted archive exceeds size limit")
+	assert.NoDirExists(t, filepath.Join(runsPath, "1", "artifacts", "503"))
+	repo, err := ghere.LoadRepository(tmpDir, owner, name, true)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), repo.LastWorkflowRunID)
+	assert.Equal(t, []int64{3}, repo.PendingWorkflowRunIDs)
+
+	// Runs fetched previously must not be fetched again, unless they had not
+	// completed.
+	client.WorkflowRuns[repoID] = []*github.WorkflowRun{
+		newRun(4, "completed"),
+		newRun(3, "completed"),
+		newRun(2, "completed"),
+		{ID: github.Int64(1), WorkflowID: github.Int64(10), Status: github.String("completed"), Conclusion: github.String("failure")},
+	}
+	client.WorkflowJobs[repoID][4] = []*github.WorkflowJob{{ID: github.Int64(401), RunID: github.Int64(4)}}
+	report, err := coll.Fetch(context.Background(), cfg, log)
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(runsPath, "3", "jobs", "301.json"))
+	assert.FileExists(t, filepath.Join(runsPath, "4", "jobs", "401.json"))
+	run1, err := ghere.LoadWorkflowRunDirect(filepath.Join(runsPath, "1", ghere.DETAIL_FILENAME), true)
+	require.NoError(t, err)
+	assert.Empty(t, run1.Run.GetConclusion())
+	repo, err = ghere.LoadRepository(tmpDir, owner, name, true)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), repo.LastWorkflowRunID)
+	assert.Empty(t, repo.PendingWorkflowRunIDs)
+	require.Len(t, report.Repositories, 1)
+	runsReport := report.Repositories[0].Resources[ghere.RESOURCE_WORKFLOW_RUNS]
+	assert.Equal(t, 1, runsReport.Created)
+	assert.Equal(t, 1, runsReport.Updated)
+
+	// Recent runs that have been re-run must be refetched, along with the
+	// jobs of their latest attempt.
+	now := time.Now()
+	for _, ghRun := range client.WorkflowRuns[repoID] {
+		ghRun.CreatedAt = &github.Timestamp{Time: now}
+	}
+	rerun := client.WorkflowRuns[repoID][2]
+	rerun.RunAttempt = github.Int(2)
+	client.WorkflowJobs[repoID][2] = []*github.WorkflowJob{{ID: github.Int64(202), RunID: github.Int64(2)}}
+	report, err = coll.Fetch(context.Background(), cfg, log)
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(runsPath, "2", "jobs", "202.json"))
+	run2, err = ghere.LoadWorkflowRunDirect(filepath.Join(runsPath, "2", ghere.DETAIL_FILENAME), true)
+	require.NoError(t, err)
+	assert.Equal(t, 2, run2.Run.GetRunAttempt())
+	assert.False(t, run2.LastDetailFetch.IsZero())
+	require.Len(t, report.Repositories, 1)
+	runsReport = report.Repositories[0].Resources[ghere.RESOURCE_WORKFLOW_RUNS]
+	assert.Equal(t, 0, runsReport.Created)
+	assert.Equal(t, 1, runsReport.Updated)
+}
+
+func TestExtractZipCleansUpOnError(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, fn := range []string{"valid.txt", "../invalid.txt"} {
+		w, err := zw.Create(fn)
+		require.NoError(t, err)
+		_, err = w.Write([]byte("contents"))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+
+	dir := filepath.Join(t.TempDir(), "extracted")
+	err := ghere.ExtractZip(buf.Bytes(), dir, 1024)
+	require.ErrorContains(t, err, "invalid path")
+	require.NoDirExists(t, dir, "partially extracted archive must be removed")
+}
+
+func TestCollectionFetchChecks(t *testing.T) {
+	log := ghere.NewNoopLogger()
+	tmpDir := t.TempDir()
+	coll, err := ghere.LoadOrCreateLocalCollection(filepath.Join(tmpDir, ghere.CONFIG_FILE_NAME))
+	require.NoError(t, err)
+
+	owner := "org"
+	name := "repo"
+	repoID := owner + "/" + name
+	_, err = coll.NewFromPath(repoID)
+	require.NoError(t, err)
+
+	now := time.Now()
+	newCommit := func(sha string, date time.Time) *github.RepositoryCommit {
+		return &github.RepositoryCommit{
+			SHA:    github.String(sha),
+			Commit: &github.Commit{Committer: &github.CommitAuthor{Date: &date}},
+		}
+	}
+	newCheckRun := func(id int64, status, conclusion string) *github.CheckRun {
+		return &github.CheckRun{
+			ID:         github.Int64(id),
+			Status:     github.String(status),
+			Conclusion: github.String(conclusion),
+			Output:     &github.CheckRunOutput{Summary: github.String("All tests passed")},
+		}
+	}
+	ghRepo := &github.Repository{
+		Owner:         &github.User{Login: &owner},
+		Name:          &name,
+		DefaultBranch: github.String("main"),
+		UpdatedAt:     &github.Timestamp{Time: now},
+	}
+	client := &MockGitHubClient{
+		Repositories: map[string]*github.Repository{repoID: ghRepo},
+		Labels:       map[string][]*github.Label{repoID: {}},
+		PullRequests: map[string][]*github.PullRequest{repoID: {
+			{Number: github.Int(1), UpdatedAt: &now, Head: &github.PullRequestBranch{SHA: github.String("pr1head")}},
+		}},
+		PullRequestReviews:  map[string]map[int][]*github.PullRequestReview{repoID: {1: {}}},
+		PullRequestComments: map[string]map[int][]*github.PullRequestComment{repoID: {1: {}}},
+		Issues:              map[string][]*github.Issue{repoID: {}},
+		Timelines:           map[string]map[int][]*github.Timeline{repoID: {1: {}}},
+		// Only commits since the lookback period are considered on the
+		// first fetch.
+		Commits: map[string][]*github.RepositoryCommit{repoID: {
+			newCommit("main2", now.Add(-time.Hour)),
+			newCommit("main1", now.Add(-ghere.CHECKS_COMMITS_LOOKBACK-time.Hour)),
+		}},
+		Statuses: map[string]map[string][]*github.RepoStatus{repoID: {
+			"main2": {{ID: github.Int64(1), Context: github.String("ci/build"), State: github.String("success")}},
+		}},
+		CheckSuites: map[string]map[string][]*github.CheckSuite{repoID: {
+			"pr1head": {{ID: github.Int64(10), Status: github.String("completed"), Conclusion: github.String("success")}},
+		}},
+		CheckRuns: map[string]map[string][]*github.CheckRun{repoID: {
+			"pr1head": {newCheckRun(100, "completed", "success")},
+			"main2":   {newCheckRun(200, "in_progress", "")},
+		}},
+	}
+	cfg := &ghere.FetchConfig{
+		Client:             client,
+		CredentialProvider: &MockGitHubCredentialProvider{},
+		RepoUpdater:        &MockGitHubRepositoryUpdater{},
+		Resources:          &ghere.ResourceSelection{Include: []string{ghere.RESOURCE_CHECKS}},
+	}
+
+	_, err = coll.Fetch(context.Background(), cfg, log)
+	require.NoError(t, err)
+	checksPath := filepath.Join(tmpDir, owner, name, "checks")
+	prChecks, err := ghere.LoadCommitChecksDirect(filepath.Join(checksPath, "pr1head.json"), true)
+	require.NoError(t, err)
+	assert.True(t, prChecks.IsComplete())
+	require.Len(t, prChecks.CheckSuites, 1)
+	require.Len(t, prChecks.CheckRuns, 1)
+	assert.Equal(t, "success", prChecks.CheckRuns[0].GetConclusion())
+	assert.Equal(t, "All tests passed", prChecks.CheckRuns[0].GetOutput().GetSummary())
+	mainChecks, err := ghere.LoadCommitChecksDirect(filepath.Join(checksPath, "main2.json"), true)
+	require.NoError(t, err)
+	assert.False(t, mainChecks.IsComplete())
+	assert.Equal(t, "success", mainChecks.CombinedStatus.GetState())
+	require.Len(t, mainChecks.CombinedStatus.Statuses, 1)
+	assert.NoFileExists(t, filepath.Join(checksPath, "main1.json"))
+
+	// Complete commits' checks must not be fetched again, whereas pending ones
+	// must.
+	client.CheckRuns[repoID]["pr1head"] = []*github.CheckRun{newCheckRun(100, "completed", "failure")}
+	client.CheckRuns[repoID]["main2"] = []*github.CheckRun{newCheckRun(200, "completed", "success")}
+	report, err := coll.Fetch(context.Background(), cfg, log)
+	require.NoError(t, err)
+	prChecks, err = ghere.LoadCommitChecksDirect(filepath.Join(checksPath, "pr1head.json"), true)
+	require.NoError(t, err)
+	assert.Equal(t, "success", prChecks.CheckRuns[0].GetConclusion())
+	mainChecks, err = ghere.LoadCommitChecksDirect(filepath.Join(checksPath, "main2.json"), true)
+	require.NoError(t, err)
+	assert.True(t, mainChecks.IsComplete())
+	assert.True(t, mainChecks.LastFetch.After(mainChecks.FirstFetch))
+	require.Len(t, report.Repositories, 1)
+	assert.Equal(t, 1, report.Repositories[0].Resources[ghere.RESOURCE_CHECKS].Updated)
+
+	// The cursor must only be advanced once all commits' checks have been
+	// fetched.
+	repo, err := ghere.LoadRepository(tmpDir, owner, name, true)
+	require.NoError(t, err)
+	lastChecksFetch := repo.LastChecksFetch
+	require.False(t, lastChecksFetch.IsZero())
+	client.Commits[repoID] = append([]*github.RepositoryCommit{newCommit("main3", time.Now())}, client.Commits[repoID]...)
+	failingCfg := *cfg
+	failingCfg.Client = &failingCheckRunsClient{MockGitHubClient: client}
+	_, err = coll.Fetch(context.Background(), &failingCfg, log)
+	require.Error(t, err)
+	repo, err = ghere.LoadRepository(tmpDir, owner, name, true)
+	require.NoError(t, err)
+	assert.True(t, repo.LastChecksFetch.Equal(lastChecksFetch))
+	assert.NoFileExists(t, filepath.Join(checksPath, "main3.json"))
+
+	_, err = coll.Fetch(context.Background(), cfg, log)
+	require.NoError(t, err)
+	repo, err = ghere.LoadRepository(tmpDir, owner, name, true)
+	require.NoError(t, err)
+	assert.True(t, repo.LastChecksFetch.After(lastChecksFetch))
+	assert.FileExists(t, filepath.Join(checksPath, "main3.json"))
+
+	// Commits whose checks cannot be fetched (e.g. because they were garbage
+	// collected) must be skipped without holding back the cursor.
+	lastChecksFetch = repo.LastChecksFetch
+	client.Commits[repoID] = append([]*github.RepositoryCommit{newCommit("main5", time.Now()), newCommit("main4", time.Now())}, client.Commits[repoID]...)
+	unavailableCfg := *cfg
+	unavailableCfg.Client = &unavailableCommitClient{MockGitHubClient: client, sha: "main4"}
+	_, err = coll.Fetch(context.Background(), &unavailableCfg, log)
+	require.NoError(t, err)
+	repo, err = ghere.LoadRepository(tmpDir, owner, name, true)
+	require.NoError(t, err)
+	assert.True(t, repo.LastChecksFetch.After(lastChecksFetch))
+	assert.NoFileExists(t, filepath.Join(checksPath, "main4.json"))
+	assert.FileExists(t, filepath.Join(checksPath, "main5.json"))
+}
+
+// unavailableCommitClient fails to get the combined status of a specific
+// commit, as GitHub does for commits that no longer exist.
+type unavailableCommitClient struct {
+	*MockGitHubClient
+	sha string
+}
+
+func (c *unavailableCommitClient) GetCombinedStatus(ctx context.Context, owner string, name string, sha string, page int) (*github.CombinedStatus, bool, error) {
+	if sha == c.sha {
+		return nil, false, errorResponse(http.StatusUnprocessableEntity, "No commit found for SHA: "+sha)
+	}
+	return c.MockGitHubClient.GetCombinedStatus(ctx, owner, name, sha, page)
+}
+
+// failingCheckRunsClient fails to list any commit's check runs.
+type failingCheckRunsClient struct {
+	*MockGitHubClient
+}
+
+func (c *failingCheckRunsClient) ListCheckRuns(ctx context.Context, owner string, name string, sha string, page int) ([]*github.CheckRun, bool, error) {
+	return nil, false, errors.New("check runs unavailable")
+}
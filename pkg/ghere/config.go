@@ -1,13 +1,21 @@
 package ghere
 
 import (
+	"fmt"
 	"time"
 )
 
 const (
-	DEFAULT_PER_PAGE int    = 100
-	CONFIG_FILE_NAME string = "ghere.json"
-	DETAIL_FILENAME  string = "detail.json"
+	DEFAULT_PER_PAGE         int    = 100
+	CONFIG_FILE_NAME         string = "ghere.json"
+	DETAIL_FILENAME          string = "detail.json"
+	BUNDLE_MANIFEST_FILENAME string = "manifest.json"
+
+	DEFAULT_ACTIONS_LOGS_MAX_SIZE      int64 = 50 * 1024 * 1024
+	DEFAULT_ACTIONS_ARTIFACTS_MAX_SIZE int64 = 10 * 1024 * 1024
+	// The extracted contents of downloaded zip archives may be at most this
+	// many times larger than the maximum download size.
+	MAX_ZIP_EXPANSION_RATIO int64 = 10
 )
 
 // FetchConfig provides our configuration for all fetch operations.
@@ -18,4 +26,69 @@ type FetchConfig struct {
 	GitTimeout         time.Duration
 	FailFast           bool
 	PrettyJSON         bool
+	// Restart, if true, discards any checkpoints left behind by interrupted
+	// fetches, such that all listings are fetched from the start.
+	Restart bool
+	// Repositories, if not empty, restricts fetching to the repositories in
+	// the collection with these paths (e.g. "owner/name").
+	Repositories []string
+	// Resources optionally restricts which types of resources are fetched,
+	// in addition to any restrictions configured for each repository.
+	Resources *ResourceSelection
+	// UseEvents, if true, uses each repository's events feed to determine
+	// exactly which issues and pull requests changed since the last fetch,
+	// fetching only those instead of listing all issues and pull requests.
+	UseEvents bool
+	// FetchReactions, if true, fetches the individual reactions (and the
+	// users who reacted) to issues, issue comments, pull request review
+	// comments and releases, as opposed to only their totals.
+	FetchReactions bool
+	// CommentReactionsInterval is the minimum time between refreshes of the
+	// reactions to a repository's comments when FetchReactions is true. Each
+	// refresh lists all of the repository's issue and pull request review
+	// comments. If zero, they are refreshed on every fetch.
+	CommentReactionsInterval time.Duration
+	// FetchActionsLogs, if true, downloads and extracts the log archives of
+	// completed workflow runs that are no larger than ActionsLogsMaxSize
+	// bytes.
+	FetchActionsLogs   bool
+	ActionsLogsMaxSize int64
+	// FetchActionsArtifacts, if true, downloads and extracts the artifacts of
+	// completed workflow runs that are no larger than ActionsArtifactsMaxSize
+	// bytes.
+	FetchActionsArtifacts   bool
+	ActionsArtifactsMaxSize int64
+	// Hosts provides host-specific configuration for repositories hosted on
+	// GitHub Enterprise Server instances, keyed by host name.
+	Hosts map[string]*HostFetchConfig
+	// Metrics, if not nil, is updated with the outcome of the fetch of each
+	// repository.
+	Metrics *Metrics
+
+	// The report for the repository currently being fetched, if any.
+	report *RepositoryReport
+}
+
+// HostFetchConfig provides the configuration necessary to fetch repositories
+// from a specific GitHub Enterprise Server instance.
+type HostFetchConfig struct {
+	Client             GitHubClient
+	CredentialProvider GitHubCredentialProvider
+}
+
+// forHost returns a copy of this configuration that uses the client and
+// credential provider for the given host. An empty host refers to the default
+// client and credential provider.
+func (c *FetchConfig) forHost(host string) (*FetchConfig, error) {
+	if len(host) == 0 {
+		return c, nil
+	}
+	hostCfg, exists := c.Hosts[host]
+	if !exists {
+		return nil, fmt.Errorf("no configuration for GitHub host %s", host)
+	}
+	cfg := *c
+	cfg.Client = hostCfg.Client
+	cfg.CredentialProvider = hostCfg.CredentialProvider
+	return &cfg, nil
 }
@@ -0,0 +1,417 @@
+package ghere
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	DEFAULT_SYNC_INTERVAL          time.Duration = time.Hour
+	DEFAULT_CONFIG_RELOAD_INTERVAL time.Duration = 10 * time.Second
+)
+
+// Duration is a time.Duration that is represented in JSON as a string (e.g.
+// "1h30m").
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string (e.g. \"1h30m\"): %v", err)
+	}
+	dur, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(dur)
+	return nil
+}
+
+// Schedule configures how often repositories (and projects and organizations)
+// are periodically fetched when running as a daemon.
+type Schedule struct {
+	// Interval is the time between the starts of consecutive fetches.
+	Interval Duration `json:"interval,omitempty"`
+	// Jitter is the maximum random delay added to each scheduled fetch, to
+	// avoid fetching many repositories at the same time.
+	Jitter Duration `json:"jitter,omitempty"`
+}
+
+// DaemonConfig configures a daemon that periodically fetches the repositories
+// in a collection.
+type DaemonConfig struct {
+	// Fetch is the configuration used for each repository fetch.
+	Fetch *FetchConfig
+	// Schedule is the default schedule for repositories for which neither the
+	// collection nor the repository itself configures a schedule.
+	Schedule Schedule
+	// MaxConcurrentFetches limits how many repositories can be fetched at the
+	// same time. Defaults to 1.
+	MaxConcurrentFetches int
+	// ReloadInterval is how often the collection's configuration file is
+	// checked for changes.
+	ReloadInterval time.Duration
+	// NewFetchConfig, if not nil, constructs a new fetch configuration for the
+	// given collection. It is called whenever the collection's GitHub host
+	// configuration (its base and upload URLs, and its hosts) changes upon
+	// reloading. Otherwise such changes are rejected, and only take effect
+	// once the daemon is restarted.
+	NewFetchConfig func(coll *LocalCollection) (*FetchConfig, error)
+}
+
+// Daemon periodically fetches the repositories, projects and organizations in
+// a collection according to their schedules, reloading the collection's
+// configuration whenever it changes. A repository (or project or
+// organization) is never fetched by more than one run at a time.
+type Daemon struct {
+	configFile string
+	cfg        *DaemonConfig
+	log        Logger
+
+	mtx            sync.Mutex
+	coll           *LocalCollection
+	fetchCfg       *FetchConfig
+	configContents []byte
+	configLoadedAt time.Time
+	// Keyed by the path of each repository, project or organization (see
+	// fetchTarget.String).
+	repos map[string]*daemonRepo
+	rnd   *rand.Rand
+	// Acquired for the duration of each fetch.
+	slots chan struct{}
+	// Signalled whenever a fetch completes.
+	wake chan struct{}
+	wg   sync.WaitGroup
+}
+
+type daemonRepo struct {
+	schedule Schedule
+	status   *RepositorySyncStatus
+}
+
+// DaemonStatus describes the state of a running daemon.
+type DaemonStatus struct {
+	ConfigFile     string                  `json:"config_file"`
+	ConfigLoadedAt time.Time               `json:"config_loaded_at"`
+	Repositories   []*RepositorySyncStatus `json:"repositories"`
+}
+
+// RepositorySyncStatus describes the periodic fetching of a single repository,
+// project or organization.
+type RepositorySyncStatus struct {
+	// Repository is the path of the repository, project or organization.
+	Repository string   `json:"repository"`
+	Interval   Duration `json:"interval"`
+	Running    bool     `json:"running"`
+	// NextRun is when the next fetch is scheduled to start. Only meaningful if
+	// the repository is not currently being fetched.
+	NextRun           time.Time  `json:"next_run"`
+	LastRunStartedAt  *time.Time `json:"last_run_started_at,omitempty"`
+	LastRunFinishedAt *time.Time `json:"last_run_finished_at,omitempty"`
+	LastRunError      string     `json:"last_run_error,omitempty"`
+	LastSuccessAt     *time.Time `json:"last_success_at,omitempty"`
+}
+
+var _ http.Handler = (*Daemon)(nil)
+
+func NewDaemon(configFile string, cfg *DaemonConfig, log Logger) *Daemon {
+	maxConcurrent := cfg.MaxConcurrentFetches
+	if maxConcurrent < 1 {
+		maxConcurrent = 1
+	}
+	return &Daemon{
+		configFile: configFile,
+		cfg:        cfg,
+		fetchCfg:   cfg.Fetch,
+		log:        log,
+		repos:      make(map[string]*daemonRepo),
+		rnd:        rand.New(rand.NewSource(time.Now().UnixNano())),
+		slots:      make(chan struct{}, maxConcurrent),
+		wake:       make(chan struct{}, 1),
+	}
+}
+
+// Run schedules and executes fetches until the given context is cancelled,
+// after which it waits for any fetches in progress to be interrupted.
+func (d *Daemon) Run(ctx context.Context) error {
+	if err := d.reload(); err != nil {
+		return err
+	}
+	reloadInterval := d.cfg.ReloadInterval
+	if reloadInterval <= 0 {
+		reloadInterval = DEFAULT_CONFIG_RELOAD_INTERVAL
+	}
+	reloadTicker := time.NewTicker(reloadInterval)
+	defer reloadTicker.Stop()
+	for {
+		d.startDueFetches(ctx)
+		timer := time.NewTimer(d.untilNextRun())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			d.log.Info("Stopping daemon; waiting for fetches in progress to be interrupted")
+			d.wg.Wait()
+			return nil
+		case <-reloadTicker.C:
+			if err := d.reload(); err != nil {
+				d.log.Error("Failed to reload collection configuration; keeping previous configuration", "path", d.configFile, "err", err)
+			}
+		case <-d.wake:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// Status returns a snapshot of the daemon's current state.
+func (d *Daemon) Status() *DaemonStatus {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	status := &DaemonStatus{
+		ConfigFile:     d.configFile,
+		ConfigLoadedAt: d.configLoadedAt,
+		Repositories:   make([]*RepositorySyncStatus, 0, len(d.repos)),
+	}
+	for _, key := range sortedKeys(d.repos) {
+		repoStatus := *d.repos[key].status
+		status.Repositories = append(status.Repositories, &repoStatus)
+	}
+	return status
+}
+
+// ServeHTTP implements http.Handler, responding with the daemon's status as
+// JSON.
+func (d *Daemon) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b, err := json.MarshalIndent(d.Status(), "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(b)
+}
+
+// reload (re)loads the collection's configuration if it has changed since it
+// was last loaded, updating the schedules of existing repositories, projects
+// and organizations, and scheduling newly added ones for immediate fetching
+// (subject to jitter). Only those selected by the daemon's fetch configuration
+// are scheduled. If the collection's GitHub host configuration changed, the
+// fetch configuration is reconstructed (if possible).
+func (d *Daemon) reload() error {
+	b, err := os.ReadFile(d.configFile)
+	if err != nil {
+		return fmt.Errorf("failed to read collection file: %v", err)
+	}
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	if d.coll != nil && bytes.Equal(b, d.configContents) {
+		return nil
+	}
+	coll := &LocalCollection{}
+	if err := json.Unmarshal(b, coll); err != nil {
+		return fmt.Errorf("failed to parse collection file: %v", err)
+	}
+	coll.configFile = d.configFile
+	coll.rootPath = filepath.Dir(d.configFile)
+
+	fetchCfg := d.fetchCfg
+	if d.coll != nil && !sameHostConfig(d.coll, coll) {
+		if d.cfg.NewFetchConfig == nil {
+			return errors.New("GitHub host configuration (base_url, upload_url or hosts) changed; restart required")
+		}
+		fetchCfg, err = d.cfg.NewFetchConfig(coll)
+		if err != nil {
+			return fmt.Errorf("failed to reconfigure GitHub hosts: %v", err)
+		}
+		d.log.Info("Reconfigured GitHub hosts", "path", d.configFile, "hosts", len(coll.Hosts))
+	}
+	targets, err := coll.selectTargets(fetchCfg)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	repos := make(map[string]*daemonRepo, len(targets))
+	for _, target := range targets {
+		key := target.String()
+		schedule := d.schedule(coll, target)
+		dr, exists := d.repos[key]
+		if !exists {
+			dr = &daemonRepo{
+				status: &RepositorySyncStatus{
+					Repository: key,
+					NextRun:    now.Add(d.jitter(schedule)),
+				},
+			}
+		} else if schedule != dr.schedule && !dr.status.Running && dr.status.LastRunStartedAt != nil {
+			dr.status.NextRun = d.nextRun(*dr.status.LastRunStartedAt, schedule)
+		}
+		dr.schedule = schedule
+		dr.status.Interval = schedule.Interval
+		repos[key] = dr
+	}
+	for key := range d.repos {
+		if _, exists := repos[key]; !exists {
+			d.log.Info("Removed from collection; no longer scheduling fetches", "path", key)
+		}
+	}
+	if d.coll != nil {
+		d.log.Info("Reloaded collection configuration", "path", d.configFile, "targets", len(repos))
+	}
+	d.coll = coll
+	d.fetchCfg = fetchCfg
+	d.configContents = b
+	d.configLoadedAt = now
+	d.repos = repos
+	return nil
+}
+
+// sameHostConfig returns whether the given collections are configured to
+// fetch from the same GitHub hosts in the same way.
+func sameHostConfig(a, b *LocalCollection) bool {
+	return a.BaseURL == b.BaseURL && a.UploadURL == b.UploadURL && reflect.DeepEqual(a.Hosts, b.Hosts)
+}
+
+// schedule determines the effective schedule for the given target. Only
+// repositories can override the collection's schedule.
+func (d *Daemon) schedule(coll *LocalCollection, target fetchTarget) Schedule {
+	schedule := Schedule{
+		Interval: Duration(DEFAULT_SYNC_INTERVAL),
+	}
+	var targetSchedule *Schedule
+	if repo, ok := target.(*LocalRepository); ok {
+		targetSchedule = repo.Schedule
+	}
+	for _, s := range []*Schedule{&d.cfg.Schedule, coll.Schedule, targetSchedule} {
+		if s == nil {
+			continue
+		}
+		if s.Interval > 0 {
+			schedule.Interval = s.Interval
+		}
+		if s.Jitter > 0 {
+			schedule.Jitter = s.Jitter
+		}
+	}
+	return schedule
+}
+
+// nextRun computes when the next fetch of a repository whose last fetch
+// started at the given time must start. Must be called while holding the lock.
+func (d *Daemon) nextRun(lastStarted time.Time, schedule Schedule) time.Time {
+	next := lastStarted.Add(time.Duration(schedule.Interval))
+	if now := time.Now(); next.Before(now) {
+		next = now
+	}
+	return next.Add(d.jitter(schedule))
+}
+
+// Must be called while holding the lock.
+func (d *Daemon) jitter(schedule Schedule) time.Duration {
+	if schedule.Jitter <= 0 {
+		return 0
+	}
+	return time.Duration(d.rnd.Int63n(int64(schedule.Jitter)))
+}
+
+// untilNextRun computes how long to wait until the next scheduled fetch of a
+// repository that is not currently being fetched. If no more fetches can be
+// started at present, we wait for a fetch in progress to complete instead.
+func (d *Daemon) untilNextRun() time.Duration {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	wait := DEFAULT_SYNC_INTERVAL
+	if len(d.slots) == cap(d.slots) {
+		return wait
+	}
+	for _, dr := range d.repos {
+		if dr.status.Running {
+			continue
+		}
+		if until := time.Until(dr.status.NextRun); until < wait {
+			wait = until
+		}
+	}
+	return wait
+}
+
+// startDueFetches starts fetches, in the background, of all repositories
+// whose fetches are due and are not already being fetched, as long as
+// concurrency limits allow.
+func (d *Daemon) startDueFetches(ctx context.Context) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	now := time.Now()
+	due := make([]*daemonRepo, 0)
+	for _, dr := range d.repos {
+		if !dr.status.Running && !dr.status.NextRun.After(now) {
+			due = append(due, dr)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].status.NextRun.Before(due[j].status.NextRun)
+	})
+	for _, dr := range due {
+		select {
+		case d.slots <- struct{}{}:
+		default:
+			return
+		}
+		dr.status.Running = true
+		startedAt := now
+		dr.status.LastRunStartedAt = &startedAt
+		d.wg.Add(1)
+		go d.runFetch(ctx, d.coll, d.fetchCfg, dr)
+	}
+}
+
+func (d *Daemon) runFetch(ctx context.Context, coll *LocalCollection, fetchCfg *FetchConfig, dr *daemonRepo) {
+	defer d.wg.Done()
+	key := dr.status.Repository
+	d.log.Info("Starting scheduled fetch", "path", key)
+	cfg := *fetchCfg
+	cfg.Repositories = []string{key}
+	_, err := coll.Fetch(ctx, &cfg, d.log)
+
+	d.mtx.Lock()
+	finishedAt := time.Now()
+	dr.status.Running = false
+	dr.status.LastRunFinishedAt = &finishedAt
+	dr.status.LastRunError = ""
+	if err != nil {
+		dr.status.LastRunError = err.Error()
+	} else {
+		dr.status.LastSuccessAt = &finishedAt
+	}
+	dr.status.NextRun = d.nextRun(*dr.status.LastRunStartedAt, dr.schedule)
+	nextRun := dr.status.NextRun
+	d.mtx.Unlock()
+
+	<-d.slots
+	if err != nil {
+		if ctx.Err() == nil {
+			d.log.Error("Scheduled fetch failed", "path", key, "err", err, "nextRun", nextRun)
+		}
+	} else {
+		d.log.Info("Scheduled fetch complete", "path", key, "nextRun", nextRun)
+	}
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
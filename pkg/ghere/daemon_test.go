@@ -0,0 +1,225 @@
+package ghere_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v48/github"
+	"github.com/informalsystems/ghere/pkg/ghere"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDaemon(t *testing.T) {
+	log := ghere.NewNoopLogger()
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, ghere.CONFIG_FILE_NAME)
+	coll, err := ghere.LoadOrCreateLocalCollection(configFile)
+	require.NoError(t, err)
+
+	owner := "org"
+	repos := map[string]*github.Repository{}
+	for _, name := range []string{"repo1", "repo2"} {
+		name := name
+		repos[owner+"/"+name] = &github.Repository{
+			Owner: &github.User{Login: &owner},
+			Name:  &name,
+		}
+	}
+	coll.Schedule = &ghere.Schedule{Interval: ghere.Duration(100 * time.Millisecond)}
+	_, err = coll.NewFromPath("org/repo1")
+	require.NoError(t, err)
+	require.NoError(t, coll.Save())
+
+	client := &MockGitHubClient{
+		Repositories: repos,
+		Projects: map[string]*ghere.GitHubProjectV2{
+			"org/projects/1": {ID: "PVT_1", Number: 1, UpdatedAt: time.Now()},
+		},
+		ProjectItems: map[string][]*ghere.GitHubProjectV2Item{"PVT_1": {}},
+		Teams:        map[string][]*github.Team{"org": {}},
+	}
+	daemon := ghere.NewDaemon(configFile, &ghere.DaemonConfig{
+		Fetch: &ghere.FetchConfig{
+			Client:             client,
+			CredentialProvider: &MockGitHubCredentialProvider{},
+			RepoUpdater:        &MockGitHubRepositoryUpdater{},
+		},
+		ReloadInterval: 20 * time.Millisecond,
+	}, log)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- daemon.Run(ctx)
+	}()
+
+	// The repository must be fetched immediately, and then again after its
+	// interval has elapsed.
+	var firstSuccess time.Time
+	require.Eventually(t, func() bool {
+		status := daemon.Status()
+		if len(status.Repositories) != 1 || status.Repositories[0].LastSuccessAt == nil {
+			return false
+		}
+		if firstSuccess.IsZero() {
+			firstSuccess = *status.Repositories[0].LastSuccessAt
+			return false
+		}
+		return status.Repositories[0].LastSuccessAt.After(firstSuccess)
+	}, 5*time.Second, 10*time.Millisecond)
+	status := daemon.Status()
+	assert.Equal(t, "org/repo1", status.Repositories[0].Repository)
+	assert.Equal(t, ghere.Duration(100*time.Millisecond), status.Repositories[0].Interval)
+	assert.Empty(t, status.Repositories[0].LastRunError)
+
+	// Repositories added to the collection's configuration must be picked up
+	// without restarting the daemon.
+	_, err = coll.NewFromPath("org/repo2")
+	require.NoError(t, err)
+	coll.Repositories[1].Schedule = &ghere.Schedule{Interval: ghere.Duration(time.Hour)}
+	require.NoError(t, coll.Save())
+	require.Eventually(t, func() bool {
+		status := daemon.Status()
+		return len(status.Repositories) == 2 && status.Repositories[1].LastSuccessAt != nil
+	}, 5*time.Second, 10*time.Millisecond)
+	status = daemon.Status()
+	assert.Equal(t, "org/repo2", status.Repositories[1].Repository)
+	assert.Equal(t, ghere.Duration(time.Hour), status.Repositories[1].Interval)
+	assert.True(t, status.Repositories[1].NextRun.After(time.Now().Add(50*time.Minute)))
+
+	// Projects must be scheduled along with repositories.
+	_, err = coll.NewProjectFromPath("org/projects/1")
+	require.NoError(t, err)
+	require.NoError(t, coll.Save())
+	require.Eventually(t, func() bool {
+		status := daemon.Status()
+		return len(status.Repositories) == 3 && status.Repositories[0].LastSuccessAt != nil
+	}, 5*time.Second, 10*time.Millisecond)
+	status = daemon.Status()
+	assert.Equal(t, "org/projects/1", status.Repositories[0].Repository)
+	assert.Empty(t, status.Repositories[0].LastRunError)
+	assert.FileExists(t, filepath.Join(tmpDir, "org", "_org", "projects", "000001", ghere.DETAIL_FILENAME))
+
+	// As must organizations.
+	_, err = coll.NewOrganization("org")
+	require.NoError(t, err)
+	require.NoError(t, coll.Save())
+	require.Eventually(t, func() bool {
+		status := daemon.Status()
+		return len(status.Repositories) == 4 && status.Repositories[0].LastSuccessAt != nil
+	}, 5*time.Second, 10*time.Millisecond)
+	status = daemon.Status()
+	assert.Equal(t, "org", status.Repositories[0].Repository)
+	assert.Empty(t, status.Repositories[0].LastRunError)
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("daemon did not stop")
+	}
+}
+
+func TestDaemonHostReconfiguration(t *testing.T) {
+	log := ghere.NewNoopLogger()
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, ghere.CONFIG_FILE_NAME)
+	coll, err := ghere.LoadOrCreateLocalCollection(configFile)
+	require.NoError(t, err)
+
+	host := "github.example.com"
+	owner := "org"
+	name := "repo"
+	repoID := owner + "/" + name
+	coll.Schedule = &ghere.Schedule{Interval: ghere.Duration(time.Hour)}
+	_, err = coll.NewFromPath(repoID)
+	require.NoError(t, err)
+	require.NoError(t, coll.Save())
+
+	newClient := func() *MockGitHubClient {
+		return &MockGitHubClient{
+			Repositories: map[string]*github.Repository{
+				repoID: {Owner: &github.User{Login: &owner}, Name: &name},
+			},
+		}
+	}
+	newFetchConfig := func(coll *ghere.LocalCollection) (*ghere.FetchConfig, error) {
+		cfg := &ghere.FetchConfig{
+			Client:             newClient(),
+			CredentialProvider: &MockGitHubCredentialProvider{},
+			RepoUpdater:        &MockGitHubRepositoryUpdater{},
+			Hosts:              map[string]*ghere.HostFetchConfig{},
+		}
+		for hostName := range coll.Hosts {
+			cfg.Hosts[hostName] = &ghere.HostFetchConfig{
+				Client:             newClient(),
+				CredentialProvider: &MockGitHubCredentialProvider{},
+			}
+		}
+		return cfg, nil
+	}
+	cfg, err := newFetchConfig(coll)
+	require.NoError(t, err)
+	run := func(daemonCfg *ghere.DaemonConfig) (*ghere.Daemon, func()) {
+		daemon := ghere.NewDaemon(configFile, daemonCfg, log)
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() {
+			done <- daemon.Run(ctx)
+		}()
+		return daemon, func() {
+			cancel()
+			require.NoError(t, <-done)
+		}
+	}
+
+	// Repositories on newly configured hosts must be fetched once the fetch
+	// configuration has been reconstructed.
+	daemon, stop := run(&ghere.DaemonConfig{
+		Fetch:          cfg,
+		ReloadInterval: 20 * time.Millisecond,
+		NewFetchConfig: newFetchConfig,
+	})
+	require.Eventually(t, func() bool {
+		status := daemon.Status()
+		return len(status.Repositories) == 1 && status.Repositories[0].LastSuccessAt != nil
+	}, 5*time.Second, 10*time.Millisecond)
+	coll.Hosts = map[string]*ghere.GitHubHost{
+		host: {BaseURL: "https://github.example.com/api/v3/"},
+	}
+	_, err = coll.NewFromPath(host + "/" + repoID)
+	require.NoError(t, err)
+	require.NoError(t, coll.Save())
+	require.Eventually(t, func() bool {
+		status := daemon.Status()
+		if len(status.Repositories) != 2 {
+			return false
+		}
+		for _, repoStatus := range status.Repositories {
+			if repoStatus.LastSuccessAt == nil {
+				return false
+			}
+		}
+		return true
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.FileExists(t, filepath.Join(tmpDir, host, owner, name, ghere.DETAIL_FILENAME))
+	stop()
+
+	// Without a way of reconstructing the fetch configuration, host changes
+	// must be rejected.
+	cfg, err = newFetchConfig(coll)
+	require.NoError(t, err)
+	daemon, stop = run(&ghere.DaemonConfig{Fetch: cfg, ReloadInterval: 20 * time.Millisecond})
+	defer stop()
+	require.Eventually(t, func() bool {
+		return len(daemon.Status().Repositories) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	loadedAt := daemon.Status().ConfigLoadedAt
+	coll.Hosts[host].BaseURL = "https://github.example.com/api/v4/"
+	require.NoError(t, coll.Save())
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, loadedAt, daemon.Status().ConfigLoadedAt)
+}
@@ -0,0 +1,356 @@
+package ghere
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// GitHubActor is the user (or bot) that authored or acted upon a discussion
+// or comment.
+type GitHubActor struct {
+	Login string `json:"login"`
+}
+
+// GitHubDiscussionCategory is a category of a repository's discussions, as
+// provided by GitHub's GraphQL API.
+type GitHubDiscussionCategory struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	Slug         string    `json:"slug"`
+	Description  string    `json:"description,omitempty"`
+	Emoji        string    `json:"emoji,omitempty"`
+	IsAnswerable bool      `json:"isAnswerable"`
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+// GitHubDiscussionCommentRef refers to a discussion comment.
+type GitHubDiscussionCommentRef struct {
+	ID         string `json:"id"`
+	DatabaseID int64  `json:"databaseId"`
+}
+
+// GitHubDiscussion is a repository discussion, as provided by GitHub's
+// GraphQL API.
+type GitHubDiscussion struct {
+	ID          string                    `json:"id"`
+	Number      int                       `json:"number"`
+	Title       string                    `json:"title"`
+	Body        string                    `json:"body"`
+	URL         string                    `json:"url"`
+	Author      *GitHubActor              `json:"author"`
+	Category    *GitHubDiscussionCategory `json:"category"`
+	Closed      bool                      `json:"closed"`
+	Locked      bool                      `json:"locked"`
+	UpvoteCount int                       `json:"upvoteCount"`
+	CreatedAt   time.Time                 `json:"createdAt"`
+	UpdatedAt   time.Time                 `json:"updatedAt"`
+	// Answer refers to the comment marked as the discussion's answer, if any.
+	Answer         *GitHubDiscussionCommentRef `json:"answer"`
+	AnswerChosenAt *time.Time                  `json:"answerChosenAt"`
+	AnswerChosenBy *GitHubActor                `json:"answerChosenBy"`
+}
+
+// GitHubDiscussionComment is a comment on a discussion (or a reply to such a
+// comment), as provided by GitHub's GraphQL API.
+type GitHubDiscussionComment struct {
+	ID          string       `json:"id"`
+	DatabaseID  int64        `json:"databaseId"`
+	Body        string       `json:"body"`
+	URL         string       `json:"url"`
+	Author      *GitHubActor `json:"author"`
+	IsAnswer    bool         `json:"isAnswer"`
+	UpvoteCount int          `json:"upvoteCount"`
+	CreatedAt   time.Time    `json:"createdAt"`
+	UpdatedAt   time.Time    `json:"updatedAt"`
+	// ReplyTo refers to the comment to which this comment is a reply, if any.
+	ReplyTo *GitHubDiscussionCommentRef `json:"replyTo"`
+	// Replies are the replies to this comment, oldest first. Replies cannot
+	// themselves be replied to.
+	Replies []*GitHubDiscussionComment `json:"replies,omitempty"`
+}
+
+type DiscussionCategory struct {
+	Category *GitHubDiscussionCategory `json:"category"`
+}
+
+func LoadDiscussionCategory(rootPath string, repo *Repository, categoryID string, mustExist bool) (*DiscussionCategory, error) {
+	var err error
+	category := &DiscussionCategory{}
+	path := discussionCategoryPath(rootPath, repo.GetOwner(), repo.GetName(), categoryID)
+	if mustExist {
+		err = readJSONFile(path, category)
+	} else {
+		err = readJSONFileOrEmpty(path, category)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read discussion category file: %v", err)
+	}
+	return category, nil
+}
+
+func (c *DiscussionCategory) Save(rootPath string, repo *Repository, prettyJSON bool) error {
+	path := discussionCategoryPath(rootPath, repo.GetOwner(), repo.GetName(), c.Category.ID)
+	if err := writeJSONFile(path, c, prettyJSON); err != nil {
+		return fmt.Errorf("failed to write discussion category file: %v", err)
+	}
+	return nil
+}
+
+type Discussion struct {
+	Discussion *GitHubDiscussion `json:"discussion"`
+
+	LastDetailFetch   time.Time `json:"last_detail_fetch"`
+	LastCommentsFetch time.Time `json:"last_comments_fetch"`
+}
+
+func LoadDiscussion(rootPath string, repo *Repository, discussionNum int, mustExist bool) (*Discussion, error) {
+	path := discussionDetailPath(rootPath, repo.GetOwner(), repo.GetName(), discussionNum)
+	return LoadDiscussionDirect(path, mustExist)
+}
+
+func LoadDiscussionDirect(path string, mustExist bool) (*Discussion, error) {
+	var err error
+	discussion := &Discussion{}
+	if mustExist {
+		err = readJSONFile(path, discussion)
+	} else {
+		err = readJSONFileOrEmpty(path, discussion)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read discussion detail file: %v", err)
+	}
+	return discussion, nil
+}
+
+func (d *Discussion) Save(rootPath string, repo *Repository, prettyJSON bool) error {
+	path := discussionDetailPath(rootPath, repo.GetOwner(), repo.GetName(), d.GetNumber())
+	if err := writeJSONFile(path, d, prettyJSON); err != nil {
+		return fmt.Errorf("failed to write discussion detail file: %v", err)
+	}
+	return nil
+}
+
+// GetNumber is a shortcut for accessing the inner discussion's number.
+func (d *Discussion) GetNumber() int {
+	return d.Discussion.Number
+}
+
+func (d *Discussion) MustFetchComments() bool {
+	return d.Discussion.UpdatedAt.After(d.LastCommentsFetch)
+}
+
+// DiscussionComment is a top-level comment on a discussion, along with its
+// replies.
+type DiscussionComment struct {
+	Comment *GitHubDiscussionComment `json:"comment"`
+}
+
+func LoadDiscussionComment(rootPath string, repo *Repository, discussionNum int, commentID int64, mustExist bool) (*DiscussionComment, error) {
+	var err error
+	comment := &DiscussionComment{}
+	path := discussionCommentPath(rootPath, repo.GetOwner(), repo.GetName(), discussionNum, commentID)
+	if mustExist {
+		err = readJSONFile(path, comment)
+	} else {
+		err = readJSONFileOrEmpty(path, comment)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read discussion comment file: %v", err)
+	}
+	return comment, nil
+}
+
+func (c *DiscussionComment) Save(rootPath string, repo *Repository, discussionNum int, prettyJSON bool) error {
+	path := discussionCommentPath(rootPath, repo.GetOwner(), repo.GetName(), discussionNum, c.Comment.DatabaseID)
+	if err := writeJSONFile(path, c, prettyJSON); err != nil {
+		return fmt.Errorf("failed to write discussion comment file: %v", err)
+	}
+	return nil
+}
+
+// discussionsFetcher fetches a repository's discussion categories, followed by
+// the discussions updated since the last fetch.
+type discussionsFetcher struct {
+	rootPath string
+	repo     *Repository
+}
+
+var _ fetcher = (*discussionsFetcher)(nil)
+var _ planner = (*discussionsFetcher)(nil)
+
+func newDiscussionsFetcher(rootPath string, repo *Repository) *discussionsFetcher {
+	return &discussionsFetcher{
+		rootPath: rootPath,
+		repo:     repo,
+	}
+}
+
+func (f *discussionsFetcher) resource() string {
+	return RESOURCE_DISCUSSIONS
+}
+
+func (f *discussionsFetcher) fetch(ctx context.Context, cfg *FetchConfig, log Logger) ([]fetcher, error) {
+	fetchStarted := time.Now()
+	categories, err := cfg.Client.ListDiscussionCategories(ctx, f.repo.GetOwner(), f.repo.GetName())
+	if err != nil {
+		return nil, err
+	}
+	// Repositories with discussions enabled always have at least one
+	// category.
+	if len(categories) == 0 {
+		log.Info("Repository has no discussion categories; skipping discussions", "repo", f.repo.String())
+		return nil, nil
+	}
+	for _, ghCategory := range categories {
+		category, err := LoadDiscussionCategory(f.rootPath, f.repo, ghCategory.ID, false)
+		if err != nil {
+			return nil, err
+		}
+		cfg.report.recordItem(RESOURCE_DISCUSSION_CATEGORIES, category.Category != nil, category.Category, ghCategory)
+		category.Category = ghCategory
+		if err := category.Save(f.rootPath, f.repo, cfg.PrettyJSON); err != nil {
+			return nil, err
+		}
+	}
+	log.Info("Fetched discussion categories", "repo", f.repo.String(), "categories", len(categories))
+
+	// Discussions are listed from the most to the least recently updated, so
+	// we can stop as soon as we encounter one that has not been updated since
+	// the last fetch.
+	updated := 0
+	done := false
+	for cursor := ""; !done; {
+		var discussions []*GitHubDiscussion
+		discussions, cursor, err = cfg.Client.ListDiscussions(ctx, f.repo.GetOwner(), f.repo.GetName(), cursor)
+		if err != nil {
+			return nil, err
+		}
+		done = len(cursor) == 0
+		for _, ghDiscussion := range discussions {
+			if !ghDiscussion.UpdatedAt.After(f.repo.LastDiscussionsFetch) {
+				done = true
+				break
+			}
+			discussion, err := LoadDiscussion(f.rootPath, f.repo, ghDiscussion.Number, false)
+			if err != nil {
+				return nil, err
+			}
+			cfg.report.recordItem(RESOURCE_DISCUSSIONS, discussion.Discussion != nil, discussion.Discussion, ghDiscussion)
+			discussion.Discussion = ghDiscussion
+			discussion.LastDetailFetch = time.Now()
+			if err := discussion.Save(f.rootPath, f.repo, cfg.PrettyJSON); err != nil {
+				return nil, err
+			}
+			updated++
+		}
+	}
+	log.Info("Fetched updated discussions' details", "repo", f.repo.String(), "discussions", updated)
+
+	f.repo.LastDiscussionsFetch = fetchStarted
+	if err := f.repo.Save(f.rootPath, cfg.PrettyJSON); err != nil {
+		return nil, err
+	}
+
+	fetchComments, err := f.discussionsWithStaleComments()
+	if err != nil {
+		return nil, err
+	}
+	if len(fetchComments) == 0 {
+		return nil, nil
+	}
+	return []fetcher{newDiscussionCommentsFetcher(f.rootPath, f.repo, fetchComments)}, nil
+}
+
+// discussionsWithStaleComments returns the local discussions whose comments
+// must be fetched.
+func (f *discussionsFetcher) discussionsWithStaleComments() ([]*Discussion, error) {
+	fetchComments := []*Discussion{}
+	discussionsPath := repoDiscussionsPath(f.rootPath, f.repo.GetOwner(), f.repo.GetName())
+	pattern := filepath.Join(discussionsPath, "*", DETAIL_FILENAME)
+	discussionDetailFiles, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list discussions' detail files from pattern %s: %v", pattern, err)
+	}
+	for _, fn := range discussionDetailFiles {
+		discussion, err := LoadDiscussionDirect(fn, true)
+		if err != nil {
+			return nil, err
+		}
+		if discussion.MustFetchComments() {
+			fetchComments = append(fetchComments, discussion)
+		}
+	}
+	return fetchComments, nil
+}
+
+func (f *discussionsFetcher) plan(cfg *FetchConfig) (*FetcherPlan, error) {
+	fetchComments, err := f.discussionsWithStaleComments()
+	if err != nil {
+		return nil, err
+	}
+	return &FetcherPlan{
+		Resource:          RESOURCE_DISCUSSIONS,
+		Description:       fmt.Sprintf("list discussion categories and updated discussions, then fetch comments for at least %d discussion(s)", len(fetchComments)),
+		EstimatedAPICalls: 2 + len(fetchComments),
+	}, nil
+}
+
+type discussionCommentsFetcher struct {
+	rootPath    string
+	repo        *Repository
+	discussions []*Discussion
+}
+
+var _ fetcher = (*discussionCommentsFetcher)(nil)
+
+func newDiscussionCommentsFetcher(rootPath string, repo *Repository, discussions []*Discussion) *discussionCommentsFetcher {
+	return &discussionCommentsFetcher{
+		rootPath:    rootPath,
+		repo:        repo,
+		discussions: discussions,
+	}
+}
+
+func (f *discussionCommentsFetcher) resource() string {
+	return RESOURCE_DISCUSSION_COMMENTS
+}
+
+func (f *discussionCommentsFetcher) fetch(ctx context.Context, cfg *FetchConfig, log Logger) ([]fetcher, error) {
+	for _, discussion := range f.discussions {
+		done := false
+		for cursor := ""; !done; {
+			var comments []*GitHubDiscussionComment
+			var err error
+			comments, cursor, err = cfg.Client.ListDiscussionComments(
+				ctx,
+				f.repo.GetOwner(),
+				f.repo.GetName(),
+				discussion.GetNumber(),
+				cursor,
+			)
+			if err != nil {
+				return nil, err
+			}
+			done = len(cursor) == 0
+			for _, ghComment := range comments {
+				comment, err := LoadDiscussionComment(f.rootPath, f.repo, discussion.GetNumber(), ghComment.DatabaseID, false)
+				if err != nil {
+					return nil, err
+				}
+				cfg.report.recordItem(RESOURCE_DISCUSSION_COMMENTS, comment.Comment != nil, comment.Comment, ghComment)
+				comment.Comment = ghComment
+				if err := comment.Save(f.rootPath, f.repo, discussion.GetNumber(), cfg.PrettyJSON); err != nil {
+					return nil, err
+				}
+			}
+		}
+		discussion.LastCommentsFetch = time.Now()
+		if err := discussion.Save(f.rootPath, f.repo, cfg.PrettyJSON); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,184 @@
+package ghere_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v48/github"
+	"github.com/informalsystems/ghere/pkg/ghere"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDiscussionsServer serves a repository's details via the REST API and its
+// discussions via the GraphQL API, serving one discussion per page.
+type fakeDiscussionsServer struct {
+	mtx         sync.Mutex
+	discussions []map[string]interface{}
+	comments    map[int][]map[string]interface{}
+	// Replies that are only returned when explicitly paginating through a
+	// comment's replies.
+	moreReplies map[string][]map[string]interface{}
+	// The discussions whose comments were requested.
+	commentRequests []int
+}
+
+func (s *fakeDiscussionsServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if r.URL.Path == "/repos/org/repo" {
+		fmt.Fprintf(w, `{"name": "repo", "owner": {"login": "org"}, "updated_at": %q}`, time.Now().Format(time.RFC3339))
+		return
+	}
+	if r.URL.Path != "/graphql" || r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	var req struct {
+		Query     string                 `json:"query"`
+		Variables map[string]interface{} `json:"variables"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var data interface{}
+	switch {
+	case strings.Contains(req.Query, "discussionCategories"):
+		data = map[string]interface{}{"repository": map[string]interface{}{
+			"discussionCategories": connection([]map[string]interface{}{
+				{"id": "DIC_1", "name": "Q&A", "slug": "q-a", "isAnswerable": true},
+			}, ""),
+		}}
+	case strings.Contains(req.Query, "node(id: $id)"):
+		data = map[string]interface{}{"node": map[string]interface{}{
+			"replies": connection(s.moreReplies[req.Variables["id"].(string)], ""),
+		}}
+	case strings.Contains(req.Query, "discussion(number: $number)"):
+		num := int(req.Variables["number"].(float64))
+		s.commentRequests = append(s.commentRequests, num)
+		data = map[string]interface{}{"repository": map[string]interface{}{
+			"discussion": map[string]interface{}{"comments": connection(s.comments[num], "")},
+		}}
+	case strings.Contains(req.Query, "discussions("):
+		page := 0
+		if after, ok := req.Variables["after"].(string); ok {
+			page, _ = strconv.Atoi(after)
+		}
+		nodes := []map[string]interface{}{}
+		next := ""
+		if page < len(s.discussions) {
+			nodes = append(nodes, s.discussions[page])
+			if page+1 < len(s.discussions) {
+				next = strconv.Itoa(page + 1)
+			}
+		}
+		data = map[string]interface{}{"repository": map[string]interface{}{
+			"discussions": connection(nodes, next),
+		}}
+	default:
+		fmt.Fprint(w, `{"errors": [{"message": "unexpected query"}]}`)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+}
+
+func connection(nodes []map[string]interface{}, next string) map[string]interface{} {
+	return map[string]interface{}{
+		"nodes":    nodes,
+		"pageInfo": map[string]interface{}{"hasNextPage": len(next) > 0, "endCursor": next},
+	}
+}
+
+func TestCollectionFetchDiscussions(t *testing.T) {
+	log := ghere.NewNoopLogger()
+	tmpDir := t.TempDir()
+	coll, err := ghere.LoadOrCreateLocalCollection(filepath.Join(tmpDir, ghere.CONFIG_FILE_NAME))
+	require.NoError(t, err)
+	_, err = coll.NewFromPath("org/repo")
+	require.NoError(t, err)
+
+	now := time.Now().UTC()
+	discussion := func(num int, updatedAt time.Time) map[string]interface{} {
+		return map[string]interface{}{
+			"id":        fmt.Sprintf("D_%d", num),
+			"number":    num,
+			"title":     fmt.Sprintf("Discussion %d", num),
+			"updatedAt": updatedAt.Format(time.RFC3339),
+			"category":  map[string]interface{}{"id": "DIC_1", "slug": "q-a"},
+		}
+	}
+	answered := discussion(1, now.Add(-time.Hour))
+	answered["answer"] = map[string]interface{}{"id": "DC_11", "databaseId": 11}
+	srv := &fakeDiscussionsServer{
+		discussions: []map[string]interface{}{answered, discussion(2, now.Add(-2*time.Hour))},
+		comments: map[int][]map[string]interface{}{
+			1: {{
+				"id":         "DC_11",
+				"databaseId": 11,
+				"isAnswer":   true,
+				"replies": connection([]map[string]interface{}{
+					{"id": "DC_21", "databaseId": 21, "replyTo": map[string]interface{}{"id": "DC_11", "databaseId": 11}},
+				}, "1"),
+			}},
+			2: {{"id": "DC_12", "databaseId": 12, "replies": connection(nil, "")}},
+		},
+		moreReplies: map[string][]map[string]interface{}{
+			"DC_11": {{"id": "DC_22", "databaseId": 22, "replyTo": map[string]interface{}{"id": "DC_11", "databaseId": 11}}},
+		},
+	}
+	httpSrv := httptest.NewServer(srv)
+	defer httpSrv.Close()
+	baseURL, err := url.Parse(httpSrv.URL + "/")
+	require.NoError(t, err)
+	client := github.NewClient(nil)
+	client.BaseURL = baseURL
+	cfg := &ghere.FetchConfig{
+		Client:             ghere.NewGitHubClient(client, nil, log),
+		CredentialProvider: &MockGitHubCredentialProvider{},
+		RepoUpdater:        &MockGitHubRepositoryUpdater{},
+		Resources:          &ghere.ResourceSelection{Only: []string{ghere.RESOURCE_DISCUSSIONS}},
+	}
+
+	_, err = coll.Fetch(context.Background(), cfg, log)
+	require.NoError(t, err)
+	discussionsPath := filepath.Join(tmpDir, "org", "repo", "discussions")
+	assert.FileExists(t, filepath.Join(discussionsPath, "categories", "DIC_1.json"))
+	d, err := ghere.LoadDiscussionDirect(filepath.Join(discussionsPath, "000001", ghere.DETAIL_FILENAME), true)
+	require.NoError(t, err)
+	require.NotNil(t, d.Discussion.Answer)
+	assert.Equal(t, int64(11), d.Discussion.Answer.DatabaseID)
+	assert.FileExists(t, filepath.Join(discussionsPath, "000002", ghere.DETAIL_FILENAME))
+	c := &ghere.DiscussionComment{}
+	require.NoError(t, ghere.ReadJSONFile(filepath.Join(discussionsPath, "000001", "comments", "11.json"), c))
+	assert.True(t, c.Comment.IsAnswer)
+	require.Len(t, c.Comment.Replies, 2)
+	assert.Equal(t, int64(21), c.Comment.Replies[0].DatabaseID)
+	assert.Equal(t, int64(22), c.Comment.Replies[1].DatabaseID)
+	assert.ElementsMatch(t, []int{1, 2}, srv.commentRequests)
+
+	// Only the discussion updated since the last fetch must be refetched,
+	// along with its comments.
+	srv.mtx.Lock()
+	updated := discussion(2, time.Now().Add(time.Minute))
+	updated["title"] = "Discussion 2 (edited)"
+	srv.discussions = []map[string]interface{}{updated, answered}
+	srv.commentRequests = nil
+	srv.mtx.Unlock()
+	_, err = coll.Fetch(context.Background(), cfg, log)
+	require.NoError(t, err)
+	d, err = ghere.LoadDiscussionDirect(filepath.Join(discussionsPath, "000002", ghere.DETAIL_FILENAME), true)
+	require.NoError(t, err)
+	assert.Equal(t, "Discussion 2 (edited)", d.Discussion.Title)
+	assert.Equal(t, []int{2}, srv.commentRequests)
+}
@@ -1,10 +1,14 @@
 package ghere
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 // ErrRepositoryAlreadyExists is returned from a call that attempts to create a
 // repository, but that repository already exists.
 type ErrRepositoryAlreadyExists struct {
+	Host  string
 	Owner string
 	Name  string
 }
@@ -12,5 +16,103 @@ type ErrRepositoryAlreadyExists struct {
 var _ error = (*ErrRepositoryAlreadyExists)(nil)
 
 func (e *ErrRepositoryAlreadyExists) Error() string {
+	if len(e.Host) > 0 {
+		return fmt.Sprintf("repository already exists: %s/%s/%s", e.Host, e.Owner, e.Name)
+	}
 	return fmt.Sprintf("repository already exists: %s/%s", e.Owner, e.Name)
 }
+
+// ErrProjectAlreadyExists is returned from a call that attempts to add a
+// project to a collection, but that project already exists.
+type ErrProjectAlreadyExists struct {
+	Path string
+}
+
+var _ error = (*ErrProjectAlreadyExists)(nil)
+
+func (e *ErrProjectAlreadyExists) Error() string {
+	return fmt.Sprintf("project already exists: %s", e.Path)
+}
+
+// ErrOrganizationAlreadyExists is returned from a call that attempts to add an
+// organization to a collection, but that organization already exists.
+type ErrOrganizationAlreadyExists struct {
+	Host  string
+	Owner string
+}
+
+var _ error = (*ErrOrganizationAlreadyExists)(nil)
+
+func (e *ErrOrganizationAlreadyExists) Error() string {
+	if len(e.Host) > 0 {
+		return fmt.Sprintf("organization already exists: %s/%s", e.Host, e.Owner)
+	}
+	return fmt.Sprintf("organization already exists: %s", e.Owner)
+}
+
+// ErrDownloadTooLarge is returned when a download (e.g. of a workflow run's
+// logs) is larger than the configured size limit.
+type ErrDownloadTooLarge struct {
+	Limit int64
+}
+
+var _ error = (*ErrDownloadTooLarge)(nil)
+
+func (e *ErrDownloadTooLarge) Error() string {
+	return fmt.Sprintf("download exceeds size limit of %d bytes", e.Limit)
+}
+
+// ErrArchiveTooLarge is returned when the extracted contents of an archive
+// would be larger than the permitted size.
+type ErrArchiveTooLarge struct {
+	Limit int64
+}
+
+var _ error = (*ErrArchiveTooLarge)(nil)
+
+func (e *ErrArchiveTooLarge) Error() string {
+	return fmt.Sprintf("extracted archive exceeds size limit of %d bytes", e.Limit)
+}
+
+// ErrDownloadUnavailable is returned when a download (e.g. of a workflow run's
+// logs) is no longer available from GitHub, usually because it expired.
+type ErrDownloadUnavailable struct {
+	Status string
+}
+
+var _ error = (*ErrDownloadUnavailable)(nil)
+
+func (e *ErrDownloadUnavailable) Error() string {
+	return fmt.Sprintf("download unavailable: %s", e.Status)
+}
+
+// ErrFetchInterrupted is returned when fetching a collection is interrupted
+// (e.g. by way of SIGINT/SIGTERM) before all of its repositories could be
+// fetched. All progress made up to that point is persisted.
+type ErrFetchInterrupted struct {
+	// Cause is the reason for the interruption (usually context.Canceled).
+	Cause error
+	// Completed lists the repositories that were completely fetched.
+	Completed []string
+	// Interrupted is the repository whose fetch was interrupted, if any.
+	Interrupted string
+	// Remaining lists the repositories that were not fetched at all.
+	Remaining []string
+}
+
+var _ error = (*ErrFetchInterrupted)(nil)
+
+func (e *ErrFetchInterrupted) Error() string {
+	msg := fmt.Sprintf("fetch interrupted (%v) after fetching %d repositories", e.Cause, len(e.Completed))
+	if len(e.Interrupted) > 0 {
+		msg += fmt.Sprintf("; partially fetched: %s", e.Interrupted)
+	}
+	if len(e.Remaining) > 0 {
+		msg += fmt.Sprintf("; not fetched: %s", strings.Join(e.Remaining, ", "))
+	}
+	return msg
+}
+
+func (e *ErrFetchInterrupted) Unwrap() error {
+	return e.Cause
+}
@@ -0,0 +1,338 @@
+package ghere
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/google/go-github/v48/github"
+)
+
+// GitHub only serves up to 300 of a repository's most recent events (or those
+// from the past 90 days, whichever is fewer), 100 per page.
+const maxRepositoryEventPages = 3
+
+// repositoryChanges describes the issues and pull requests that changed since
+// the last processed event in a repository's events feed.
+type repositoryChanges struct {
+	// complete is true if the events feed contained all events since the last
+	// processed event. If false, changes can only be detected by listing all
+	// issues/pull requests.
+	complete bool
+	// latestEventID is the ID of the most recent event in the feed.
+	latestEventID string
+	issues        map[int]bool
+	pullRequests  map[int]bool
+}
+
+// detectRepositoryChanges reads the repository's events feed, from the most
+// recent event back to the last processed event, to determine which issues and
+// pull requests changed in the meantime.
+func detectRepositoryChanges(ctx context.Context, cfg *FetchConfig, repo *Repository, log Logger) (*repositoryChanges, error) {
+	changes := &repositoryChanges{
+		issues:       make(map[int]bool),
+		pullRequests: make(map[int]bool),
+	}
+	done := false
+	for page := 1; !done && page <= maxRepositoryEventPages; page++ {
+		var events []*github.Event
+		var err error
+		events, done, err = cfg.Client.ListRepositoryEvents(ctx, repo.GetOwner(), repo.GetName(), page)
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range events {
+			if len(changes.latestEventID) == 0 {
+				changes.latestEventID = event.GetID()
+			}
+			if len(repo.LastEventID) > 0 && !eventIsAfter(event.GetID(), repo.LastEventID) {
+				return changes.completed(repo, log), nil
+			}
+			if err := changes.add(event); err != nil {
+				log.Warn("Failed to parse repository event; falling back to full listing", "repo", repo.String(), "event", event.GetID(), "err", err)
+				return changes, nil
+			}
+		}
+	}
+	if len(repo.LastEventID) == 0 {
+		return changes, nil
+	}
+	// If we reached the end of the feed (or it was empty) without finding the
+	// last processed event, the feed contains every event since then (e.g.
+	// the last processed event was the oldest, and has since expired).
+	if done {
+		return changes.completed(repo, log), nil
+	}
+	log.Info("Last processed event is no longer in the repository's events feed; falling back to full listing", "repo", repo.String())
+	return changes, nil
+}
+
+func (c *repositoryChanges) completed(repo *Repository, log Logger) *repositoryChanges {
+	c.complete = true
+	log.Info(
+		"Detected changes from repository events",
+		"repo", repo.String(),
+		"issues", len(c.issues),
+		"pullRequests", len(c.pullRequests),
+	)
+	return c
+}
+
+// eventIsAfter returns whether the event with the given ID occurred after the
+// event with the given other ID. Event IDs are numeric and increase over time.
+func eventIsAfter(id, otherID string) bool {
+	n, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return id != otherID
+	}
+	other, err := strconv.ParseInt(otherID, 10, 64)
+	if err != nil {
+		return id != otherID
+	}
+	return n > other
+}
+
+// add records the issue or pull request affected by the given event, if any.
+// Pull requests are also recorded as issues, since every pull request has a
+// corresponding issue.
+func (c *repositoryChanges) add(event *github.Event) error {
+	switch event.GetType() {
+	case "IssuesEvent", "IssueCommentEvent", "PullRequestEvent", "PullRequestReviewEvent",
+		"PullRequestReviewCommentEvent", "PullRequestReviewThreadEvent":
+	default:
+		return nil
+	}
+	payload, err := event.ParsePayload()
+	if err != nil {
+		return err
+	}
+	switch p := payload.(type) {
+	case *github.IssuesEvent:
+		c.addIssue(p.GetIssue())
+	case *github.IssueCommentEvent:
+		c.addIssue(p.GetIssue())
+	case *github.PullRequestEvent:
+		c.addPullRequest(p.GetPullRequest().GetNumber())
+	case *github.PullRequestReviewEvent:
+		c.addPullRequest(p.GetPullRequest().GetNumber())
+	case *github.PullRequestReviewCommentEvent:
+		c.addPullRequest(p.GetPullRequest().GetNumber())
+	case *github.PullRequestReviewThreadEvent:
+		c.addPullRequest(p.GetPullRequest().GetNumber())
+	}
+	return nil
+}
+
+func (c *repositoryChanges) addIssue(issue *github.Issue) {
+	c.issues[issue.GetNumber()] = true
+	if issue.IsPullRequest() {
+		c.pullRequests[issue.GetNumber()] = true
+	}
+}
+
+func (c *repositoryChanges) addPullRequest(num int) {
+	c.issues[num] = true
+	c.pullRequests[num] = true
+}
+
+// isItemGone returns whether the given error, resulting from a request for a
+// specific issue or pull request, indicates that the item no longer exists
+// (e.g. because it was deleted or transferred to another repository).
+func isItemGone(err error) bool {
+	var errRes *github.ErrorResponse
+	if !errors.As(err, &errRes) || errRes.Response == nil {
+		return false
+	}
+	return errRes.Response.StatusCode == http.StatusNotFound || errRes.Response.StatusCode == http.StatusGone
+}
+
+func sortedNumbers(m map[int]bool) []int {
+	nums := make([]int, 0, len(m))
+	for n := range m {
+		nums = append(nums, n)
+	}
+	sort.Ints(nums)
+	return nums
+}
+
+// issueUpdatesFetcher fetches specific issues (and their comments), as opposed
+// to listing all of a repository's issues.
+type issueUpdatesFetcher struct {
+	rootPath  string
+	repo      *Repository
+	numbers   []int
+	timelines bool
+}
+
+var _ fetcher = (*issueUpdatesFetcher)(nil)
+
+func newIssueUpdatesFetcher(rootPath string, repo *Repository, numbers []int, timelines bool) *issueUpdatesFetcher {
+	return &issueUpdatesFetcher{
+		rootPath:  rootPath,
+		repo:      repo,
+		numbers:   numbers,
+		timelines: timelines,
+	}
+}
+
+func (f *issueUpdatesFetcher) resource() string {
+	return RESOURCE_ISSUES
+}
+
+func (f *issueUpdatesFetcher) fetch(ctx context.Context, cfg *FetchConfig, log Logger) ([]fetcher, error) {
+	fetchComments := []*Issue{}
+	fetchEvents := []*Issue{}
+	deleted := 0
+	for _, num := range f.numbers {
+		ghIssue, err := cfg.Client.GetIssue(ctx, f.repo.GetOwner(), f.repo.GetName(), num)
+		if err != nil && !isItemGone(err) {
+			return nil, err
+		}
+		issue, loadErr := LoadIssue(f.rootPath, f.repo, num, false)
+		if loadErr != nil {
+			return nil, loadErr
+		}
+		if err != nil {
+			log.Info("Changed issue no longer exists; skipping", "repo", f.repo.String(), "issue", num, "err", err)
+			if issue.Issue != nil {
+				deleted++
+			}
+			continue
+		}
+		cfg.report.recordItem(RESOURCE_ISSUES, issue.Issue != nil, issue.Issue, ghIssue)
+		issue.Issue = ghIssue
+		issue.LastDetailFetch = time.Now()
+		if err := issue.fetchReactions(ctx, cfg, f.repo); err != nil {
+			return nil, err
+		}
+		if err := issue.Save(f.rootPath, f.repo, cfg.PrettyJSON); err != nil {
+			return nil, err
+		}
+		if issue.MustUpdateComments() {
+			fetchComments = append(fetchComments, issue)
+		}
+		if f.timelines && issue.MustFetchEvents() {
+			fetchEvents = append(fetchEvents, issue)
+		}
+	}
+	cfg.report.recordDeleted(RESOURCE_ISSUES, deleted)
+	log.Info("Fetched changed issues' details", "repo", f.repo.String(), "issues", len(f.numbers))
+	fetchers := []fetcher{}
+	if len(fetchComments) > 0 {
+		fetchers = append(fetchers, newIssueCommentsFetcher(f.rootPath, f.repo, fetchComments))
+	}
+	if len(fetchEvents) > 0 {
+		fetchers = append(fetchers, newIssueEventsFetcher(f.rootPath, f.repo, fetchEvents))
+	}
+	return fetchers, nil
+}
+
+// pullRequestUpdatesFetcher fetches specific pull requests (and their reviews
+// and comments), as opposed to listing all of a repository's pull requests.
+type pullRequestUpdatesFetcher struct {
+	rootPath  string
+	repo      *Repository
+	numbers   []int
+	timelines bool
+}
+
+var _ fetcher = (*pullRequestUpdatesFetcher)(nil)
+
+func newPullRequestUpdatesFetcher(rootPath string, repo *Repository, numbers []int, timelines bool) *pullRequestUpdatesFetcher {
+	return &pullRequestUpdatesFetcher{
+		rootPath:  rootPath,
+		repo:      repo,
+		numbers:   numbers,
+		timelines: timelines,
+	}
+}
+
+func (f *pullRequestUpdatesFetcher) resource() string {
+	return RESOURCE_PULL_REQUESTS
+}
+
+func (f *pullRequestUpdatesFetcher) fetch(ctx context.Context, cfg *FetchConfig, log Logger) ([]fetcher, error) {
+	fetchReviews := []*PullRequest{}
+	fetchComments := []*PullRequest{}
+	fetchEvents := []*PullRequest{}
+	deleted := 0
+	for _, num := range f.numbers {
+		ghPull, err := cfg.Client.GetPullRequest(ctx, f.repo.GetOwner(), f.repo.GetName(), num)
+		if err != nil && !isItemGone(err) {
+			return nil, err
+		}
+		pull, loadErr := LoadPullRequest(f.rootPath, f.repo, num, false)
+		if loadErr != nil {
+			return nil, loadErr
+		}
+		if err != nil {
+			log.Info("Changed pull request no longer exists; skipping", "repo", f.repo.String(), "pullRequest", num, "err", err)
+			if pull.PullRequest != nil {
+				deleted++
+			}
+			continue
+		}
+		cfg.report.recordItem(RESOURCE_PULL_REQUESTS, pull.PullRequest != nil, pull.PullRequest, ghPull)
+		pull.PullRequest = ghPull
+		pull.LastDetailFetch = time.Now()
+		if err := pull.Save(f.rootPath, f.repo, cfg.PrettyJSON); err != nil {
+			return nil, err
+		}
+		if pull.MustFetchReviews() {
+			fetchReviews = append(fetchReviews, pull)
+		}
+		if pull.MustFetchComments() {
+			fetchComments = append(fetchComments, pull)
+		}
+		if f.timelines && pull.MustFetchEvents() {
+			fetchEvents = append(fetchEvents, pull)
+		}
+	}
+	cfg.report.recordDeleted(RESOURCE_PULL_REQUESTS, deleted)
+	log.Info("Fetched changed pull requests' details", "repo", f.repo.String(), "pullRequests", len(f.numbers))
+	fetchers := []fetcher{}
+	if len(fetchReviews) > 0 {
+		fetchers = append(fetchers, newPullRequestReviewsFetcher(f.rootPath, f.repo, fetchReviews))
+	}
+	if len(fetchComments) > 0 {
+		fetchers = append(fetchers, newPullRequestCommentsFetcher(f.rootPath, f.repo, fetchComments))
+	}
+	if len(fetchEvents) > 0 {
+		fetchers = append(fetchers, newPullRequestEventsFetcher(f.rootPath, f.repo, fetchEvents))
+	}
+	return fetchers, nil
+}
+
+// eventCursorFetcher records the ID of the latest event processed for a
+// repository. It must be the last of the repository's fetchers to run, such
+// that the cursor is only advanced once all changes have been fetched.
+type eventCursorFetcher struct {
+	rootPath string
+	repo     *Repository
+	eventID  string
+}
+
+var _ fetcher = (*eventCursorFetcher)(nil)
+
+func newEventCursorFetcher(rootPath string, repo *Repository, eventID string) *eventCursorFetcher {
+	return &eventCursorFetcher{
+		rootPath: rootPath,
+		repo:     repo,
+		eventID:  eventID,
+	}
+}
+
+func (f *eventCursorFetcher) resource() string {
+	return RESOURCE_REPOSITORY
+}
+
+func (f *eventCursorFetcher) fetch(ctx context.Context, cfg *FetchConfig, log Logger) ([]fetcher, error) {
+	f.repo.LastEventID = f.eventID
+	if err := f.repo.Save(f.rootPath, cfg.PrettyJSON); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
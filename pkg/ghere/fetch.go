@@ -1,15 +1,29 @@
 package ghere
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type fetcher interface {
 	fetch(ctx context.Context, cfg *FetchConfig, log Logger) ([]fetcher, error)
+	// resource returns the type of resource fetched by this fetcher, for
+	// reporting purposes.
+	resource() string
 }
 
 func fetchRecursively(ctx context.Context, cfg *FetchConfig, fetchers []fetcher, log Logger) error {
 	for _, fetcher := range fetchers {
+		// Only check for cancellation between fetchers so that each fetcher
+		// gets to persist the progress it has made so far.
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		start := time.Now()
 		subFetchers, err := fetcher.fetch(ctx, cfg, log)
+		cfg.report.recordDuration(fetcher.resource(), time.Since(start))
 		if err != nil {
+			cfg.report.recordError(fetcher.resource(), err)
 			return err
 		}
 		if len(subFetchers) > 0 {
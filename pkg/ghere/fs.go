@@ -1,11 +1,15 @@
 package ghere
 
 import (
+	"archive/zip"
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 func writeJSONFile(filename string, v interface{}, pretty bool) error {
@@ -23,14 +27,42 @@ func writeJSONFile(filename string, v interface{}, pretty bool) error {
 	return writeFile(filename, b)
 }
 
+// writeFile atomically writes the given data to the specified file by first
+// writing it to a temporary file in the same directory and then renaming it.
+// This ensures that an interrupted fetch never leaves partially written files
+// behind.
 func writeFile(filename string, data []byte) error {
-	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
-		return fmt.Errorf("failed to create parent directory for %s: %v", filename, err)
+	_, err := writeFileFrom(filename, bytes.NewReader(data))
+	return err
+}
+
+// writeFileFrom atomically writes everything read from the given reader to the
+// given file, returning the number of bytes written.
+func writeFileFrom(filename string, r io.Reader) (int64, error) {
+	dir := filepath.Dir(filename)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return 0, fmt.Errorf("failed to create parent directory for %s: %v", filename, err)
 	}
-	if err := os.WriteFile(filename, data, 0o644); err != nil {
-		return fmt.Errorf("failed to write to file %s: %v", filename, err)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create temporary file for %s: %v", filename, err)
 	}
-	return nil
+	tmpName := tmp.Name()
+	n, err := io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpName, 0o644)
+	}
+	if err == nil {
+		err = os.Rename(tmpName, filename)
+	}
+	if err != nil {
+		_ = os.Remove(tmpName)
+		return 0, fmt.Errorf("failed to write to file %s: %v", filename, err)
+	}
+	return n, nil
 }
 
 func readJSONFile(filename string, v interface{}) error {
@@ -82,3 +114,63 @@ func dirExists(path string) (bool, error) {
 	}
 	return true, nil
 }
+
+// extractZip extracts the given zip archive into the specified directory.
+// Entries whose paths would resolve to locations outside of the directory are
+// rejected, as are archives whose extracted contents would exceed maxSize
+// bytes in total. If extraction fails, nothing is left behind in the
+// directory.
+func extractZip(data []byte, dir string, maxSize int64) error {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return fmt.Errorf("failed to open zip archive: %v", err)
+	}
+	// Check the sizes declared by the archive up front, to avoid extracting
+	// anything from archives that are obviously too large.
+	var declared uint64
+	for _, zf := range zr.File {
+		declared += zf.UncompressedSize64
+		if declared > uint64(maxSize) {
+			return &ErrArchiveTooLarge{Limit: maxSize}
+		}
+	}
+	if err := extractZipEntries(zr, dir, maxSize); err != nil {
+		if rmErr := os.RemoveAll(dir); rmErr != nil {
+			return fmt.Errorf("failed to remove partially extracted zip archive from %s: %v (extraction failed: %v)", dir, rmErr, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// extractZipEntries extracts the entries of the given zip archive into the
+// specified directory, failing as soon as their extracted contents exceed
+// maxSize bytes in total. We do not rely on the declared sizes alone (even
+// though archive/zip rejects entries that are larger than declared), and also
+// limit the amount of data actually extracted.
+func extractZipEntries(zr *zip.Reader, dir string, maxSize int64) error {
+	remaining := maxSize
+	for _, zf := range zr.File {
+		if zf.FileInfo().IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, filepath.FromSlash(zf.Name))
+		if !strings.HasPrefix(path, filepath.Clean(dir)+string(os.PathSeparator)) {
+			return fmt.Errorf("zip archive entry has invalid path: %s", zf.Name)
+		}
+		r, err := zf.Open()
+		if err != nil {
+			return fmt.Errorf("failed to open zip archive entry %s: %v", zf.Name, err)
+		}
+		n, err := writeFileFrom(path, io.LimitReader(r, remaining+1))
+		r.Close()
+		if err != nil {
+			return fmt.Errorf("failed to extract zip archive entry %s: %v", zf.Name, err)
+		}
+		remaining -= n
+		if remaining < 0 {
+			return &ErrArchiveTooLarge{Limit: maxSize}
+		}
+	}
+	return nil
+}
@@ -3,3 +3,11 @@ package ghere
 func ReadJSONFile(filename string, v interface{}) error {
 	return readJSONFile(filename, v)
 }
+
+func WriteJSONFile(filename string, v interface{}) error {
+	return writeJSONFile(filename, v, false)
+}
+
+func ExtractZip(data []byte, dir string, maxSize int64) error {
+	return extractZip(data, dir, maxSize)
+}
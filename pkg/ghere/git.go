@@ -6,8 +6,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/go-git/go-git/v5/plumbing/transport/http"
 	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
@@ -20,14 +23,18 @@ const (
 )
 
 type GitHubCredentials struct {
-	PubKeys   *ssh.PublicKeys
+	// SSHAuth contains the SSH authentication methods to attempt, in order of
+	// preference.
+	SSHAuth   []ssh.AuthMethod
 	BasicAuth *http.BasicAuth
 }
 
 // GitHubCredentialProvider provides a way to access GitHub credentials in
 // order to fetch Git repositories from protected remote repositories.
 type GitHubCredentialProvider interface {
-	GetGitHubCredentials(ctx context.Context) (*GitHubCredentials, error)
+	// GetGitHubCredentials obtains the credentials to use when fetching
+	// repositories belonging to the given owner (user or organization).
+	GetGitHubCredentials(ctx context.Context, owner string) (*GitHubCredentials, error)
 }
 
 type envVarCredentialProvider struct {
@@ -46,80 +53,152 @@ func NewGitHubEnvVarCredentialProvider(privKeyFile, username string) GitHubCrede
 	}
 }
 
-func (cp *envVarCredentialProvider) GetGitHubCredentials(ctx context.Context) (*GitHubCredentials, error) {
+func (cp *envVarCredentialProvider) GetGitHubCredentials(ctx context.Context, owner string) (*GitHubCredentials, error) {
 	select {
 	case <-ctx.Done():
 		return nil, errors.New("credentials fetch cancelled")
 	default:
 	}
-	pubKeys, err := cp.getSSHKeys()
+	creds := &GitHubCredentials{
+		SSHAuth:   []ssh.AuthMethod{},
+		BasicAuth: cp.getBasicAuth(),
+	}
+	pubKeys, err := loadSSHKeys(cp.privKeyFile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load SSH key: %v", err)
 	}
-	basicAuth := cp.getBasicAuth()
-	return &GitHubCredentials{
-		PubKeys:   pubKeys,
-		BasicAuth: basicAuth,
-	}, nil
+	if pubKeys != nil {
+		creds.SSHAuth = append(creds.SSHAuth, pubKeys)
+	}
+	return creds, nil
 }
 
+// getBasicAuth returns nil if no password is available.
 func (cp *envVarCredentialProvider) getBasicAuth() *http.BasicAuth {
+	password := os.Getenv(GITHUB_PASSWORD_ENVVAR)
+	if len(password) == 0 {
+		return nil
+	}
 	return &http.BasicAuth{
 		Username: cp.username,
-		Password: os.Getenv(GITHUB_PASSWORD_ENVVAR),
+		Password: password,
 	}
 }
 
-func (cp *envVarCredentialProvider) getSSHKeys() (*ssh.PublicKeys, error) {
+// loadSSHKeys loads the given private key file, decrypting it with the
+// password from the SSH_PRIVKEY_PASSWORD environment variable if necessary.
+// Returns nil if the file does not exist.
+func loadSSHKeys(privKeyFile string) (*ssh.PublicKeys, error) {
 	privKeyFilePassword := os.Getenv(SSH_PRIVKEY_PASSWORD_ENVVAR)
-	exists, err := fileExists(cp.privKeyFile)
+	exists, err := fileExists(privKeyFile)
 	if err != nil || !exists {
 		return nil, nil
 	}
-	pubKeys, err := ssh.NewPublicKeysFromFile("git", cp.privKeyFile, privKeyFilePassword)
+	pubKeys, err := ssh.NewPublicKeysFromFile("git", privKeyFile, privKeyFilePassword)
 	if err != nil {
-		return nil, fmt.Errorf("unable to generate Git public keys from %s: %v", cp.privKeyFile, err)
+		return nil, fmt.Errorf("unable to generate Git public keys from %s: %v", privKeyFile, err)
 	}
 	return pubKeys, nil
 }
 
 type GitHubRepositoryUpdater interface {
-	CloneOrUpdateRepository(ctx context.Context, repoDir string, repo *github.Repository, credentialProvider GitHubCredentialProvider, log Logger) error
+	CloneOrUpdateRepository(ctx context.Context, repoDir string, repo *github.Repository, opts *GitOptions, credentialProvider GitHubCredentialProvider, log Logger) (*GitUpdateResult, error)
 }
 
-type githubRepositoryUpdater struct{}
+// GitOptions allows for customization of how a specific repository's code is
+// cloned and updated.
+type GitOptions struct {
+	// Depth, if greater than 0, limits the clone/pull to the specified number
+	// of commits from the tip of each branch.
+	Depth int `json:"depth,omitempty"`
+	// SingleBranch, if true, only clones/pulls the repository's default branch
+	// (or the first of Branches, if specified).
+	SingleBranch bool `json:"single_branch,omitempty"`
+	// Branches, if specified, limits the branches that are cloned/pulled to
+	// only those listed. The first branch will be checked out.
+	Branches []string `json:"branches,omitempty"`
+}
+
+// GitUpdateResult provides information about the outcome of a successful
+// clone/update operation on a Git repository.
+type GitUpdateResult struct {
+	// MissingSubmodules contains the submodule commits that could not be
+	// retrieved. Only populated if submodule fetching is enabled.
+	MissingSubmodules []*MissingSubmodule
+	// UpdatedRefs lists the references (branches, tags, etc.) that were
+	// created, updated or deleted by the clone/update operation.
+	UpdatedRefs []*GitRefUpdate
+}
+
+// GitRefUpdate describes a change to a single Git reference.
+type GitRefUpdate struct {
+	Name string `json:"name"`
+	// Old is the hash to which the reference previously pointed. Empty if the
+	// reference was created.
+	Old string `json:"old,omitempty"`
+	// New is the hash to which the reference now points. Empty if the
+	// reference was deleted.
+	New string `json:"new,omitempty"`
+}
+
+type githubRepositoryUpdater struct {
+	fetchSubmodules bool
+}
 
 var _ GitHubRepositoryUpdater = (*githubRepositoryUpdater)(nil)
 
-func NewGitHubRepositoryUpdater() GitHubRepositoryUpdater {
-	return &githubRepositoryUpdater{}
+// NewGitHubRepositoryUpdater creates a [GitHubRepositoryUpdater] that uses
+// go-git to clone/pull repositories. If fetchSubmodules is true, all
+// submodules (recursively) will be initialized and updated after each clone
+// or pull.
+func NewGitHubRepositoryUpdater(fetchSubmodules bool) GitHubRepositoryUpdater {
+	return &githubRepositoryUpdater{
+		fetchSubmodules: fetchSubmodules,
+	}
 }
 
 type githubAuthMethod struct {
 	repoURL string
 	auth    transport.AuthMethod
+	// Whether this authentication method requires SSH URLs (as opposed to
+	// HTTP(S) URLs).
+	ssh bool
 }
 
-func (u *githubRepositoryUpdater) CloneOrUpdateRepository(ctx context.Context, repoDir string, repo *github.Repository, credentialProvider GitHubCredentialProvider, log Logger) error {
-	creds, err := credentialProvider.GetGitHubCredentials(ctx)
+func (u *githubRepositoryUpdater) CloneOrUpdateRepository(ctx context.Context, repoDir string, repo *github.Repository, opts *GitOptions, credentialProvider GitHubCredentialProvider, log Logger) (*GitUpdateResult, error) {
+	creds, err := credentialProvider.GetGitHubCredentials(ctx, repo.GetOwner().GetLogin())
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if opts == nil {
+		opts = &GitOptions{}
 	}
 	repoID := repo.GetOwner().GetLogin() + "/" + repo.GetName()
 	authMethods := make([]*githubAuthMethod, 0)
-	if len(repo.GetSSHURL()) > 0 && creds.PubKeys != nil {
-		log.Debug("Configured SSH credentials", "repo", repoID)
-		authMethods = append(authMethods, &githubAuthMethod{
-			repoURL: repo.GetSSHURL(),
-			auth:    creds.PubKeys,
-		})
-	}
-	if len(repo.GetCloneURL()) > 0 && creds.BasicAuth != nil {
-		log.Debug("Configured HTTP credentials", "repo", repo.GetOwner().GetLogin()+"/"+repo.GetName())
-		authMethods = append(authMethods, &githubAuthMethod{
-			repoURL: repo.GetCloneURL(),
-			auth:    creds.BasicAuth,
-		})
+	if len(repo.GetSSHURL()) > 0 {
+		for _, sshAuth := range creds.SSHAuth {
+			log.Debug("Configured SSH credentials", "repo", repoID, "method", sshAuth.Name())
+			authMethods = append(authMethods, &githubAuthMethod{
+				repoURL: repo.GetSSHURL(),
+				auth:    sshAuth,
+				ssh:     true,
+			})
+		}
+	}
+	if len(repo.GetCloneURL()) > 0 {
+		if creds.BasicAuth != nil {
+			log.Debug("Configured HTTP credentials", "repo", repoID)
+			authMethods = append(authMethods, &githubAuthMethod{
+				repoURL: repo.GetCloneURL(),
+				auth:    creds.BasicAuth,
+			})
+		} else if !repo.GetPrivate() {
+			// Public repositories can be cloned anonymously.
+			log.Debug("Configured anonymous HTTP access", "repo", repoID)
+			authMethods = append(authMethods, &githubAuthMethod{
+				repoURL: repo.GetCloneURL(),
+			})
+		}
 	}
 	if len(authMethods) == 0 {
 		log.Warn("No SSH or HTTP(S) credentials specified for repository", "repo", repoID)
@@ -127,31 +206,98 @@ func (u *githubRepositoryUpdater) CloneOrUpdateRepository(ctx context.Context, r
 	gitDir := filepath.Join(repoDir, ".git")
 	exists, err := dirExists(gitDir)
 	if err != nil {
-		return fmt.Errorf("failed to access Git repository directory %s: %v", gitDir, err)
+		return nil, fmt.Errorf("failed to access Git repository directory %s: %v", gitDir, err)
+	}
+	refsBefore := map[string]string{}
+	if exists {
+		refsBefore = gitRefs(repoDir, log)
 	}
 	for _, method := range authMethods {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		if exists {
 			log.Info("Attempting to pull latest changes from repository", "repoDir", repoDir, "repoURL", method.repoURL)
-			err = updateRepository(ctx, repoDir, method.auth)
+			err = updateRepository(ctx, repoDir, method.auth, opts)
 			if err == nil {
 				log.Info("Successfully pulled latest changes from repository", "repoDir", repoDir, "repoURL", method.repoURL)
-				return nil
+				return u.afterCloneOrUpdate(ctx, repoDir, method.repoURL, authMethods, refsBefore, log)
 			}
 			log.Warn("Failed to update repository", "repoDir", repoDir, "err", err)
 		} else {
 			log.Info("Attempting to clone repository", "repoDir", repoDir, "repoURL", method.repoURL)
-			err = cloneRepository(ctx, repoDir, method.repoURL, method.auth)
+			err = cloneRepository(ctx, repoDir, method.repoURL, method.auth, opts)
 			if err == nil {
 				log.Info("Successfully cloned repository", "repoDir", repoDir, "repoURL", method.repoURL)
-				return nil
+				return u.afterCloneOrUpdate(ctx, repoDir, method.repoURL, authMethods, refsBefore, log)
 			}
 			log.Warn("Failed to clone repository", "repoDir", repoDir, "repoURL", method.repoURL, "err", err)
 		}
 	}
-	return fmt.Errorf("failed to clone/update repository %s, or no appropriate authentication method for repository", repoID)
+	return nil, fmt.Errorf("failed to clone/update repository %s, or no appropriate authentication method for repository", repoID)
 }
 
-func updateRepository(ctx context.Context, repoDir string, auth transport.AuthMethod) error {
+func (u *githubRepositoryUpdater) afterCloneOrUpdate(ctx context.Context, repoDir, repoURL string, authMethods []*githubAuthMethod, refsBefore map[string]string, log Logger) (*GitUpdateResult, error) {
+	result := &GitUpdateResult{
+		UpdatedRefs: diffGitRefs(refsBefore, gitRefs(repoDir, log)),
+	}
+	if !u.fetchSubmodules {
+		return result, nil
+	}
+	missing, err := updateSubmodules(ctx, repoDir, "", repoURL, authMethods, log)
+	if err != nil {
+		return nil, err
+	}
+	if len(missing) > 0 {
+		log.Warn("Some submodule commits could not be retrieved", "repoDir", repoDir, "count", len(missing))
+	}
+	result.MissingSubmodules = missing
+	return result, nil
+}
+
+// gitRefs returns a mapping of all of the references in the given repository
+// to the hashes to which they point. Symbolic references are ignored. Failure
+// to read the references is logged and results in an empty mapping.
+func gitRefs(repoDir string, log Logger) map[string]string {
+	refs := map[string]string{}
+	repo, err := git.PlainOpen(repoDir)
+	if err != nil {
+		log.Warn("Unable to open Git repository to read its references", "repoDir", repoDir, "err", err)
+		return refs
+	}
+	iter, err := repo.References()
+	if err != nil {
+		log.Warn("Unable to read Git repository references", "repoDir", repoDir, "err", err)
+		return refs
+	}
+	_ = iter.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() == plumbing.HashReference {
+			refs[ref.Name().String()] = ref.Hash().String()
+		}
+		return nil
+	})
+	return refs
+}
+
+func diffGitRefs(before, after map[string]string) []*GitRefUpdate {
+	updates := []*GitRefUpdate{}
+	for name, newHash := range after {
+		if oldHash := before[name]; oldHash != newHash {
+			updates = append(updates, &GitRefUpdate{Name: name, Old: oldHash, New: newHash})
+		}
+	}
+	for name, oldHash := range before {
+		if _, exists := after[name]; !exists {
+			updates = append(updates, &GitRefUpdate{Name: name, Old: oldHash})
+		}
+	}
+	sort.Slice(updates, func(i, j int) bool {
+		return updates[i].Name < updates[j].Name
+	})
+	return updates
+}
+
+func updateRepository(ctx context.Context, repoDir string, auth transport.AuthMethod, opts *GitOptions) error {
 	repo, err := git.PlainOpen(repoDir)
 	if err != nil {
 		return fmt.Errorf("unable to open Git repository %s: %v", repoDir, err)
@@ -160,29 +306,78 @@ func updateRepository(ctx context.Context, repoDir string, auth transport.AuthMe
 	if err != nil {
 		return fmt.Errorf("unable to open worktree for Git repository %s: %v", repoDir, err)
 	}
-	err = wt.PullContext(ctx, &git.PullOptions{
-		Auth:       auth,
-		RemoteName: "origin",
-		Progress:   os.Stdout,
-	})
+	pullOpts := &git.PullOptions{
+		Auth:         auth,
+		RemoteName:   "origin",
+		Progress:     os.Stdout,
+		Depth:        opts.Depth,
+		SingleBranch: opts.SingleBranch || len(opts.Branches) > 0,
+	}
+	if len(opts.Branches) > 0 {
+		pullOpts.ReferenceName = plumbing.NewBranchReferenceName(opts.Branches[0])
+	}
+	err = wt.PullContext(ctx, pullOpts)
 	if err != nil && err != git.NoErrAlreadyUpToDate {
 		return fmt.Errorf("failed to pull remote changes for %s: %v", repoDir, err)
 	}
-	return nil
+	return fetchBranches(ctx, repo, auth, opts)
 }
 
-func cloneRepository(ctx context.Context, repoDir, repoURL string, auth transport.AuthMethod) error {
+func cloneRepository(ctx context.Context, repoDir, repoURL string, auth transport.AuthMethod, opts *GitOptions) error {
 	if err := os.MkdirAll(repoDir, 0o755); err != nil {
 		return fmt.Errorf("failed to create repository directory %s: %v", repoDir, err)
 	}
-	_, err := git.PlainCloneContext(ctx, repoDir, false, &git.CloneOptions{
+	cloneOpts := &git.CloneOptions{
+		Auth:         auth,
+		URL:          repoURL,
+		Progress:     os.Stdout,
+		RemoteName:   "origin",
+		Depth:        opts.Depth,
+		SingleBranch: opts.SingleBranch || len(opts.Branches) > 0,
+	}
+	if len(opts.Branches) > 0 {
+		cloneOpts.ReferenceName = plumbing.NewBranchReferenceName(opts.Branches[0])
+	}
+	repo, err := git.PlainCloneContext(ctx, repoDir, false, cloneOpts)
+	if err != nil {
+		return fmt.Errorf("failed to clone repository %s into %s: %v", repoURL, repoDir, err)
+	}
+	return fetchBranches(ctx, repo, auth, opts)
+}
+
+// fetchBranches ensures that all of the branches explicitly configured in the
+// given options are tracked and fetched from the remote repository. Does
+// nothing if fewer than two branches are configured, since the first branch
+// is always fetched by way of a clone/pull.
+func fetchBranches(ctx context.Context, repo *git.Repository, auth transport.AuthMethod, opts *GitOptions) error {
+	if len(opts.Branches) < 2 {
+		return nil
+	}
+	refSpecs := make([]config.RefSpec, 0, len(opts.Branches))
+	for _, branch := range opts.Branches {
+		refSpecs = append(refSpecs, config.RefSpec(fmt.Sprintf("+refs/heads/%s:refs/remotes/origin/%s", branch, branch)))
+	}
+	cfg, err := repo.Config()
+	if err != nil {
+		return fmt.Errorf("failed to load Git repository configuration: %v", err)
+	}
+	remote, exists := cfg.Remotes["origin"]
+	if !exists {
+		return fmt.Errorf("Git repository has no \"origin\" remote")
+	}
+	remote.Fetch = refSpecs
+	if err := repo.Storer.SetConfig(cfg); err != nil {
+		return fmt.Errorf("failed to update Git repository configuration: %v", err)
+	}
+	err = repo.FetchContext(ctx, &git.FetchOptions{
+		RemoteName: "origin",
+		RefSpecs:   refSpecs,
+		Depth:      opts.Depth,
 		Auth:       auth,
-		URL:        repoURL,
 		Progress:   os.Stdout,
-		RemoteName: "origin",
 	})
-	if err != nil {
-		return fmt.Errorf("failed to clone repository %s into %s: %v", repoURL, repoDir, err)
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return fmt.Errorf("failed to fetch branches %v: %v", opts.Branches, err)
 	}
 	return nil
 }
@@ -2,9 +2,18 @@ package ghere_test
 
 import (
 	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
 
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/google/go-github/v48/github"
 	"github.com/informalsystems/ghere/pkg/ghere"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // MockGitHubCredentialProvider does nothing.
@@ -13,7 +22,7 @@ type MockGitHubCredentialProvider struct{}
 var _ ghere.GitHubCredentialProvider = (*MockGitHubCredentialProvider)(nil)
 
 // GetGitHubCredentials implements ghere.GitHubCredentialProvider
-func (*MockGitHubCredentialProvider) GetGitHubCredentials(ctx context.Context) (*ghere.GitHubCredentials, error) {
+func (*MockGitHubCredentialProvider) GetGitHubCredentials(ctx context.Context, owner string) (*ghere.GitHubCredentials, error) {
 	return nil, nil
 }
 
@@ -23,6 +32,205 @@ type MockGitHubRepositoryUpdater struct{}
 var _ ghere.GitHubRepositoryUpdater = (*MockGitHubRepositoryUpdater)(nil)
 
 // CloneOrUpdateRepository implements ghere.GitHubRepositoryUpdater
-func (*MockGitHubRepositoryUpdater) CloneOrUpdateRepository(ctx context.Context, repoDir string, repo *github.Repository, credentialProvider ghere.GitHubCredentialProvider, log ghere.Logger) error {
-	return nil
+func (*MockGitHubRepositoryUpdater) CloneOrUpdateRepository(ctx context.Context, repoDir string, repo *github.Repository, opts *ghere.GitOptions, credentialProvider ghere.GitHubCredentialProvider, log ghere.Logger) (*ghere.GitUpdateResult, error) {
+	return &ghere.GitUpdateResult{}, nil
+}
+
+func TestParseGitRemoteURL(t *testing.T) {
+	testCases := []struct {
+		url  string
+		ssh  bool
+		host string
+		path string
+		err  bool
+	}{
+		{url: "https://github.com/org/repo.git", host: "github.com", path: "org/repo.git"},
+		{url: "http://user@ghe.example.com:8080/org/repo", host: "ghe.example.com", path: "org/repo"},
+		{url: "git://github.com/org/repo", host: "github.com", path: "org/repo"},
+		{url: "ssh://git@github.com/org/repo.git", ssh: true, host: "github.com", path: "org/repo.git"},
+		{url: "git@github.com:org/repo.git", ssh: true, host: "github.com", path: "org/repo.git"},
+		{url: "git@github.com:/org/repo.git", ssh: true, host: "github.com", path: "org/repo.git"},
+		{url: "github.com:org/repo", ssh: true, host: "github.com", path: "org/repo"},
+		{url: "ftp://github.com/org/repo", err: true},
+		{url: "org/repo", err: true},
+		{url: "git@:org/repo", err: true},
+	}
+	for _, tc := range testCases {
+		ssh, host, path, err := ghere.ParseGitRemoteURL(tc.url)
+		if tc.err {
+			assert.Error(t, err, tc.url)
+			continue
+		}
+		require.NoError(t, err, tc.url)
+		assert.Equal(t, tc.ssh, ssh, tc.url)
+		assert.Equal(t, tc.host, host, tc.url)
+		assert.Equal(t, tc.path, path, tc.url)
+	}
+}
+
+func TestResolveSubmoduleURL(t *testing.T) {
+	defaultParentURL := "https://github.com/org/repo.git"
+	testCases := []struct {
+		parentURL string
+		subURL    string
+		ssh       bool
+		expected  string
+		authed    bool
+		err       bool
+	}{
+		{subURL: "../other.git", expected: "https://github.com/org/other.git", authed: true},
+		{subURL: "../other.git", ssh: true, expected: "ssh://git@github.com/org/other.git", authed: true},
+		{subURL: "./sub.git", expected: "https://github.com/org/repo.git/sub.git", authed: true},
+		{subURL: "git@github.com:org/lib.git", expected: "https://github.com/org/lib.git", authed: true},
+		{subURL: "https://github.com/org/lib", ssh: true, expected: "ssh://git@github.com/org/lib", authed: true},
+		{parentURL: "git@github.com:org/repo.git", subURL: "../other.git", expected: "https://github.com/org/other.git", authed: true},
+		// Submodules on other hosts are fetched anonymously from their
+		// original URLs.
+		{subURL: "https://gitlab.com/org/lib.git", expected: "https://gitlab.com/org/lib.git"},
+		{subURL: "git@gitlab.com:org/lib.git", ssh: true, expected: "git@gitlab.com:org/lib.git"},
+		{parentURL: "org/repo", subURL: "../other.git", err: true},
+		{subURL: "ftp://github.com/org/lib", err: true},
+	}
+	for _, tc := range testCases {
+		parent := tc.parentURL
+		if len(parent) == 0 {
+			parent = defaultParentURL
+		}
+		resolved, authed, err := ghere.ResolveSubmoduleURL(parent, tc.subURL, tc.ssh)
+		if tc.err {
+			assert.Error(t, err, tc.subURL)
+			continue
+		}
+		require.NoError(t, err, tc.subURL)
+		assert.Equal(t, tc.expected, resolved, tc.subURL)
+		assert.Equal(t, tc.authed, authed, tc.subURL)
+	}
+}
+
+func TestIsSubmoduleOnOtherHost(t *testing.T) {
+	parentURL := "https://github.com/org/repo.git"
+	testCases := []struct {
+		subURL    string
+		otherHost bool
+	}{
+		{subURL: "../other.git"},
+		{subURL: "git@github.com:org/lib.git"},
+		{subURL: "https://GitHub.com/org/lib"},
+		{subURL: "https://gitlab.com/org/lib.git", otherHost: true},
+		{subURL: "git@gitlab.com:org/lib.git", otherHost: true},
+	}
+	for _, tc := range testCases {
+		otherHost, err := ghere.IsOnOtherHost(parentURL, tc.subURL)
+		require.NoError(t, err, tc.subURL)
+		assert.Equal(t, tc.otherHost, otherHost, tc.subURL)
+	}
+	_, err := ghere.IsOnOtherHost(parentURL, "ftp://github.com/org/lib")
+	assert.Error(t, err)
+}
+
+// staticCredentialProvider provides no credentials, allowing for anonymous
+// access.
+type staticCredentialProvider struct{}
+
+func (*staticCredentialProvider) GetGitHubCredentials(ctx context.Context, owner string) (*ghere.GitHubCredentials, error) {
+	return &ghere.GitHubCredentials{}, nil
+}
+
+func TestGitHubRepositoryUpdaterOptions(t *testing.T) {
+	log := ghere.NewNoopLogger()
+	tmpDir := t.TempDir()
+
+	// Set up a "remote" repository with a few branches.
+	remotePath := filepath.Join(tmpDir, "remote")
+	remote, err := git.PlainInit(remotePath, false)
+	require.NoError(t, err)
+	worktree, err := remote.Worktree()
+	require.NoError(t, err)
+	commit := func(content string) plumbing.Hash {
+		require.NoError(t, os.WriteFile(filepath.Join(remotePath, "README.md"), []byte(content), 0o644))
+		_, err := worktree.Add("README.md")
+		require.NoError(t, err)
+		hash, err := worktree.Commit(content, &git.CommitOptions{
+			Author: &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()},
+		})
+		require.NoError(t, err)
+		return hash
+	}
+	checkout := func(branch string, create bool) {
+		require.NoError(t, worktree.Checkout(&git.CheckoutOptions{
+			Branch: plumbing.NewBranchReferenceName(branch),
+			Create: create,
+		}))
+	}
+	commit("first")
+	commit("second")
+	checkout("dev", true)
+	devHead := commit("dev")
+	checkout("other", true)
+	otherHead := commit("other")
+	checkout("master", false)
+	commit("third")
+
+	owner := "org"
+	name := "repo"
+	repo := &github.Repository{
+		Owner:    &github.User{Login: &owner},
+		Name:     &name,
+		CloneURL: github.String("file://" + remotePath),
+	}
+	updater := ghere.NewGitHubRepositoryUpdater(false)
+	refs := func(repoDir string) map[string]string {
+		r, err := git.PlainOpen(repoDir)
+		require.NoError(t, err)
+		iter, err := r.References()
+		require.NoError(t, err)
+		refs := map[string]string{}
+		require.NoError(t, iter.ForEach(func(ref *plumbing.Reference) error {
+			if ref.Type() == plumbing.HashReference {
+				refs[ref.Name().String()] = ref.Hash().String()
+			}
+			return nil
+		}))
+		return refs
+	}
+
+	// Shallow clones must only contain the requested number of commits.
+	shallowPath := filepath.Join(tmpDir, "shallow")
+	_, err = updater.CloneOrUpdateRepository(context.Background(), shallowPath, repo, &ghere.GitOptions{Depth: 1, SingleBranch: true}, &staticCredentialProvider{}, log)
+	require.NoError(t, err)
+	shallow, err := git.PlainOpen(shallowPath)
+	require.NoError(t, err)
+	shallowCommits, err := shallow.Storer.Shallow()
+	require.NoError(t, err)
+	assert.Len(t, shallowCommits, 1)
+	head, err := shallow.Head()
+	require.NoError(t, err)
+	assert.Equal(t, shallowCommits[0], head.Hash())
+	shallowRefs := refs(shallowPath)
+	assert.Equal(t, head.Hash().String(), shallowRefs["refs/remotes/origin/master"])
+	assert.NotContains(t, shallowRefs, "refs/remotes/origin/dev")
+	assert.NotContains(t, shallowRefs, "refs/remotes/origin/other")
+
+	// Only the configured branches must be fetched, with the first of them
+	// checked out.
+	branchesPath := filepath.Join(tmpDir, "branches")
+	opts := &ghere.GitOptions{Branches: []string{"dev", "other"}}
+	_, err = updater.CloneOrUpdateRepository(context.Background(), branchesPath, repo, opts, &staticCredentialProvider{}, log)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"refs/heads/dev":            devHead.String(),
+		"refs/remotes/origin/dev":   devHead.String(),
+		"refs/remotes/origin/other": otherHead.String(),
+	}, refs(branchesPath))
+
+	// Updates to all of the configured branches must be fetched.
+	checkout("other", false)
+	newOtherHead := commit("other again")
+	result, err := updater.CloneOrUpdateRepository(context.Background(), branchesPath, repo, opts, &staticCredentialProvider{}, log)
+	require.NoError(t, err)
+	assert.Equal(t, []*ghere.GitRefUpdate{{
+		Name: "refs/remotes/origin/other",
+		Old:  otherHead.String(),
+		New:  newOtherHead.String(),
+	}}, result.UpdatedRefs)
 }
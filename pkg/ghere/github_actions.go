@@ -0,0 +1,173 @@
+package ghere
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/google/go-github/v48/github"
+)
+
+func (c *githubClient) ListWorkflows(ctx context.Context, owner, name string, page int) ([]*github.Workflow, bool, error) {
+	var workflows *github.Workflows
+	c.log.Info("List workflows", "repo", owner+"/"+name, "page", page)
+	err := c.callRateLimited(ctx, "ListWorkflows", func(cx context.Context, client *github.Client) (res *github.Response, err error) {
+		workflows, res, err = client.Actions.ListWorkflows(cx, owner, name, &github.ListOptions{
+			Page:    page,
+			PerPage: DEFAULT_PER_PAGE,
+		})
+		return
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return workflows.Workflows, len(workflows.Workflows) < DEFAULT_PER_PAGE, nil
+}
+
+func (c *githubClient) ListWorkflowRuns(ctx context.Context, owner, name string, page int) ([]*github.WorkflowRun, bool, error) {
+	var runs *github.WorkflowRuns
+	c.log.Info("List workflow runs", "repo", owner+"/"+name, "page", page)
+	err := c.callRateLimited(ctx, "ListWorkflowRuns", func(cx context.Context, client *github.Client) (res *github.Response, err error) {
+		runs, res, err = client.Actions.ListRepositoryWorkflowRuns(cx, owner, name, &github.ListWorkflowRunsOptions{
+			ListOptions: github.ListOptions{
+				Page:    page,
+				PerPage: DEFAULT_PER_PAGE,
+			},
+		})
+		return
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return runs.WorkflowRuns, len(runs.WorkflowRuns) < DEFAULT_PER_PAGE, nil
+}
+
+func (c *githubClient) GetWorkflowRun(ctx context.Context, owner, name string, runID int64) (*github.WorkflowRun, error) {
+	var run *github.WorkflowRun
+	c.log.Info("Get workflow run", "repo", owner+"/"+name, "run", runID)
+	err := c.callRateLimited(ctx, "GetWorkflowRun", func(cx context.Context, client *github.Client) (res *github.Response, err error) {
+		run, res, err = client.Actions.GetWorkflowRunByID(cx, owner, name, runID)
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+func (c *githubClient) ListWorkflowJobs(ctx context.Context, owner, name string, runID int64, page int) ([]*github.WorkflowJob, bool, error) {
+	var jobs *github.Jobs
+	c.log.Info("List workflow jobs", "repo", owner+"/"+name, "run", runID, "page", page)
+	err := c.callRateLimited(ctx, "ListWorkflowJobs", func(cx context.Context, client *github.Client) (res *github.Response, err error) {
+		jobs, res, err = client.Actions.ListWorkflowJobs(cx, owner, name, runID, &github.ListWorkflowJobsOptions{
+			Filter: "all",
+			ListOptions: github.ListOptions{
+				Page:    page,
+				PerPage: DEFAULT_PER_PAGE,
+			},
+		})
+		return
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return jobs.Jobs, len(jobs.Jobs) < DEFAULT_PER_PAGE, nil
+}
+
+func (c *githubClient) ListWorkflowRunArtifacts(ctx context.Context, owner, name string, runID int64, page int) ([]*github.Artifact, bool, error) {
+	var artifacts *github.ArtifactList
+	c.log.Info("List workflow run artifacts", "repo", owner+"/"+name, "run", runID, "page", page)
+	err := c.callRateLimited(ctx, "ListWorkflowRunArtifacts", func(cx context.Context, client *github.Client) (res *github.Response, err error) {
+		artifacts, res, err = client.Actions.ListWorkflowRunArtifacts(cx, owner, name, runID, &github.ListOptions{
+			Page:    page,
+			PerPage: DEFAULT_PER_PAGE,
+		})
+		return
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return artifacts.Artifacts, len(artifacts.Artifacts) < DEFAULT_PER_PAGE, nil
+}
+
+func (c *githubClient) DownloadWorkflowRunLogs(ctx context.Context, owner, name string, runID int64, maxSize int64) ([]byte, error) {
+	var downloadURL *url.URL
+	var unavailable *ErrDownloadUnavailable
+	c.log.Info("Download workflow run logs", "repo", owner+"/"+name, "run", runID)
+	err := c.callRateLimited(ctx, "DownloadWorkflowRunLogs", func(cx context.Context, client *github.Client) (res *github.Response, err error) {
+		downloadURL, res, err = client.Actions.GetWorkflowRunLogs(cx, owner, name, runID, true)
+		if err != nil && res != nil && isDownloadUnavailable(res.StatusCode) {
+			unavailable = &ErrDownloadUnavailable{Status: res.Status}
+			err = nil
+		}
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	if unavailable != nil {
+		return nil, unavailable
+	}
+	return download(ctx, downloadURL, maxSize)
+}
+
+func (c *githubClient) DownloadArtifact(ctx context.Context, owner, name string, artifactID int64, maxSize int64) ([]byte, error) {
+	var downloadURL *url.URL
+	var unavailable *ErrDownloadUnavailable
+	c.log.Info("Download workflow run artifact", "repo", owner+"/"+name, "artifact", artifactID)
+	err := c.callRateLimited(ctx, "DownloadArtifact", func(cx context.Context, client *github.Client) (res *github.Response, err error) {
+		downloadURL, res, err = client.Actions.DownloadArtifact(cx, owner, name, artifactID, true)
+		if err != nil && res != nil && isDownloadUnavailable(res.StatusCode) {
+			unavailable = &ErrDownloadUnavailable{Status: res.Status}
+			err = nil
+		}
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	if unavailable != nil {
+		return nil, unavailable
+	}
+	return download(ctx, downloadURL, maxSize)
+}
+
+// isDownloadUnavailable returns whether the given HTTP status code indicates
+// that a download has expired (or has been deleted).
+func isDownloadUnavailable(statusCode int) bool {
+	return statusCode == http.StatusNotFound || statusCode == http.StatusGone
+}
+
+// download fetches the contents of the given (pre-signed) download URL,
+// failing if they are larger than maxSize bytes. Download URLs do not require
+// authentication, and downloads are not subject to the per-request timeout of
+// API calls.
+func download(ctx context.Context, downloadURL *url.URL, maxSize int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadURL.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to construct download request: %v", err)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %v", downloadURL.Redacted(), err)
+	}
+	defer res.Body.Close()
+	switch {
+	case isDownloadUnavailable(res.StatusCode):
+		return nil, &ErrDownloadUnavailable{Status: res.Status}
+	case res.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("failed to download %s: %s", downloadURL.Redacted(), res.Status)
+	case res.ContentLength > maxSize:
+		return nil, &ErrDownloadTooLarge{Limit: maxSize}
+	}
+	data, err := io.ReadAll(io.LimitReader(res.Body, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %v", downloadURL.Redacted(), err)
+	}
+	if int64(len(data)) > maxSize {
+		return nil, &ErrDownloadTooLarge{Limit: maxSize}
+	}
+	return data, nil
+}
@@ -2,10 +2,14 @@ package ghere
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"sync"
 	"time"
 
 	"github.com/google/go-github/v48/github"
+	"golang.org/x/oauth2"
 )
 
 // GitHubClient provides an interface through which we can access GitHub data.
@@ -22,34 +26,271 @@ type GitHubClient interface {
 	ListPullRequestComments(ctx context.Context, owner, name string, prNum int, page int) ([]*github.PullRequestComment, bool, error)
 	ListRepositoryIssues(ctx context.Context, owner, name string, page int) ([]*github.Issue, bool, error)
 	ListIssueComments(ctx context.Context, owner, name string, issueNum int, page int) ([]*github.IssueComment, bool, error)
+	// ListIssueTimeline lists the events in the timeline of the issue or pull
+	// request with the given number, oldest first.
+	ListIssueTimeline(ctx context.Context, owner, name string, issueNum int, page int) ([]*github.Timeline, bool, error)
+	GetIssue(ctx context.Context, owner, name string, issueNum int) (*github.Issue, error)
+	ListIssueReactions(ctx context.Context, owner, name string, issueNum int, page int) ([]*github.Reaction, bool, error)
+	ListIssueCommentReactions(ctx context.Context, owner, name string, commentID int64, page int) ([]*github.Reaction, bool, error)
+	ListPullRequestCommentReactions(ctx context.Context, owner, name string, commentID int64, page int) ([]*github.Reaction, bool, error)
+	ListReleaseReactions(ctx context.Context, owner, name string, releaseID int64, page int) ([]*github.Reaction, bool, error)
+	// ListReleases lists a repository's releases, along with the summaries of
+	// their reactions, most recent first.
+	ListReleases(ctx context.Context, owner, name string, page int) ([]*GitHubRelease, bool, error)
+	// ListRepositoryIssueComments lists the comments on all of a repository's
+	// issues and pull requests, oldest first.
+	ListRepositoryIssueComments(ctx context.Context, owner, name string, page int) ([]*github.IssueComment, bool, error)
+	// ListRepositoryPullRequestComments lists the review comments on all of a
+	// repository's pull requests, oldest first.
+	ListRepositoryPullRequestComments(ctx context.Context, owner, name string, page int) ([]*github.PullRequestComment, bool, error)
+	GetPullRequest(ctx context.Context, owner, name string, prNum int) (*github.PullRequest, error)
+	// ListDiscussionCategories lists all of a repository's discussion
+	// categories. Repositories without discussions enabled have none.
+	ListDiscussionCategories(ctx context.Context, owner, name string) ([]*GitHubDiscussionCategory, error)
+	// ListDiscussions lists a page of a repository's discussions, most
+	// recently updated first, starting from the given cursor (empty for the
+	// first page). The cursor for the next page is returned, which is empty if
+	// there are no more pages.
+	ListDiscussions(ctx context.Context, owner, name string, cursor string) ([]*GitHubDiscussion, string, error)
+	// ListDiscussionComments lists a page of a discussion's comments, oldest
+	// first, along with all of their replies, starting from the given cursor
+	// (empty for the first page). The cursor for the next page is returned,
+	// which is empty if there are no more pages.
+	ListDiscussionComments(ctx context.Context, owner, name string, discussionNum int, cursor string) ([]*GitHubDiscussionComment, string, error)
+	// GetProject fetches a (v2) project along with its field definitions. If
+	// name is empty, the project is looked up at the organization level,
+	// otherwise via the repository with the given name.
+	GetProject(ctx context.Context, owner, name string, number int) (*GitHubProjectV2, []*GitHubProjectV2Field, error)
+	// ListProjectItems lists a page of the items of the project with the given
+	// (GraphQL node) ID, along with their field values, starting from the
+	// given cursor (empty for the first page). The cursor for the next page is
+	// returned, which is empty if there are no more pages.
+	ListProjectItems(ctx context.Context, projectID string, cursor string) ([]*GitHubProjectV2Item, string, error)
+	ListWorkflows(ctx context.Context, owner, name string, page int) ([]*github.Workflow, bool, error)
+	// ListWorkflowRuns lists a repository's workflow runs, most recent first.
+	ListWorkflowRuns(ctx context.Context, owner, name string, page int) ([]*github.WorkflowRun, bool, error)
+	GetWorkflowRun(ctx context.Context, owner, name string, runID int64) (*github.WorkflowRun, error)
+	// ListWorkflowJobs lists the jobs (including their steps) of all attempts
+	// of a workflow run.
+	ListWorkflowJobs(ctx context.Context, owner, name string, runID int64, page int) ([]*github.WorkflowJob, bool, error)
+	ListWorkflowRunArtifacts(ctx context.Context, owner, name string, runID int64, page int) ([]*github.Artifact, bool, error)
+	// DownloadWorkflowRunLogs downloads the (zip) archive of a workflow run's
+	// logs, failing with [ErrDownloadTooLarge] if it is larger than maxSize
+	// bytes, or with [ErrDownloadUnavailable] if it is no longer available.
+	DownloadWorkflowRunLogs(ctx context.Context, owner, name string, runID int64, maxSize int64) ([]byte, error)
+	// DownloadArtifact downloads the (zip) archive of a workflow run's
+	// artifact, failing like DownloadWorkflowRunLogs.
+	DownloadArtifact(ctx context.Context, owner, name string, artifactID int64, maxSize int64) ([]byte, error)
+	// ListCommits lists the commits reachable from the given branch whose
+	// commit dates are no earlier than since, most recent first.
+	ListCommits(ctx context.Context, owner, name, branch string, since time.Time, page int) ([]*github.RepositoryCommit, bool, error)
+	// GetCombinedStatus gets the combined status of the given commit, along
+	// with a page of its individual statuses.
+	GetCombinedStatus(ctx context.Context, owner, name, sha string, page int) (*github.CombinedStatus, bool, error)
+	ListCheckSuites(ctx context.Context, owner, name, sha string, page int) ([]*github.CheckSuite, bool, error)
+	// ListCheckRuns lists all check runs (including those superseded by
+	// re-runs) for the given commit.
+	ListCheckRuns(ctx context.Context, owner, name, sha string, page int) ([]*github.CheckRun, bool, error)
+	ListOrganizationTeams(ctx context.Context, org string, page int) ([]*github.Team, bool, error)
+	// ListTeamMembers lists the members of the team with the given slug
+	// having the given role ("member" or "maintainer").
+	ListTeamMembers(ctx context.Context, org, slug, role string, page int) ([]*github.User, bool, error)
+	// ListTeamRepositories lists the repositories to which the team with the
+	// given slug has access, along with the team's permissions on each.
+	ListTeamRepositories(ctx context.Context, org, slug string, page int) ([]*github.Repository, bool, error)
+	// ListRepositoryEvents lists a repository's recent events, most recent
+	// first.
+	ListRepositoryEvents(ctx context.Context, owner, name string, page int) ([]*github.Event, bool, error)
+}
+
+// GitHubClientStats provides statistics about the requests made using a
+// [GitHubClient].
+type GitHubClientStats struct {
+	// Requests is the total number of requests made, including retries.
+	Requests int `json:"requests"`
+	// Retries is the number of requests that were retries of failed requests.
+	Retries int `json:"retries"`
+	// RequestsByEndpoint and RetriesByEndpoint break down Requests and Retries
+	// by endpoint (named after the corresponding [GitHubClient] method).
+	RequestsByEndpoint map[string]int `json:"requests_by_endpoint,omitempty"`
+	RetriesByEndpoint  map[string]int `json:"retries_by_endpoint,omitempty"`
+	// RateLimit is the total primary (REST API) rate limit across all tokens,
+	// as of the latest response received using each token.
+	RateLimit int `json:"rate_limit"`
+	// RateLimitRemaining is the total remaining primary (REST API) rate limit
+	// quota across all tokens, as of the latest response received using each
+	// token.
+	RateLimitRemaining int `json:"rate_limit_remaining"`
+}
+
+// GitHubClientStatsProvider is optionally implemented by [GitHubClient]
+// implementations that keep track of statistics about the requests they make.
+type GitHubClientStatsProvider interface {
+	Stats() GitHubClientStats
+}
+
+// requestCounter counts the requests made using a [GitHubClient] on behalf of
+// a single fetch. It is carried by the context of each request, so that
+// requests are attributed to the right fetch even when several fetches share
+// a client concurrently.
+type requestCounter struct {
+	mtx      sync.Mutex
+	requests int
+	retries  int
+}
+
+type requestCounterKey struct{}
+
+// withRequestCounter returns a context that counts the requests made using it
+// with the returned counter.
+func withRequestCounter(ctx context.Context) (context.Context, *requestCounter) {
+	counter := &requestCounter{}
+	return context.WithValue(ctx, requestCounterKey{}, counter), counter
+}
+
+// countRequest records a request made using the given context with its
+// request counter, if any.
+func countRequest(ctx context.Context, retry bool) {
+	counter, ok := ctx.Value(requestCounterKey{}).(*requestCounter)
+	if !ok {
+		return
+	}
+	counter.mtx.Lock()
+	defer counter.mtx.Unlock()
+	counter.requests++
+	if retry {
+		counter.retries++
+	}
+}
+
+func (c *requestCounter) counts() (requests, retries int) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.requests, c.retries
 }
 
 type githubClient struct {
-	client  *github.Client
-	retries int
-	timeout time.Duration
+	mtx     sync.Mutex
+	clients []*pooledClient
+	policy  *RetryPolicy
 	log     Logger
+	// Request and retry counts, keyed by endpoint.
+	requests map[string]int
+	retries  map[string]int
+}
+
+// pooledClient tracks the rate limit status of a single client in a pool of
+// clients, each of which is authenticated using a different token.
+type pooledClient struct {
+	client *github.Client
+	// The 1-based index of this client in the pool, for logging purposes.
+	id int
+	// Rate limit information from the last response received using this
+	// client, keyed by the rate limited resource (e.g. "core" for the REST
+	// API, and "graphql" for the GraphQL API), each of which has its own
+	// quota. Resources for which no requests have been made yet using this
+	// client are missing.
+	rates    map[string]*github.Rate
+	requests int
+}
+
+// The resources for which GitHub enforces separate primary rate limits, as
+// indicated by the X-RateLimit-Resource header of its responses.
+const (
+	rateLimitResourceCore    string = "core"
+	rateLimitResourceGraphQL string = "graphql"
+)
+
+// rateLimitResource determines which rate limited resource the given response
+// pertains to, defaulting to the one for the REST API.
+func rateLimitResource(res *http.Response) string {
+	if res != nil {
+		if resource := res.Header.Get("X-RateLimit-Resource"); len(resource) > 0 {
+			return resource
+		}
+	}
+	return rateLimitResourceCore
 }
 
-var _ GitHubClient = (*githubClient)(nil)
+var (
+	_ GitHubClient              = (*githubClient)(nil)
+	_ GitHubClientStatsProvider = (*githubClient)(nil)
+)
 
 // NewGitHubClient constructs a [GitHubClient] implementation that automatically
 // handles rate limiting (by waiting until the rate limit reset time when the
-// rate limit is hit) as well as request timeouts and retries.
-func NewGitHubClient(client *github.Client, retries int, timeout time.Duration, log Logger) GitHubClient {
+// rate limit is hit) as well as request timeouts and retries as per the given
+// retry policy. If the policy is nil, [DefaultRetryPolicy] is used.
+func NewGitHubClient(client *github.Client, policy *RetryPolicy, log Logger) GitHubClient {
+	return NewGitHubClientPool([]*github.Client{client}, policy, log)
+}
+
+// NewGitHubClientPool constructs a [GitHubClient] implementation like
+// [NewGitHubClient], but which rotates between the given clients (each of
+// which should be authenticated using a different token). Each request is
+// made using the client with the most remaining rate limit quota, and
+// rate limiting only results in waiting when all clients' quotas have been
+// exhausted.
+func NewGitHubClientPool(clients []*github.Client, policy *RetryPolicy, log Logger) GitHubClient {
+	if policy == nil {
+		policy = DefaultRetryPolicy()
+	}
+	pool := make([]*pooledClient, 0, len(clients))
+	for i, client := range clients {
+		pool = append(pool, &pooledClient{
+			client: client,
+			id:     i + 1,
+			rates:  make(map[string]*github.Rate),
+		})
+	}
 	return &githubClient{
-		client:  client,
-		retries: retries,
-		timeout: timeout,
-		log:     log,
+		clients:  pool,
+		policy:   policy,
+		log:      log,
+		requests: make(map[string]int),
+		retries:  make(map[string]int),
+	}
+}
+
+// NewGitHubClientPoolTokenSource constructs an [oauth2.TokenSource] that
+// follows the rotation of the given client pool, providing the token of the
+// client that the pool would currently use for API requests. This allows Git
+// operations over HTTPS to rotate between the same tokens as API requests.
+// The given token sources must correspond, in order, to the clients from which
+// the pool was constructed. If the given client is not a pool constructed via
+// [NewGitHubClientPool], the first token source is always used.
+func NewGitHubClientPoolTokenSource(pool GitHubClient, tokenSources []oauth2.TokenSource) oauth2.TokenSource {
+	c, ok := pool.(*githubClient)
+	if !ok || len(tokenSources) != len(c.clients) {
+		return tokenSources[0]
+	}
+	return &poolTokenSource{
+		pool:         c,
+		tokenSources: tokenSources,
+	}
+}
+
+type poolTokenSource struct {
+	pool         *githubClient
+	tokenSources []oauth2.TokenSource
+}
+
+// Token implements oauth2.TokenSource.
+func (ts *poolTokenSource) Token() (*oauth2.Token, error) {
+	// If all clients' quotas have been exhausted, Git operations need not
+	// wait, since they are not subject to the API rate limits.
+	pc, _ := ts.pool.nextClient(rateLimitResourceCore)
+	if pc == nil {
+		return ts.tokenSources[0].Token()
 	}
+	return ts.tokenSources[pc.id-1].Token()
 }
 
 func (c *githubClient) GetRepository(ctx context.Context, owner, name string) (*github.Repository, error) {
 	var repo *github.Repository
 	c.log.Info("Get repository", "repo", owner+"/"+name)
-	err := c.callRateLimited(ctx, func(cx context.Context) (res *github.Response, err error) {
-		repo, res, err = c.client.Repositories.Get(cx, owner, name)
+	err := c.callRateLimited(ctx, "GetRepository", func(cx context.Context, client *github.Client) (res *github.Response, err error) {
+		repo, res, err = client.Repositories.Get(cx, owner, name)
 		return
 	})
 	if err != nil {
@@ -61,8 +302,8 @@ func (c *githubClient) GetRepository(ctx context.Context, owner, name string) (*
 func (c *githubClient) ListRepositoryLabels(ctx context.Context, owner, name string, page int) ([]*github.Label, bool, error) {
 	var labels []*github.Label
 	c.log.Info("List repository labels", "repo", owner+"/"+name, "page", page)
-	err := c.callRateLimited(ctx, func(cx context.Context) (res *github.Response, err error) {
-		labels, res, err = c.client.Issues.ListLabels(cx, owner, name, &github.ListOptions{
+	err := c.callRateLimited(ctx, "ListRepositoryLabels", func(cx context.Context, client *github.Client) (res *github.Response, err error) {
+		labels, res, err = client.Issues.ListLabels(cx, owner, name, &github.ListOptions{
 			Page:    page,
 			PerPage: DEFAULT_PER_PAGE,
 		})
@@ -77,8 +318,8 @@ func (c *githubClient) ListRepositoryLabels(ctx context.Context, owner, name str
 func (c *githubClient) ListRepositoryPullRequests(ctx context.Context, owner, name string, page int) ([]*github.PullRequest, bool, error) {
 	var prs []*github.PullRequest
 	c.log.Info("List repository pull requests", "repo", owner+"/"+name, "page", page)
-	err := c.callRateLimited(ctx, func(cx context.Context) (res *github.Response, err error) {
-		prs, res, err = c.client.PullRequests.List(cx, owner, name, &github.PullRequestListOptions{
+	err := c.callRateLimited(ctx, "ListRepositoryPullRequests", func(cx context.Context, client *github.Client) (res *github.Response, err error) {
+		prs, res, err = client.PullRequests.List(cx, owner, name, &github.PullRequestListOptions{
 			State:     "all",
 			Sort:      "created",
 			Direction: "asc",
@@ -98,8 +339,8 @@ func (c *githubClient) ListRepositoryPullRequests(ctx context.Context, owner, na
 func (c *githubClient) ListPullRequestReviews(ctx context.Context, owner, name string, prNum int, page int) ([]*github.PullRequestReview, bool, error) {
 	var reviews []*github.PullRequestReview
 	c.log.Info("List repository pull request reviews", "repo", owner+"/"+name, "pr", prNum, "page", page)
-	err := c.callRateLimited(ctx, func(cx context.Context) (res *github.Response, err error) {
-		reviews, res, err = c.client.PullRequests.ListReviews(cx, owner, name, prNum, &github.ListOptions{
+	err := c.callRateLimited(ctx, "ListPullRequestReviews", func(cx context.Context, client *github.Client) (res *github.Response, err error) {
+		reviews, res, err = client.PullRequests.ListReviews(cx, owner, name, prNum, &github.ListOptions{
 			Page:    page,
 			PerPage: DEFAULT_PER_PAGE,
 		})
@@ -114,8 +355,8 @@ func (c *githubClient) ListPullRequestReviews(ctx context.Context, owner, name s
 func (c *githubClient) ListPullRequestReviewComments(ctx context.Context, owner, name string, prNum int, reviewID int64, page int) ([]*github.PullRequestComment, bool, error) {
 	var comments []*github.PullRequestComment
 	c.log.Info("List pull request review comments", "repo", owner+"/"+name, "pr", prNum, "reviewID", reviewID, "page", page)
-	err := c.callRateLimited(ctx, func(cx context.Context) (res *github.Response, err error) {
-		comments, res, err = c.client.PullRequests.ListReviewComments(
+	err := c.callRateLimited(ctx, "ListPullRequestReviewComments", func(cx context.Context, client *github.Client) (res *github.Response, err error) {
+		comments, res, err = client.PullRequests.ListReviewComments(
 			cx,
 			owner,
 			name,
@@ -137,8 +378,8 @@ func (c *githubClient) ListPullRequestReviewComments(ctx context.Context, owner,
 func (c *githubClient) ListPullRequestComments(ctx context.Context, owner, name string, prNum int, page int) ([]*github.PullRequestComment, bool, error) {
 	var comments []*github.PullRequestComment
 	c.log.Info("List pull request comments", "repo", owner+"/"+name, "pr", prNum, "page", page)
-	err := c.callRateLimited(ctx, func(cx context.Context) (res *github.Response, err error) {
-		comments, res, err = c.client.PullRequests.ListComments(
+	err := c.callRateLimited(ctx, "ListPullRequestComments", func(cx context.Context, client *github.Client) (res *github.Response, err error) {
+		comments, res, err = client.PullRequests.ListComments(
 			cx,
 			owner,
 			name,
@@ -163,8 +404,8 @@ func (c *githubClient) ListPullRequestComments(ctx context.Context, owner, name
 func (c *githubClient) ListRepositoryIssues(ctx context.Context, owner, name string, page int) ([]*github.Issue, bool, error) {
 	var issues []*github.Issue
 	c.log.Info("List repository issues", "repo", owner+"/"+name, "page", page)
-	err := c.callRateLimited(ctx, func(cx context.Context) (res *github.Response, err error) {
-		issues, res, err = c.client.Issues.ListByRepo(cx, owner, name, &github.IssueListByRepoOptions{
+	err := c.callRateLimited(ctx, "ListRepositoryIssues", func(cx context.Context, client *github.Client) (res *github.Response, err error) {
+		issues, res, err = client.Issues.ListByRepo(cx, owner, name, &github.IssueListByRepoOptions{
 			State:     "all",
 			Sort:      "created",
 			Direction: "asc",
@@ -185,12 +426,12 @@ func (c *githubClient) ListRepositoryIssues(ctx context.Context, owner, name str
 func (c *githubClient) ListIssueComments(ctx context.Context, owner, name string, issueNum int, page int) ([]*github.IssueComment, bool, error) {
 	var comments []*github.IssueComment
 	c.log.Info("List issue comments", "repo", owner+"/"+name, "issue", issueNum, "page", page)
-	err := c.callRateLimited(ctx, func(cx context.Context) (res *github.Response, err error) {
+	err := c.callRateLimited(ctx, "ListIssueComments", func(cx context.Context, client *github.Client) (res *github.Response, err error) {
 		// For some reason the issue comment listing Go API requires pointers to
 		// strings as parameters instead of raw strings
 		sortParam := "created"
 		dirParam := "asc"
-		comments, res, err = c.client.Issues.ListComments(cx, owner, name, issueNum, &github.IssueListCommentsOptions{
+		comments, res, err = client.Issues.ListComments(cx, owner, name, issueNum, &github.IssueListCommentsOptions{
 			Sort:      &sortParam,
 			Direction: &dirParam,
 			ListOptions: github.ListOptions{
@@ -207,46 +448,428 @@ func (c *githubClient) ListIssueComments(ctx context.Context, owner, name string
 	return comments, len(comments) < DEFAULT_PER_PAGE, nil
 }
 
-func (c *githubClient) callRateLimited(ctx context.Context, fn func(cx context.Context) (*github.Response, error)) error {
-	var res *github.Response
-	var err error
+func (c *githubClient) ListIssueTimeline(ctx context.Context, owner, name string, issueNum int, page int) ([]*github.Timeline, bool, error) {
+	var events []*github.Timeline
+	c.log.Info("List issue timeline", "repo", owner+"/"+name, "issue", issueNum, "page", page)
+	err := c.callRateLimited(ctx, "ListIssueTimeline", func(cx context.Context, client *github.Client) (res *github.Response, err error) {
+		events, res, err = client.Issues.ListIssueTimeline(cx, owner, name, issueNum, &github.ListOptions{
+			Page:    page,
+			PerPage: DEFAULT_PER_PAGE,
+		})
+		return
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return events, len(events) < DEFAULT_PER_PAGE, nil
+}
+
+func (c *githubClient) ListIssueReactions(ctx context.Context, owner, name string, issueNum int, page int) ([]*github.Reaction, bool, error) {
+	var reactions []*github.Reaction
+	c.log.Info("List issue reactions", "repo", owner+"/"+name, "issue", issueNum, "page", page)
+	err := c.callRateLimited(ctx, "ListIssueReactions", func(cx context.Context, client *github.Client) (res *github.Response, err error) {
+		reactions, res, err = client.Reactions.ListIssueReactions(cx, owner, name, issueNum, &github.ListOptions{
+			Page:    page,
+			PerPage: DEFAULT_PER_PAGE,
+		})
+		return
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return reactions, len(reactions) < DEFAULT_PER_PAGE, nil
+}
+
+func (c *githubClient) ListIssueCommentReactions(ctx context.Context, owner, name string, commentID int64, page int) ([]*github.Reaction, bool, error) {
+	var reactions []*github.Reaction
+	c.log.Info("List issue comment reactions", "repo", owner+"/"+name, "comment", commentID, "page", page)
+	err := c.callRateLimited(ctx, "ListIssueCommentReactions", func(cx context.Context, client *github.Client) (res *github.Response, err error) {
+		reactions, res, err = client.Reactions.ListIssueCommentReactions(cx, owner, name, commentID, &github.ListOptions{
+			Page:    page,
+			PerPage: DEFAULT_PER_PAGE,
+		})
+		return
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return reactions, len(reactions) < DEFAULT_PER_PAGE, nil
+}
+
+func (c *githubClient) ListPullRequestCommentReactions(ctx context.Context, owner, name string, commentID int64, page int) ([]*github.Reaction, bool, error) {
+	var reactions []*github.Reaction
+	c.log.Info("List pull request comment reactions", "repo", owner+"/"+name, "comment", commentID, "page", page)
+	err := c.callRateLimited(ctx, "ListPullRequestCommentReactions", func(cx context.Context, client *github.Client) (res *github.Response, err error) {
+		reactions, res, err = client.Reactions.ListPullRequestCommentReactions(cx, owner, name, commentID, &github.ListOptions{
+			Page:    page,
+			PerPage: DEFAULT_PER_PAGE,
+		})
+		return
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return reactions, len(reactions) < DEFAULT_PER_PAGE, nil
+}
 
+// ListReleaseReactions lists the reactions to a release. The version of the
+// GitHub API client we use does not support this endpoint, so we construct
+// the request ourselves.
+func (c *githubClient) ListReleaseReactions(ctx context.Context, owner, name string, releaseID int64, page int) ([]*github.Reaction, bool, error) {
+	var reactions []*github.Reaction
+	c.log.Info("List release reactions", "repo", owner+"/"+name, "release", releaseID, "page", page)
+	err := c.callRateLimited(ctx, "ListReleaseReactions", func(cx context.Context, client *github.Client) (*github.Response, error) {
+		u := fmt.Sprintf("repos/%s/%s/releases/%d/reactions?page=%d&per_page=%d", owner, name, releaseID, page, DEFAULT_PER_PAGE)
+		req, err := client.NewRequest(http.MethodGet, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		reactions = nil
+		return client.Do(cx, req, &reactions)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return reactions, len(reactions) < DEFAULT_PER_PAGE, nil
+}
+
+// GitHubRelease is a release along with the summary of its reactions. The
+// version of the GitHub API client we use does not include reactions in
+// releases, so we decode them separately.
+type GitHubRelease struct {
+	*github.RepositoryRelease
+	Reactions *github.Reactions `json:"reactions,omitempty"`
+}
+
+func (c *githubClient) ListReleases(ctx context.Context, owner, name string, page int) ([]*GitHubRelease, bool, error) {
+	var releases []*GitHubRelease
+	c.log.Info("List releases", "repo", owner+"/"+name, "page", page)
+	err := c.callRateLimited(ctx, "ListReleases", func(cx context.Context, client *github.Client) (*github.Response, error) {
+		u := fmt.Sprintf("repos/%s/%s/releases?page=%d&per_page=%d", owner, name, page, DEFAULT_PER_PAGE)
+		req, err := client.NewRequest(http.MethodGet, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		releases = nil
+		return client.Do(cx, req, &releases)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return releases, len(releases) < DEFAULT_PER_PAGE, nil
+}
+
+func (c *githubClient) ListRepositoryIssueComments(ctx context.Context, owner, name string, page int) ([]*github.IssueComment, bool, error) {
+	var comments []*github.IssueComment
+	c.log.Info("List repository issue comments", "repo", owner+"/"+name, "page", page)
+	err := c.callRateLimited(ctx, "ListRepositoryIssueComments", func(cx context.Context, client *github.Client) (res *github.Response, err error) {
+		sortParam := "created"
+		dirParam := "asc"
+		// An issue number of 0 lists the comments on all issues.
+		comments, res, err = client.Issues.ListComments(cx, owner, name, 0, &github.IssueListCommentsOptions{
+			Sort:      &sortParam,
+			Direction: &dirParam,
+			ListOptions: github.ListOptions{
+				Page:    page,
+				PerPage: DEFAULT_PER_PAGE,
+			},
+		})
+		return
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return comments, len(comments) < DEFAULT_PER_PAGE, nil
+}
+
+func (c *githubClient) ListRepositoryPullRequestComments(ctx context.Context, owner, name string, page int) ([]*github.PullRequestComment, bool, error) {
+	var comments []*github.PullRequestComment
+	c.log.Info("List repository pull request comments", "repo", owner+"/"+name, "page", page)
+	err := c.callRateLimited(ctx, "ListRepositoryPullRequestComments", func(cx context.Context, client *github.Client) (res *github.Response, err error) {
+		// A pull request number of 0 lists the comments on all pull requests.
+		comments, res, err = client.PullRequests.ListComments(cx, owner, name, 0, &github.PullRequestListCommentsOptions{
+			Sort:      "created",
+			Direction: "asc",
+			ListOptions: github.ListOptions{
+				Page:    page,
+				PerPage: DEFAULT_PER_PAGE,
+			},
+		})
+		return
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return comments, len(comments) < DEFAULT_PER_PAGE, nil
+}
+
+func (c *githubClient) GetIssue(ctx context.Context, owner, name string, issueNum int) (*github.Issue, error) {
+	var issue *github.Issue
+	c.log.Info("Get issue", "repo", owner+"/"+name, "issue", issueNum)
+	err := c.callRateLimited(ctx, "GetIssue", func(cx context.Context, client *github.Client) (res *github.Response, err error) {
+		issue, res, err = client.Issues.Get(cx, owner, name, issueNum)
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	return issue, nil
+}
+
+func (c *githubClient) GetPullRequest(ctx context.Context, owner, name string, prNum int) (*github.PullRequest, error) {
+	var pr *github.PullRequest
+	c.log.Info("Get pull request", "repo", owner+"/"+name, "pr", prNum)
+	err := c.callRateLimited(ctx, "GetPullRequest", func(cx context.Context, client *github.Client) (res *github.Response, err error) {
+		pr, res, err = client.PullRequests.Get(cx, owner, name, prNum)
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pr, nil
+}
+
+func (c *githubClient) ListRepositoryEvents(ctx context.Context, owner, name string, page int) ([]*github.Event, bool, error) {
+	var events []*github.Event
+	c.log.Info("List repository events", "repo", owner+"/"+name, "page", page)
+	err := c.callRateLimited(ctx, "ListRepositoryEvents", func(cx context.Context, client *github.Client) (res *github.Response, err error) {
+		events, res, err = client.Activity.ListRepositoryEvents(cx, owner, name, &github.ListOptions{
+			Page:    page,
+			PerPage: DEFAULT_PER_PAGE,
+		})
+		return
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return events, len(events) < DEFAULT_PER_PAGE, nil
+}
+
+func (c *githubClient) ListOrganizationTeams(ctx context.Context, org string, page int) ([]*github.Team, bool, error) {
+	var teams []*github.Team
+	c.log.Info("List organization teams", "org", org, "page", page)
+	err := c.callRateLimited(ctx, "ListOrganizationTeams", func(cx context.Context, client *github.Client) (res *github.Response, err error) {
+		teams, res, err = client.Teams.ListTeams(cx, org, &github.ListOptions{
+			Page:    page,
+			PerPage: DEFAULT_PER_PAGE,
+		})
+		return
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return teams, len(teams) < DEFAULT_PER_PAGE, nil
+}
+
+func (c *githubClient) ListTeamMembers(ctx context.Context, org, slug, role string, page int) ([]*github.User, bool, error) {
+	var members []*github.User
+	c.log.Info("List team members", "org", org, "team", slug, "role", role, "page", page)
+	err := c.callRateLimited(ctx, "ListTeamMembers", func(cx context.Context, client *github.Client) (res *github.Response, err error) {
+		members, res, err = client.Teams.ListTeamMembersBySlug(cx, org, slug, &github.TeamListTeamMembersOptions{
+			Role: role,
+			ListOptions: github.ListOptions{
+				Page:    page,
+				PerPage: DEFAULT_PER_PAGE,
+			},
+		})
+		return
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return members, len(members) < DEFAULT_PER_PAGE, nil
+}
+
+func (c *githubClient) ListTeamRepositories(ctx context.Context, org, slug string, page int) ([]*github.Repository, bool, error) {
+	var repos []*github.Repository
+	c.log.Info("List team repositories", "org", org, "team", slug, "page", page)
+	err := c.callRateLimited(ctx, "ListTeamRepositories", func(cx context.Context, client *github.Client) (res *github.Response, err error) {
+		repos, res, err = client.Teams.ListTeamReposBySlug(cx, org, slug, &github.ListOptions{
+			Page:    page,
+			PerPage: DEFAULT_PER_PAGE,
+		})
+		return
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return repos, len(repos) < DEFAULT_PER_PAGE, nil
+}
+
+// callRateLimited executes the given REST API request, rotating between
+// clients to avoid the primary rate limit (waiting for the rate limit to reset
+// if all clients have exhausted their quotas), and retrying retryable failures
+// as per the client's retry policy. All waiting is aborted if the context is
+// cancelled.
+func (c *githubClient) callRateLimited(ctx context.Context, endpoint string, fn func(cx context.Context, client *github.Client) (*github.Response, error)) error {
+	return c.callRateLimitedResource(ctx, rateLimitResourceCore, endpoint, fn)
+}
+
+// callRateLimitedResource is like callRateLimited, but for requests that count
+// towards the primary rate limit of the given resource.
+func (c *githubClient) callRateLimitedResource(ctx context.Context, resource, endpoint string, fn func(cx context.Context, client *github.Client) (*github.Response, error)) error {
+	attempt := 0
+	rateLimited := 0
 	for {
-		res, err = c.retryWithTimeout(ctx, fn)
+		pc, waitUntil := c.nextClient(resource)
+		if pc == nil {
+			c.log.Warn("GitHub rate limit hit for all tokens, waiting until reset time", "reset", waitUntil.Local().String())
+			if err := sleepContext(ctx, time.Until(waitUntil)+time.Second); err != nil {
+				return fmt.Errorf("cancelled while waiting for GitHub rate limit reset: %w", err)
+			}
+			continue
+		}
+		res, err := c.attempt(ctx, pc, fn)
+		c.updateRate(pc, endpoint, res, attempt > 0 || rateLimited > 0)
+		countRequest(ctx, attempt > 0 || rateLimited > 0)
 		if err == nil {
-			break
+			return nil
 		}
-		if res.Rate.Remaining > 0 {
+		kind, wait := classifyRequestError(ctx, err)
+		switch kind {
+		case requestErrorFatal:
 			return err
+		case requestErrorPrimaryRateLimit:
+			c.markRateLimited(pc, err)
+			rateLimited++
+			if rateLimited >= c.policy.MaxAttempts*len(c.clients) {
+				return fmt.Errorf("GitHub rate limit still exceeded after %d attempt(s): %w", attempt+rateLimited, err)
+			}
+			continue
+		}
+		attempt++
+		if attempt >= c.policy.MaxAttempts {
+			return fmt.Errorf("GitHub request failed after %d attempt(s): %w", attempt, err)
+		}
+		if wait == 0 {
+			wait = c.policy.Backoff(attempt)
+		}
+		c.log.Warn(
+			"GitHub request failed; retrying",
+			"err", err,
+			"attempt", attempt,
+			"maxAttempts", c.policy.MaxAttempts,
+			"wait", wait.String(),
+		)
+		if err := sleepContext(ctx, wait); err != nil {
+			return fmt.Errorf("cancelled while waiting to retry GitHub request: %w", err)
+		}
+	}
+}
+
+// attempt executes a single attempt of a request, subject to the retry
+// policy's per-attempt timeout.
+func (c *githubClient) attempt(ctx context.Context, pc *pooledClient, fn func(cx context.Context, client *github.Client) (*github.Response, error)) (*github.Response, error) {
+	attemptCtx := ctx
+	if c.policy.Timeout > 0 {
+		var cancel context.CancelFunc
+		attemptCtx, cancel = context.WithTimeout(ctx, c.policy.Timeout)
+		defer cancel()
+	}
+	return fn(attemptCtx, pc.client)
+}
+
+// markRateLimited records that the given client has hit its primary rate
+// limit for the resource to which the error pertains, so that it will not be
+// selected again for that resource until its reset time.
+func (c *githubClient) markRateLimited(pc *pooledClient, err error) {
+	var rateLimitErr *github.RateLimitError
+	if !errors.As(err, &rateLimitErr) {
+		return
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	resource := rateLimitResource(rateLimitErr.Response)
+	rate := rateLimitErr.Rate
+	rate.Remaining = 0
+	// Never retry with this client immediately, even if the reported reset
+	// time is missing or has already passed.
+	if minReset := time.Now().Add(primaryRateLimitMinWait); rate.Reset.Time.Before(minReset) {
+		rate.Reset = github.Timestamp{Time: minReset}
+	}
+	pc.rates[resource] = &rate
+	c.log.Warn("GitHub rate limit hit", "token", pc.id, "resource", resource, "limit", rate.Limit, "reset", rate.Reset.Local().String())
+}
+
+// nextClient selects the client with the most remaining rate limit quota for
+// the given resource. Clients for which no requests have been made yet for
+// the resource are preferred. If all clients' quotas have been exhausted, nil
+// is returned along with the earliest time at which a client's quota will be
+// reset.
+func (c *githubClient) nextClient(resource string) (*pooledClient, time.Time) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	var best *pooledClient
+	var earliestReset time.Time
+	now := time.Now()
+	for _, pc := range c.clients {
+		rate := pc.rates[resource]
+		if rate == nil {
+			return pc, time.Time{}
 		}
-		c.log.Warn("GitHub rate limit hit, waiting until reset time", "limit", res.Rate.Limit, "reset", res.Rate.Reset.Local().String())
-		time.Sleep(time.Until(res.Rate.Reset.Time) + time.Second)
-	}
-	c.log.Debug("Rate limiting", "limit", res.Rate.Limit, "remaining", res.Rate.Remaining)
-	return nil
-}
-
-type retryResult struct {
-	res *github.Response
-	err error
-}
-
-func (c *githubClient) retryWithTimeout(ctx context.Context, fn func(cx context.Context) (*github.Response, error)) (*github.Response, error) {
-	for attempt := 0; attempt < c.retries; attempt++ {
-		attemptCtx, cancelAttempt := context.WithCancel(ctx)
-		resChan := make(chan retryResult)
-		go func() {
-			defer cancelAttempt()
-			res, err := fn(attemptCtx)
-			resChan <- retryResult{res, err}
-		}()
-		select {
-		case res := <-resChan:
-			return res.res, res.err
-		case <-time.After(c.timeout):
-			c.log.Warn("Timed out while attempting GitHub request; retrying", "timeout", c.timeout.String(), "attempt", attempt+1, "retries", c.retries)
-			cancelAttempt()
+		if rate.Remaining <= 0 && now.Before(rate.Reset.Time) {
+			if earliestReset.IsZero() || rate.Reset.Time.Before(earliestReset) {
+				earliestReset = rate.Reset.Time
+			}
+			continue
 		}
+		if best == nil || remainingQuota(rate, now) > remainingQuota(best.rates[resource], now) {
+			best = pc
+		}
+	}
+	return best, earliestReset
+}
+
+func (c *githubClient) updateRate(pc *pooledClient, endpoint string, res *github.Response, retry bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.requests[endpoint]++
+	if retry {
+		c.retries[endpoint]++
+	}
+	if res == nil {
+		return
+	}
+	resource := rateLimitResource(res.Response)
+	rate := res.Rate
+	pc.rates[resource] = &rate
+	pc.requests++
+	c.log.Debug("Rate limiting", "token", pc.id, "resource", resource, "requests", pc.requests, "limit", rate.Limit, "remaining", rate.Remaining)
+}
+
+// Stats implements GitHubClientStatsProvider.
+func (c *githubClient) Stats() GitHubClientStats {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	stats := GitHubClientStats{
+		RequestsByEndpoint: make(map[string]int, len(c.requests)),
+		RetriesByEndpoint:  make(map[string]int, len(c.retries)),
+	}
+	for endpoint, n := range c.requests {
+		stats.Requests += n
+		stats.RequestsByEndpoint[endpoint] = n
+	}
+	for endpoint, n := range c.retries {
+		stats.Retries += n
+		stats.RetriesByEndpoint[endpoint] = n
+	}
+	now := time.Now()
+	for _, pc := range c.clients {
+		rate := pc.rates[rateLimitResourceCore]
+		if rate == nil {
+			continue
+		}
+		stats.RateLimit += rate.Limit
+		stats.RateLimitRemaining += remainingQuota(rate, now)
+	}
+	return stats
+}
+
+// remainingQuota returns the number of requests expected to be available at
+// the given time, given the latest known rate limit status.
+func remainingQuota(rate *github.Rate, now time.Time) int {
+	if !now.Before(rate.Reset.Time) {
+		return rate.Limit
 	}
-	return nil, fmt.Errorf("failed to execute GitHub request %d times", c.retries)
+	return rate.Remaining
 }
@@ -3,11 +3,293 @@ package ghere_test
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
 
 	"github.com/google/go-github/v48/github"
 	"github.com/informalsystems/ghere/pkg/ghere"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
 )
 
+func TestGitHubClientPoolRotation(t *testing.T) {
+	var mtx sync.Mutex
+	// Remaining quota per token
+	remaining := map[string]int{
+		"token1": 10,
+		"token2": 1000,
+		"token3": 0,
+	}
+	used := []string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		used = append(used, token)
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+		if remaining[token] == 0 {
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"message": "API rate limit exceeded"}`)
+			return
+		}
+		remaining[token]--
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining[token]))
+		fmt.Fprint(w, `{"name": "repo", "owner": {"login": "org"}}`)
+	}))
+	defer srv.Close()
+
+	baseURL, err := url.Parse(srv.URL + "/")
+	require.NoError(t, err)
+	clients := []*github.Client{}
+	tokenSources := []oauth2.TokenSource{}
+	for _, token := range []string{"token3", "token1", "token2"} {
+		ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
+		client := github.NewClient(oauth2.NewClient(context.Background(), ts))
+		client.BaseURL = baseURL
+		clients = append(clients, client)
+		tokenSources = append(tokenSources, ts)
+	}
+	pool := ghere.NewGitHubClientPool(clients, nil, ghere.NewNoopLogger())
+	poolTokenSource := ghere.NewGitHubClientPoolTokenSource(pool, tokenSources)
+	token, err := poolTokenSource.Token()
+	require.NoError(t, err)
+	assert.Equal(t, "token3", token.AccessToken)
+
+	for i := 0; i < 5; i++ {
+		repo, err := pool.GetRepository(context.Background(), "org", "repo")
+		require.NoError(t, err)
+		assert.Equal(t, "repo", repo.GetName())
+	}
+	// Each token is tried once (the exhausted token fails), after which the
+	// token with the most remaining quota is always used.
+	assert.Equal(t, []string{"token3", "token1", "token2", "token2", "token2", "token2"}, used)
+	// Git operations must use the same token as API requests.
+	token, err = poolTokenSource.Token()
+	require.NoError(t, err)
+	assert.Equal(t, "token2", token.AccessToken)
+}
+
+func TestGitHubClientGraphQLRateLimit(t *testing.T) {
+	var mtx sync.Mutex
+	used := []string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+		if r.URL.Path != "/graphql" {
+			used = append(used, "rest:"+token)
+			w.Header().Set("X-RateLimit-Resource", "core")
+			w.Header().Set("X-RateLimit-Limit", "5000")
+			w.Header().Set("X-RateLimit-Remaining", "4000")
+			fmt.Fprint(w, `{"name": "repo", "owner": {"login": "org"}}`)
+			return
+		}
+		used = append(used, "graphql:"+token)
+		w.Header().Set("X-RateLimit-Resource", "graphql")
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		// token1's GraphQL quota is exhausted, which GitHub reports along
+		// with a successful status code.
+		if token == "token1" {
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			fmt.Fprint(w, `{"errors": [{"type": "RATE_LIMITED", "message": "API rate limit exceeded"}]}`)
+			return
+		}
+		w.Header().Set("X-RateLimit-Remaining", "100")
+		fmt.Fprint(w, `{"data": {"repository": {"discussionCategories": {"nodes": [{"id": "DIC_1", "name": "Q&A"}], "pageInfo": {"hasNextPage": false}}}}}`)
+	}))
+	defer srv.Close()
+
+	baseURL, err := url.Parse(srv.URL + "/")
+	require.NoError(t, err)
+	clients := []*github.Client{}
+	for _, token := range []string{"token1", "token2"} {
+		client := github.NewClient(oauth2.NewClient(context.Background(), oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})))
+		client.BaseURL = baseURL
+		clients = append(clients, client)
+	}
+	pool := ghere.NewGitHubClientPool(clients, nil, ghere.NewNoopLogger())
+
+	categories, err := pool.ListDiscussionCategories(context.Background(), "org", "repo")
+	require.NoError(t, err)
+	require.Len(t, categories, 1)
+	// The rate limited token remains usable for the REST API, whose quota is
+	// tracked separately, but not for the GraphQL API.
+	for i := 0; i < 3; i++ {
+		_, err = pool.GetRepository(context.Background(), "org", "repo")
+		require.NoError(t, err)
+	}
+	_, err = pool.ListDiscussionCategories(context.Background(), "org", "repo")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"graphql:token1",
+		"graphql:token2",
+		"rest:token1",
+		"rest:token2",
+		"rest:token1",
+		"graphql:token2",
+	}, used)
+}
+
+func TestGitHubClientRetries(t *testing.T) {
+	var mtx sync.Mutex
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		requests++
+		switch {
+		case strings.Contains(r.URL.Path, "flaky") && requests < 3:
+			w.WriteHeader(http.StatusBadGateway)
+			fmt.Fprint(w, `{"message": "Server Error"}`)
+		case strings.Contains(r.URL.Path, "throttled") && requests < 3:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"message": "Too Many Requests"}`)
+		case strings.Contains(r.URL.Path, "forbidden"):
+			if strings.Contains(r.URL.Path, "later") {
+				w.Header().Set("Retry-After", "3600")
+			}
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"message": "Forbidden"}`)
+		case strings.Contains(r.URL.Path, "missing"):
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "Not Found"}`)
+		case strings.Contains(r.URL.Path, "abused"):
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"message": "You have exceeded a secondary rate limit", "documentation_url": "https://docs.github.com/rest/overview/resources-in-the-rest-api#secondary-rate-limits"}`)
+		default:
+			fmt.Fprint(w, `{"name": "repo", "owner": {"login": "org"}}`)
+		}
+	}))
+	defer srv.Close()
+
+	baseURL, err := url.Parse(srv.URL + "/")
+	require.NoError(t, err)
+	client := github.NewClient(nil)
+	client.BaseURL = baseURL
+	policy := ghere.DefaultRetryPolicy()
+	policy.MaxAttempts = 5
+	policy.InitialBackoff = time.Millisecond
+	gc := ghere.NewGitHubClient(client, policy, ghere.NewNoopLogger())
+
+	// 502 responses are retried
+	repo, err := gc.GetRepository(context.Background(), "org", "flaky")
+	require.NoError(t, err)
+	assert.Equal(t, "repo", repo.GetName())
+	assert.Equal(t, 3, requests)
+
+	// 429 responses are retried
+	requests = 0
+	_, err = gc.GetRepository(context.Background(), "org", "throttled")
+	require.NoError(t, err)
+	assert.Equal(t, 3, requests)
+
+	// 403 responses are only retried if GitHub says when to retry
+	requests = 0
+	_, err = gc.GetRepository(context.Background(), "org", "forbidden")
+	require.Error(t, err)
+	assert.Equal(t, 1, requests)
+	requests = 0
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = gc.GetRepository(ctx, "org", "forbidden-later")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 1, requests)
+
+	// 404 responses are not retried
+	requests = 0
+	_, err = gc.GetRepository(context.Background(), "org", "missing")
+	require.Error(t, err)
+	assert.Equal(t, 1, requests)
+
+	// Waiting for a secondary rate limit is aborted on cancellation
+	requests = 0
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = gc.GetRepository(ctx, "org", "abused")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 10*time.Second)
+	assert.Equal(t, 1, requests)
+}
+
+func TestGitHubClientPrimaryRateLimit(t *testing.T) {
+	var mtx sync.Mutex
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		requests++
+		// The reported reset time has already passed.
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10))
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"message": "API rate limit exceeded"}`)
+	}))
+	defer srv.Close()
+
+	baseURL, err := url.Parse(srv.URL + "/")
+	require.NoError(t, err)
+	newClients := func(n int) []*github.Client {
+		clients := []*github.Client{}
+		for i := 0; i < n; i++ {
+			client := github.NewClient(nil)
+			client.BaseURL = baseURL
+			clients = append(clients, client)
+		}
+		return clients
+	}
+	policy := ghere.DefaultRetryPolicy()
+
+	// A rate limited token is not immediately retried, even if its reset time
+	// has passed.
+	pool := ghere.NewGitHubClientPool(newClients(1), policy, ghere.NewNoopLogger())
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = pool.GetRepository(ctx, "org", "repo")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 1, requests)
+
+	// Rate limited attempts are counted, and eventually given up on.
+	requests = 0
+	policy.MaxAttempts = 1
+	pool = ghere.NewGitHubClientPool(newClients(3), policy, ghere.NewNoopLogger())
+	_, err = pool.GetRepository(context.Background(), "org", "repo")
+	var rateLimitErr *github.RateLimitError
+	require.ErrorAs(t, err, &rateLimitErr)
+	assert.Equal(t, 3, requests)
+	stats := pool.(ghere.GitHubClientStatsProvider).Stats()
+	assert.Equal(t, 3, stats.Requests)
+	assert.Equal(t, 2, stats.Retries)
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := &ghere.RetryPolicy{
+		InitialBackoff: time.Second,
+		MaxBackoff:     10 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.5,
+	}
+	for retry, expected := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second} {
+		backoff := policy.Backoff(retry + 1)
+		assert.LessOrEqual(t, backoff, expected)
+		assert.GreaterOrEqual(t, backoff, expected/2)
+	}
+}
+
 type MockGitHubClient struct {
 	Repositories              map[string]*github.Repository
 	Labels                    map[string][]*github.Label
@@ -17,6 +299,44 @@ type MockGitHubClient struct {
 	PullRequestComments       map[string]map[int][]*github.PullRequestComment
 	Issues                    map[string][]*github.Issue
 	IssueComments             map[string]map[int][]*github.IssueComment
+	Events                    map[string][]*github.Event
+	Timelines                 map[string]map[int][]*github.Timeline
+	Releases                  map[string][]*ghere.GitHubRelease
+	// Reactions are keyed by repository, and then by the type and ID/number
+	// of the item reacted to (e.g. "issue/1", "issue_comment/2",
+	// "pull_request_comment/3" or "release/4").
+	Reactions map[string]map[string][]*github.Reaction
+	// Repositories without discussion categories are treated as having
+	// discussions disabled.
+	DiscussionCategories map[string][]*ghere.GitHubDiscussionCategory
+	Discussions          map[string][]*ghere.GitHubDiscussion
+	DiscussionComments   map[string]map[int][]*ghere.GitHubDiscussionComment
+	// Projects are keyed by path (e.g. "org/projects/1" or
+	// "org/repo/projects/2"), and their fields and items by project ID.
+	Projects      map[string]*ghere.GitHubProjectV2
+	ProjectFields map[string][]*ghere.GitHubProjectV2Field
+	ProjectItems  map[string][]*ghere.GitHubProjectV2Item
+	// Teams are keyed by organization, and their members (keyed by role) and
+	// repositories by "org/slug".
+	Teams            map[string][]*github.Team
+	TeamMembers      map[string]map[string][]*github.User
+	TeamRepositories map[string][]*github.Repository
+	// Repositories without workflow runs are treated as not using GitHub
+	// Actions. Jobs and artifacts are keyed by repository and then by run ID,
+	// and the zip archives of runs' logs and of artifacts by their IDs.
+	Workflows         map[string][]*github.Workflow
+	WorkflowRuns      map[string][]*github.WorkflowRun
+	WorkflowJobs      map[string]map[int64][]*github.WorkflowJob
+	WorkflowArtifacts map[string]map[int64][]*github.Artifact
+	WorkflowRunLogs   map[int64][]byte
+	ArtifactArchives  map[int64][]byte
+	// Commits are the commits on each repository's default branch, most
+	// recent first. Statuses and checks are keyed by repository and then by
+	// commit SHA, where missing commits are treated as having none.
+	Commits     map[string][]*github.RepositoryCommit
+	Statuses    map[string]map[string][]*github.RepoStatus
+	CheckSuites map[string]map[string][]*github.CheckSuite
+	CheckRuns   map[string]map[string][]*github.CheckRun
 }
 
 var _ ghere.GitHubClient = (*MockGitHubClient)(nil)
@@ -69,6 +389,311 @@ func (c *MockGitHubClient) ListRepositoryPullRequests(ctx context.Context, owner
 	return getPageForRepo(c.PullRequests, owner, name, page)
 }
 
+// ListIssueTimeline implements ghere.GitHubClient
+func (c *MockGitHubClient) ListIssueTimeline(ctx context.Context, owner string, name string, issueNum int, page int) ([]*github.Timeline, bool, error) {
+	return getPageForIssueOrPR(c.Timelines, owner, name, issueNum, page, "issue or pull request")
+}
+
+// ListIssueReactions implements ghere.GitHubClient
+func (c *MockGitHubClient) ListIssueReactions(ctx context.Context, owner string, name string, issueNum int, page int) ([]*github.Reaction, bool, error) {
+	return c.getReactionsPage(owner, name, fmt.Sprintf("issue/%d", issueNum), page)
+}
+
+// ListIssueCommentReactions implements ghere.GitHubClient
+func (c *MockGitHubClient) ListIssueCommentReactions(ctx context.Context, owner string, name string, commentID int64, page int) ([]*github.Reaction, bool, error) {
+	return c.getReactionsPage(owner, name, fmt.Sprintf("issue_comment/%d", commentID), page)
+}
+
+// ListPullRequestCommentReactions implements ghere.GitHubClient
+func (c *MockGitHubClient) ListPullRequestCommentReactions(ctx context.Context, owner string, name string, commentID int64, page int) ([]*github.Reaction, bool, error) {
+	return c.getReactionsPage(owner, name, fmt.Sprintf("pull_request_comment/%d", commentID), page)
+}
+
+// ListReleaseReactions implements ghere.GitHubClient
+func (c *MockGitHubClient) ListReleaseReactions(ctx context.Context, owner string, name string, releaseID int64, page int) ([]*github.Reaction, bool, error) {
+	return c.getReactionsPage(owner, name, fmt.Sprintf("release/%d", releaseID), page)
+}
+
+// ListReleases implements ghere.GitHubClient
+func (c *MockGitHubClient) ListReleases(ctx context.Context, owner string, name string, page int) ([]*ghere.GitHubRelease, bool, error) {
+	return getListPage(c.Releases[owner+"/"+name], page)
+}
+
+func (c *MockGitHubClient) getReactionsPage(owner, name, item string, page int) ([]*github.Reaction, bool, error) {
+	forRepo, err := getForRepo(c.Reactions, owner, name)
+	if err != nil {
+		return nil, false, err
+	}
+	reactions, exists := forRepo[item]
+	if !exists {
+		return nil, false, fmt.Errorf("no reactions for %s of %s/%s", item, owner, name)
+	}
+	return getListPage(reactions, page)
+}
+
+// ListRepositoryIssueComments implements ghere.GitHubClient
+func (c *MockGitHubClient) ListRepositoryIssueComments(ctx context.Context, owner string, name string, page int) ([]*github.IssueComment, bool, error) {
+	return getListPage(allForRepo(c.IssueComments[owner+"/"+name]), page)
+}
+
+// ListRepositoryPullRequestComments implements ghere.GitHubClient
+func (c *MockGitHubClient) ListRepositoryPullRequestComments(ctx context.Context, owner string, name string, page int) ([]*github.PullRequestComment, bool, error) {
+	return getListPage(allForRepo(c.PullRequestComments[owner+"/"+name]), page)
+}
+
+// allForRepo flattens the items of all of a repository's issues or pull
+// requests, ordered by issue or pull request number.
+func allForRepo[V any](m map[int][]V) []V {
+	nums := make([]int, 0, len(m))
+	for num := range m {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+	all := []V{}
+	for _, num := range nums {
+		all = append(all, m[num]...)
+	}
+	return all
+}
+
+// ListDiscussionCategories implements ghere.GitHubClient
+func (c *MockGitHubClient) ListDiscussionCategories(ctx context.Context, owner string, name string) ([]*ghere.GitHubDiscussionCategory, error) {
+	return c.DiscussionCategories[owner+"/"+name], nil
+}
+
+// ListDiscussions implements ghere.GitHubClient
+func (c *MockGitHubClient) ListDiscussions(ctx context.Context, owner string, name string, cursor string) ([]*ghere.GitHubDiscussion, string, error) {
+	page, err := cursorPage(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	discussions, done, err := getPageForRepo(c.Discussions, owner, name, page)
+	return discussions, nextCursor(page, done), err
+}
+
+// ListDiscussionComments implements ghere.GitHubClient
+func (c *MockGitHubClient) ListDiscussionComments(ctx context.Context, owner string, name string, discussionNum int, cursor string) ([]*ghere.GitHubDiscussionComment, string, error) {
+	page, err := cursorPage(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	comments, done, err := getPageForIssueOrPR(c.DiscussionComments, owner, name, discussionNum, page, "discussion")
+	return comments, nextCursor(page, done), err
+}
+
+// GetProject implements ghere.GitHubClient
+func (c *MockGitHubClient) GetProject(ctx context.Context, owner string, name string, number int) (*ghere.GitHubProjectV2, []*ghere.GitHubProjectV2Field, error) {
+	path := owner
+	if len(name) > 0 {
+		path += "/" + name
+	}
+	path += fmt.Sprintf("/projects/%d", number)
+	project, exists := c.Projects[path]
+	if !exists {
+		return nil, nil, fmt.Errorf("no such project: %s", path)
+	}
+	return project, c.ProjectFields[project.ID], nil
+}
+
+// ListProjectItems implements ghere.GitHubClient
+func (c *MockGitHubClient) ListProjectItems(ctx context.Context, projectID string, cursor string) ([]*ghere.GitHubProjectV2Item, string, error) {
+	page, err := cursorPage(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	items, exists := c.ProjectItems[projectID]
+	if !exists {
+		return nil, "", fmt.Errorf("no such project: %s", projectID)
+	}
+	items, done, err := getListPage(items, page)
+	return items, nextCursor(page, done), err
+}
+
+// ListOrganizationTeams implements ghere.GitHubClient
+func (c *MockGitHubClient) ListOrganizationTeams(ctx context.Context, org string, page int) ([]*github.Team, bool, error) {
+	teams, exists := c.Teams[org]
+	if !exists {
+		return nil, false, fmt.Errorf("no such organization: %s", org)
+	}
+	return getListPage(teams, page)
+}
+
+// ListTeamMembers implements ghere.GitHubClient
+func (c *MockGitHubClient) ListTeamMembers(ctx context.Context, org string, slug string, role string, page int) ([]*github.User, bool, error) {
+	members, err := getForRepo(c.TeamMembers, org, slug)
+	if err != nil {
+		return nil, false, err
+	}
+	return getListPage(members[role], page)
+}
+
+// ListTeamRepositories implements ghere.GitHubClient
+func (c *MockGitHubClient) ListTeamRepositories(ctx context.Context, org string, slug string, page int) ([]*github.Repository, bool, error) {
+	return getPageForRepo(c.TeamRepositories, org, slug, page)
+}
+
+// ListWorkflows implements ghere.GitHubClient
+func (c *MockGitHubClient) ListWorkflows(ctx context.Context, owner string, name string, page int) ([]*github.Workflow, bool, error) {
+	return getListPage(c.Workflows[owner+"/"+name], page)
+}
+
+// ListWorkflowRuns implements ghere.GitHubClient
+func (c *MockGitHubClient) ListWorkflowRuns(ctx context.Context, owner string, name string, page int) ([]*github.WorkflowRun, bool, error) {
+	return getListPage(c.WorkflowRuns[owner+"/"+name], page)
+}
+
+// GetWorkflowRun implements ghere.GitHubClient
+func (c *MockGitHubClient) GetWorkflowRun(ctx context.Context, owner string, name string, runID int64) (*github.WorkflowRun, error) {
+	for _, run := range c.WorkflowRuns[owner+"/"+name] {
+		if run.GetID() == runID {
+			return run, nil
+		}
+	}
+	return nil, fmt.Errorf("no such workflow run %d for %s/%s", runID, owner, name)
+}
+
+// ListWorkflowJobs implements ghere.GitHubClient
+func (c *MockGitHubClient) ListWorkflowJobs(ctx context.Context, owner string, name string, runID int64, page int) ([]*github.WorkflowJob, bool, error) {
+	return getListPage(c.WorkflowJobs[owner+"/"+name][runID], page)
+}
+
+// ListWorkflowRunArtifacts implements ghere.GitHubClient
+func (c *MockGitHubClient) ListWorkflowRunArtifacts(ctx context.Context, owner string, name string, runID int64, page int) ([]*github.Artifact, bool, error) {
+	return getListPage(c.WorkflowArtifacts[owner+"/"+name][runID], page)
+}
+
+// DownloadWorkflowRunLogs implements ghere.GitHubClient
+func (c *MockGitHubClient) DownloadWorkflowRunLogs(ctx context.Context, owner string, name string, runID int64, maxSize int64) ([]byte, error) {
+	return getDownload(c.WorkflowRunLogs, runID, maxSize)
+}
+
+// DownloadArtifact implements ghere.GitHubClient
+func (c *MockGitHubClient) DownloadArtifact(ctx context.Context, owner string, name string, artifactID int64, maxSize int64) ([]byte, error) {
+	return getDownload(c.ArtifactArchives, artifactID, maxSize)
+}
+
+func getDownload(m map[int64][]byte, id int64, maxSize int64) ([]byte, error) {
+	data, exists := m[id]
+	if !exists {
+		return nil, &ghere.ErrDownloadUnavailable{Status: "404 Not Found"}
+	}
+	if int64(len(data)) > maxSize {
+		return nil, &ghere.ErrDownloadTooLarge{Limit: maxSize}
+	}
+	return data, nil
+}
+
+// ListCommits implements ghere.GitHubClient
+func (c *MockGitHubClient) ListCommits(ctx context.Context, owner string, name string, branch string, since time.Time, page int) ([]*github.RepositoryCommit, bool, error) {
+	commits := []*github.RepositoryCommit{}
+	for _, commit := range c.Commits[owner+"/"+name] {
+		if !commit.GetCommit().GetCommitter().GetDate().Before(since) {
+			commits = append(commits, commit)
+		}
+	}
+	return getListPage(commits, page)
+}
+
+// GetCombinedStatus implements ghere.GitHubClient
+func (c *MockGitHubClient) GetCombinedStatus(ctx context.Context, owner string, name string, sha string, page int) (*github.CombinedStatus, bool, error) {
+	allStatuses := c.Statuses[owner+"/"+name][sha]
+	statuses, done, err := getListPage(allStatuses, page)
+	if err != nil {
+		return nil, false, err
+	}
+	state := "pending"
+	if len(allStatuses) > 0 {
+		state = "success"
+	}
+	for _, status := range allStatuses {
+		switch status.GetState() {
+		case "error", "failure":
+			state = "failure"
+		case "pending":
+			if state != "failure" {
+				state = "pending"
+			}
+		}
+	}
+	return &github.CombinedStatus{
+		State:      github.String(state),
+		SHA:        github.String(sha),
+		TotalCount: github.Int(len(allStatuses)),
+		Statuses:   statuses,
+	}, done, nil
+}
+
+// ListCheckSuites implements ghere.GitHubClient
+func (c *MockGitHubClient) ListCheckSuites(ctx context.Context, owner string, name string, sha string, page int) ([]*github.CheckSuite, bool, error) {
+	return getListPage(c.CheckSuites[owner+"/"+name][sha], page)
+}
+
+// ListCheckRuns implements ghere.GitHubClient
+func (c *MockGitHubClient) ListCheckRuns(ctx context.Context, owner string, name string, sha string, page int) ([]*github.CheckRun, bool, error) {
+	return getListPage(c.CheckRuns[owner+"/"+name][sha], page)
+}
+
+// cursorPage interprets a mock GraphQL cursor as a page number.
+func cursorPage(cursor string) (int, error) {
+	if len(cursor) == 0 {
+		return 1, nil
+	}
+	return strconv.Atoi(cursor)
+}
+
+func nextCursor(page int, done bool) string {
+	if done {
+		return ""
+	}
+	return strconv.Itoa(page + 1)
+}
+
+// GetIssue implements ghere.GitHubClient
+func (c *MockGitHubClient) GetIssue(ctx context.Context, owner string, name string, issueNum int) (*github.Issue, error) {
+	issues, err := getForRepo(c.Issues, owner, name)
+	if err != nil {
+		return nil, err
+	}
+	for _, issue := range issues {
+		if issue.GetNumber() == issueNum {
+			return issue, nil
+		}
+	}
+	return nil, errorResponse(http.StatusNotFound, fmt.Sprintf("no such issue %d for %s/%s", issueNum, owner, name))
+}
+
+// GetPullRequest implements ghere.GitHubClient
+func (c *MockGitHubClient) GetPullRequest(ctx context.Context, owner string, name string, prNum int) (*github.PullRequest, error) {
+	prs, err := getForRepo(c.PullRequests, owner, name)
+	if err != nil {
+		return nil, err
+	}
+	for _, pr := range prs {
+		if pr.GetNumber() == prNum {
+			return pr, nil
+		}
+	}
+	return nil, errorResponse(http.StatusNotFound, fmt.Sprintf("no such pull request %d for %s/%s", prNum, owner, name))
+}
+
+// errorResponse mimics the error returned by the GitHub API client for a
+// response with the given status code.
+func errorResponse(statusCode int, msg string) error {
+	return &github.ErrorResponse{
+		Response: &http.Response{
+			Request:    &http.Request{Method: http.MethodGet},
+			StatusCode: statusCode,
+			Status:     http.StatusText(statusCode),
+		},
+		Message: msg,
+	}
+}
+
+// ListRepositoryEvents implements ghere.GitHubClient
+func (c *MockGitHubClient) ListRepositoryEvents(ctx context.Context, owner string, name string, page int) ([]*github.Event, bool, error) {
+	return getPageForRepo(c.Events, owner, name, page)
+}
+
 func getPageForIssueOrPR[V any](m map[string]map[int][]V, owner, name string, n, page int, tp string) ([]V, bool, error) {
 	var empty []V
 	allItems, err := getForIssueOrPR(m, owner, name, n, tp)
@@ -116,7 +741,7 @@ func getListPage[V any](l []V, page int) ([]V, bool, error) {
 		return []V{}, true, nil
 	}
 	endIdx := page * ghere.DEFAULT_PER_PAGE
-	if startIdx+endIdx >= len(l) {
+	if endIdx >= len(l) {
 		endIdx = len(l)
 	}
 	items := l[startIdx:endIdx]
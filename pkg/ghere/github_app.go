@@ -0,0 +1,183 @@
+package ghere
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v48/github"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// How long before an installation token's expiry we should consider it
+	// expired, so that we refresh it before requests start failing.
+	appTokenRefreshMargin time.Duration = 5 * time.Minute
+	// How long the JWTs used to request installation tokens are valid for.
+	// GitHub allows for a maximum of 10 minutes.
+	appJWTValidity time.Duration = 9 * time.Minute
+	// Allowance for clock drift between us and GitHub when issuing JWTs.
+	appJWTClockDrift time.Duration = 60 * time.Second
+	// Default timeout for installation token requests.
+	appTokenRequestTimeout time.Duration = 30 * time.Second
+)
+
+// GitHubAppConfig provides the configuration necessary to authenticate as a
+// GitHub App installation.
+type GitHubAppConfig struct {
+	AppID          int64
+	InstallationID int64
+	// PrivateKeyPEM is the PEM-encoded private key of the GitHub App.
+	PrivateKeyPEM []byte
+	// BaseURL optionally overrides the GitHub API base URL (e.g. for GitHub
+	// Enterprise Server, or for testing). Defaults to https://api.github.com/
+	BaseURL string
+	// HTTPClient is optionally the HTTP client to use when requesting
+	// installation tokens. Defaults to a client with a 30 second timeout.
+	HTTPClient *http.Client
+}
+
+// LoadGitHubAppPrivateKey is a convenience method to read a GitHub App's
+// PEM-encoded private key from the given file.
+func LoadGitHubAppPrivateKey(path string) ([]byte, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GitHub App private key file %s: %v", path, err)
+	}
+	return b, nil
+}
+
+type githubAppTokenSource struct {
+	appID          int64
+	installationID int64
+	key            *rsa.PrivateKey
+	client         *github.Client
+}
+
+// NewGitHubAppTokenSource creates an [oauth2.TokenSource] that mints GitHub
+// App installation tokens, automatically refreshing them shortly before they
+// expire. The resulting token source can be used both to authenticate
+// requests to the GitHub API and (via [HTTPCredentialConfig]) HTTPS Git
+// clones.
+func NewGitHubAppTokenSource(cfg *GitHubAppConfig) (oauth2.TokenSource, error) {
+	if cfg.AppID == 0 {
+		return nil, errors.New("GitHub App ID must be specified")
+	}
+	if cfg.InstallationID == 0 {
+		return nil, errors.New("GitHub App installation ID must be specified")
+	}
+	key, err := parseRSAPrivateKey(cfg.PrivateKeyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse GitHub App private key: %v", err)
+	}
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: appTokenRequestTimeout}
+	}
+	client := github.NewClient(httpClient)
+	if len(cfg.BaseURL) > 0 {
+		baseURL := cfg.BaseURL
+		if !strings.HasSuffix(baseURL, "/") {
+			baseURL += "/"
+		}
+		client.BaseURL, err = url.Parse(baseURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GitHub API base URL %s: %v", cfg.BaseURL, err)
+		}
+	}
+	ts := &githubAppTokenSource{
+		appID:          cfg.AppID,
+		installationID: cfg.InstallationID,
+		key:            key,
+		client:         client,
+	}
+	return oauth2.ReuseTokenSource(nil, ts), nil
+}
+
+// Token implements oauth2.TokenSource.
+func (ts *githubAppTokenSource) Token() (*oauth2.Token, error) {
+	jwt, err := ts.signJWT(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	req, err := ts.client.NewRequest("POST", fmt.Sprintf("app/installations/%d/access_tokens", ts.installationID), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to construct GitHub App installation token request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	installationToken := &github.InstallationToken{}
+	if _, err := ts.client.Do(context.Background(), req, installationToken); err != nil {
+		return nil, fmt.Errorf("failed to obtain GitHub App installation token: %v", err)
+	}
+	if len(installationToken.GetToken()) == 0 {
+		return nil, errors.New("GitHub returned an empty installation token")
+	}
+	return &oauth2.Token{
+		AccessToken: installationToken.GetToken(),
+		TokenType:   "token",
+		Expiry:      installationToken.GetExpiresAt().Add(-appTokenRefreshMargin),
+	}, nil
+}
+
+// signJWT produces an RS256-signed JSON Web Token identifying the GitHub App.
+// See https://docs.github.com/en/apps/creating-github-apps/authenticating-with-a-github-app/generating-a-json-web-token-jwt-for-a-github-app
+func (ts *githubAppTokenSource) signJWT(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{
+		"alg": "RS256",
+		"typ": "JWT",
+	})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iat": now.Add(-appJWTClockDrift).Unix(),
+		"exp": now.Add(appJWTValidity).Unix(),
+		"iss": strconv.FormatInt(ts.appID, 10),
+	})
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, ts.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign GitHub App JWT: %v", err)
+	}
+	return unsigned + "." + enc.EncodeToString(sig), nil
+}
+
+// parseRSAPrivateKey supports both PKCS#1 (as generated by GitHub) and PKCS#8
+// PEM-encoded RSA private keys.
+func parseRSAPrivateKey(pemBytes []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not an RSA key")
+	}
+	return rsaKey, nil
+}
@@ -0,0 +1,115 @@
+package ghere_test
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/informalsystems/ghere/pkg/ghere"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitHubAppTokenRefresh(t *testing.T) {
+	testCases := []struct {
+		name           string
+		tokenValidity  time.Duration
+		expectedMinted int32
+	}{
+		{"long-lived token is reused", time.Hour, 1},
+		{"nearly expired token is refreshed", time.Minute, 3},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			key, err := rsa.GenerateKey(rand.Reader, 2048)
+			require.NoError(t, err)
+			keyPEM := pem.EncodeToMemory(&pem.Block{
+				Type:  "RSA PRIVATE KEY",
+				Bytes: x509.MarshalPKCS1PrivateKey(key),
+			})
+
+			var minted int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost || r.URL.Path != "/app/installations/456/access_tokens" {
+					http.NotFound(w, r)
+					return
+				}
+				if err := verifyAppJWT(r.Header.Get("Authorization"), &key.PublicKey, "123"); err != nil {
+					http.Error(w, err.Error(), http.StatusUnauthorized)
+					return
+				}
+				n := atomic.AddInt32(&minted, 1)
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusCreated)
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{
+					"token":      fmt.Sprintf("token-%d", n),
+					"expires_at": time.Now().Add(tc.tokenValidity).UTC().Format(time.RFC3339),
+				})
+			}))
+			defer srv.Close()
+
+			ts, err := ghere.NewGitHubAppTokenSource(&ghere.GitHubAppConfig{
+				AppID:          123,
+				InstallationID: 456,
+				PrivateKeyPEM:  keyPEM,
+				BaseURL:        srv.URL,
+			})
+			require.NoError(t, err)
+
+			var lastToken string
+			for i := 0; i < 3; i++ {
+				token, err := ts.Token()
+				require.NoError(t, err)
+				lastToken = token.AccessToken
+			}
+			assert.Equal(t, tc.expectedMinted, atomic.LoadInt32(&minted))
+			assert.Equal(t, fmt.Sprintf("token-%d", tc.expectedMinted), lastToken)
+		})
+	}
+}
+
+func verifyAppJWT(authHeader string, key *rsa.PublicKey, expectedIssuer string) error {
+	jwt := strings.TrimPrefix(authHeader, "Bearer ")
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("malformed JWT: %s", jwt)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig); err != nil {
+		return err
+	}
+	claimsJSON, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return err
+	}
+	claims := struct {
+		Iss string `json:"iss"`
+		Exp int64  `json:"exp"`
+	}{}
+	if err := json.Unmarshal(claimsJSON, &claims); err != nil {
+		return err
+	}
+	if claims.Iss != expectedIssuer {
+		return fmt.Errorf("unexpected JWT issuer: %s", claims.Iss)
+	}
+	if time.Unix(claims.Exp, 0).Before(time.Now()) {
+		return fmt.Errorf("JWT has expired")
+	}
+	return nil
+}
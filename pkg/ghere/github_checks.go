@@ -0,0 +1,81 @@
+package ghere
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/go-github/v48/github"
+)
+
+func (c *githubClient) ListCommits(ctx context.Context, owner, name, branch string, since time.Time, page int) ([]*github.RepositoryCommit, bool, error) {
+	var commits []*github.RepositoryCommit
+	c.log.Info("List commits", "repo", owner+"/"+name, "branch", branch, "since", since, "page", page)
+	err := c.callRateLimited(ctx, "ListCommits", func(cx context.Context, client *github.Client) (res *github.Response, err error) {
+		commits, res, err = client.Repositories.ListCommits(cx, owner, name, &github.CommitsListOptions{
+			SHA:   branch,
+			Since: since,
+			ListOptions: github.ListOptions{
+				Page:    page,
+				PerPage: DEFAULT_PER_PAGE,
+			},
+		})
+		return
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return commits, len(commits) < DEFAULT_PER_PAGE, nil
+}
+
+func (c *githubClient) GetCombinedStatus(ctx context.Context, owner, name, sha string, page int) (*github.CombinedStatus, bool, error) {
+	var status *github.CombinedStatus
+	c.log.Info("Get combined status", "repo", owner+"/"+name, "sha", sha, "page", page)
+	err := c.callRateLimited(ctx, "GetCombinedStatus", func(cx context.Context, client *github.Client) (res *github.Response, err error) {
+		status, res, err = client.Repositories.GetCombinedStatus(cx, owner, name, sha, &github.ListOptions{
+			Page:    page,
+			PerPage: DEFAULT_PER_PAGE,
+		})
+		return
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return status, len(status.Statuses) < DEFAULT_PER_PAGE, nil
+}
+
+func (c *githubClient) ListCheckSuites(ctx context.Context, owner, name, sha string, page int) ([]*github.CheckSuite, bool, error) {
+	var suites *github.ListCheckSuiteResults
+	c.log.Info("List check suites", "repo", owner+"/"+name, "sha", sha, "page", page)
+	err := c.callRateLimited(ctx, "ListCheckSuites", func(cx context.Context, client *github.Client) (res *github.Response, err error) {
+		suites, res, err = client.Checks.ListCheckSuitesForRef(cx, owner, name, sha, &github.ListCheckSuiteOptions{
+			ListOptions: github.ListOptions{
+				Page:    page,
+				PerPage: DEFAULT_PER_PAGE,
+			},
+		})
+		return
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return suites.CheckSuites, len(suites.CheckSuites) < DEFAULT_PER_PAGE, nil
+}
+
+func (c *githubClient) ListCheckRuns(ctx context.Context, owner, name, sha string, page int) ([]*github.CheckRun, bool, error) {
+	var runs *github.ListCheckRunsResults
+	c.log.Info("List check runs", "repo", owner+"/"+name, "sha", sha, "page", page)
+	err := c.callRateLimited(ctx, "ListCheckRuns", func(cx context.Context, client *github.Client) (res *github.Response, err error) {
+		runs, res, err = client.Checks.ListCheckRunsForRef(cx, owner, name, sha, &github.ListCheckRunsOptions{
+			Filter: github.String("all"),
+			ListOptions: github.ListOptions{
+				Page:    page,
+				PerPage: DEFAULT_PER_PAGE,
+			},
+		})
+		return
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return runs.CheckRuns, len(runs.CheckRuns) < DEFAULT_PER_PAGE, nil
+}
@@ -0,0 +1,187 @@
+package ghere
+
+import "context"
+
+const discussionCategoriesQuery = `query($owner: String!, $name: String!, $after: String) {
+  repository(owner: $owner, name: $name) {
+    discussionCategories(first: 100, after: $after) {
+      nodes { id name slug description emoji isAnswerable createdAt updatedAt }
+      pageInfo { hasNextPage endCursor }
+    }
+  }
+}`
+
+const discussionsQuery = `query($owner: String!, $name: String!, $after: String) {
+  repository(owner: $owner, name: $name) {
+    discussions(first: 100, after: $after, orderBy: {field: UPDATED_AT, direction: DESC}) {
+      nodes {
+        id number title body url closed locked upvoteCount createdAt updatedAt
+        author { login }
+        category { id name slug }
+        answer { id databaseId }
+        answerChosenAt
+        answerChosenBy { login }
+      }
+      pageInfo { hasNextPage endCursor }
+    }
+  }
+}`
+
+const discussionCommentFields = `fragment commentFields on DiscussionComment {
+  id databaseId body url isAnswer upvoteCount createdAt updatedAt
+  author { login }
+  replyTo { id databaseId }
+}`
+
+const discussionCommentsQuery = `query($owner: String!, $name: String!, $number: Int!, $after: String) {
+  repository(owner: $owner, name: $name) {
+    discussion(number: $number) {
+      comments(first: 100, after: $after) {
+        nodes {
+          ...commentFields
+          replies(first: 100) {
+            nodes { ...commentFields }
+            pageInfo { hasNextPage endCursor }
+          }
+        }
+        pageInfo { hasNextPage endCursor }
+      }
+    }
+  }
+}
+` + discussionCommentFields
+
+const discussionCommentRepliesQuery = `query($id: ID!, $after: String) {
+  node(id: $id) {
+    ... on DiscussionComment {
+      replies(first: 100, after: $after) {
+        nodes { ...commentFields }
+        pageInfo { hasNextPage endCursor }
+      }
+    }
+  }
+}
+` + discussionCommentFields
+
+type graphQLDiscussionCommentConnection struct {
+	Nodes    []*GitHubDiscussionComment `json:"nodes"`
+	PageInfo graphQLPageInfo            `json:"pageInfo"`
+}
+
+func (c *githubClient) ListDiscussionCategories(ctx context.Context, owner, name string) ([]*GitHubDiscussionCategory, error) {
+	categories := []*GitHubDiscussionCategory{}
+	c.log.Info("List discussion categories", "repo", owner+"/"+name)
+	for cursor := ""; ; {
+		var result struct {
+			Repository struct {
+				DiscussionCategories struct {
+					Nodes    []*GitHubDiscussionCategory `json:"nodes"`
+					PageInfo graphQLPageInfo             `json:"pageInfo"`
+				} `json:"discussionCategories"`
+			} `json:"repository"`
+		}
+		err := c.graphQL(ctx, "ListDiscussionCategories", discussionCategoriesQuery, map[string]interface{}{
+			"owner": owner,
+			"name":  name,
+			"after": optionalCursor(cursor),
+		}, &result)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, result.Repository.DiscussionCategories.Nodes...)
+		cursor = result.Repository.DiscussionCategories.PageInfo.nextCursor()
+		if len(cursor) == 0 {
+			return categories, nil
+		}
+	}
+}
+
+func (c *githubClient) ListDiscussions(ctx context.Context, owner, name string, cursor string) ([]*GitHubDiscussion, string, error) {
+	var result struct {
+		Repository struct {
+			Discussions struct {
+				Nodes    []*GitHubDiscussion `json:"nodes"`
+				PageInfo graphQLPageInfo     `json:"pageInfo"`
+			} `json:"discussions"`
+		} `json:"repository"`
+	}
+	c.log.Info("List discussions", "repo", owner+"/"+name, "cursor", cursor)
+	err := c.graphQL(ctx, "ListDiscussions", discussionsQuery, map[string]interface{}{
+		"owner": owner,
+		"name":  name,
+		"after": optionalCursor(cursor),
+	}, &result)
+	if err != nil {
+		return nil, "", err
+	}
+	return result.Repository.Discussions.Nodes, result.Repository.Discussions.PageInfo.nextCursor(), nil
+}
+
+func (c *githubClient) ListDiscussionComments(ctx context.Context, owner, name string, discussionNum int, cursor string) ([]*GitHubDiscussionComment, string, error) {
+	var result struct {
+		Repository struct {
+			Discussion struct {
+				Comments struct {
+					Nodes []*struct {
+						*GitHubDiscussionComment
+						Replies graphQLDiscussionCommentConnection `json:"replies"`
+					} `json:"nodes"`
+					PageInfo graphQLPageInfo `json:"pageInfo"`
+				} `json:"comments"`
+			} `json:"discussion"`
+		} `json:"repository"`
+	}
+	c.log.Info("List discussion comments", "repo", owner+"/"+name, "discussion", discussionNum, "cursor", cursor)
+	err := c.graphQL(ctx, "ListDiscussionComments", discussionCommentsQuery, map[string]interface{}{
+		"owner":  owner,
+		"name":   name,
+		"number": discussionNum,
+		"after":  optionalCursor(cursor),
+	}, &result)
+	if err != nil {
+		return nil, "", err
+	}
+	comments := make([]*GitHubDiscussionComment, 0, len(result.Repository.Discussion.Comments.Nodes))
+	for _, node := range result.Repository.Discussion.Comments.Nodes {
+		comment := node.GitHubDiscussionComment
+		comment.Replies = node.Replies.Nodes
+		for replyCursor := node.Replies.PageInfo.nextCursor(); len(replyCursor) > 0; {
+			var replies []*GitHubDiscussionComment
+			replies, replyCursor, err = c.listDiscussionCommentReplies(ctx, comment.ID, replyCursor)
+			if err != nil {
+				return nil, "", err
+			}
+			comment.Replies = append(comment.Replies, replies...)
+		}
+		comments = append(comments, comment)
+	}
+	return comments, result.Repository.Discussion.Comments.PageInfo.nextCursor(), nil
+}
+
+// listDiscussionCommentReplies lists the replies to the discussion comment
+// with the given node ID, for comments with more replies than are returned
+// along with the comment itself.
+func (c *githubClient) listDiscussionCommentReplies(ctx context.Context, commentID string, cursor string) ([]*GitHubDiscussionComment, string, error) {
+	var result struct {
+		Node struct {
+			Replies graphQLDiscussionCommentConnection `json:"replies"`
+		} `json:"node"`
+	}
+	c.log.Info("List discussion comment replies", "comment", commentID, "cursor", cursor)
+	err := c.graphQL(ctx, "ListDiscussionCommentReplies", discussionCommentRepliesQuery, map[string]interface{}{
+		"id":    commentID,
+		"after": optionalCursor(cursor),
+	}, &result)
+	if err != nil {
+		return nil, "", err
+	}
+	return result.Node.Replies.Nodes, result.Node.Replies.PageInfo.nextCursor(), nil
+}
+
+// optionalCursor converts an empty cursor into a null GraphQL variable.
+func optionalCursor(cursor string) interface{} {
+	if len(cursor) == 0 {
+		return nil
+	}
+	return cursor
+}
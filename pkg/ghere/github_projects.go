@@ -0,0 +1,161 @@
+package ghere
+
+import (
+	"context"
+	"fmt"
+)
+
+const projectFields = `fragment projectFields on ProjectV2 {
+  id number title shortDescription readme url closed public createdAt updatedAt
+  creator { login }
+  fields(first: 100) {
+    nodes {
+      __typename
+      ... on ProjectV2FieldCommon { id name dataType createdAt updatedAt }
+      ... on ProjectV2SingleSelectField { options { id name } }
+      ... on ProjectV2IterationField {
+        configuration {
+          duration startDay
+          iterations { id title startDate duration }
+          completedIterations { id title startDate duration }
+        }
+      }
+    }
+  }
+}`
+
+const organizationProjectQuery = `query($owner: String!, $number: Int!) {
+  organization(login: $owner) {
+    projectV2(number: $number) { ...projectFields }
+  }
+}
+` + projectFields
+
+const repositoryProjectQuery = `query($owner: String!, $name: String!, $number: Int!) {
+  repository(owner: $owner, name: $name) {
+    projectV2(number: $number) { ...projectFields }
+  }
+}
+` + projectFields
+
+const projectItemsQuery = `query($id: ID!, $after: String) {
+  node(id: $id) {
+    ... on ProjectV2 {
+      items(first: 100, after: $after) {
+        nodes {
+          id type isArchived createdAt updatedAt
+          content {
+            __typename
+            ... on Issue { number title url repository { nameWithOwner } }
+            ... on PullRequest { number title url repository { nameWithOwner } }
+            ... on DraftIssue { title body }
+          }
+          fieldValues(first: 50) {
+            nodes {
+              __typename
+              ... on ProjectV2ItemFieldTextValue { text field { ...fieldRef } }
+              ... on ProjectV2ItemFieldNumberValue { number field { ...fieldRef } }
+              ... on ProjectV2ItemFieldDateValue { date field { ...fieldRef } }
+              ... on ProjectV2ItemFieldSingleSelectValue { name optionId field { ...fieldRef } }
+              ... on ProjectV2ItemFieldIterationValue { title iterationId startDate duration field { ...fieldRef } }
+            }
+          }
+        }
+        pageInfo { hasNextPage endCursor }
+      }
+    }
+  }
+}
+fragment fieldRef on ProjectV2FieldConfiguration {
+  ... on ProjectV2FieldCommon { id name }
+}`
+
+// graphQLProject is the shape of a project returned by the GraphQL API.
+type graphQLProject struct {
+	*GitHubProjectV2
+	Fields struct {
+		Nodes []*GitHubProjectV2Field `json:"nodes"`
+	} `json:"fields"`
+}
+
+func (c *githubClient) GetProject(ctx context.Context, owner, name string, number int) (*GitHubProjectV2, []*GitHubProjectV2Field, error) {
+	var project *graphQLProject
+	vars := map[string]interface{}{
+		"owner":  owner,
+		"number": number,
+	}
+	if len(name) == 0 {
+		var result struct {
+			Organization struct {
+				ProjectV2 *graphQLProject `json:"projectV2"`
+			} `json:"organization"`
+		}
+		c.log.Info("Get organization project", "owner", owner, "project", number)
+		if err := c.graphQL(ctx, "GetProject", organizationProjectQuery, vars, &result); err != nil {
+			return nil, nil, err
+		}
+		project = result.Organization.ProjectV2
+	} else {
+		var result struct {
+			Repository struct {
+				ProjectV2 *graphQLProject `json:"projectV2"`
+			} `json:"repository"`
+		}
+		vars["name"] = name
+		c.log.Info("Get repository project", "repo", owner+"/"+name, "project", number)
+		if err := c.graphQL(ctx, "GetProject", repositoryProjectQuery, vars, &result); err != nil {
+			return nil, nil, err
+		}
+		project = result.Repository.ProjectV2
+	}
+	if project == nil || project.GitHubProjectV2 == nil {
+		return nil, nil, fmt.Errorf("no such project: %d", number)
+	}
+	// Fields of types we do not query (and that therefore have no ID) are not
+	// returned.
+	fields := []*GitHubProjectV2Field{}
+	for _, field := range project.Fields.Nodes {
+		if len(field.ID) > 0 {
+			fields = append(fields, field)
+		}
+	}
+	return project.GitHubProjectV2, fields, nil
+}
+
+func (c *githubClient) ListProjectItems(ctx context.Context, projectID string, cursor string) ([]*GitHubProjectV2Item, string, error) {
+	var result struct {
+		Node struct {
+			Items struct {
+				Nodes []*struct {
+					*GitHubProjectV2Item
+					FieldValues struct {
+						Nodes []*GitHubProjectV2ItemFieldValue `json:"nodes"`
+					} `json:"fieldValues"`
+				} `json:"nodes"`
+				PageInfo graphQLPageInfo `json:"pageInfo"`
+			} `json:"items"`
+		} `json:"node"`
+	}
+	c.log.Info("List project items", "project", projectID, "cursor", cursor)
+	err := c.graphQL(ctx, "ListProjectItems", projectItemsQuery, map[string]interface{}{
+		"id":    projectID,
+		"after": optionalCursor(cursor),
+	}, &result)
+	if err != nil {
+		return nil, "", err
+	}
+	items := make([]*GitHubProjectV2Item, 0, len(result.Node.Items.Nodes))
+	for _, node := range result.Node.Items.Nodes {
+		item := node.GitHubProjectV2Item
+		// Values of types we do not query (e.g. labels, which are already
+		// available from the linked issue/pull request) are omitted.
+		item.FieldValues = []*GitHubProjectV2ItemFieldValue{}
+		for _, value := range node.FieldValues.Nodes {
+			if value.Field != nil {
+				item.FieldValues = append(item.FieldValues, value)
+			}
+		}
+		items = append(items, item)
+	}
+	return items, result.Node.Items.PageInfo.nextCursor(), nil
+}
@@ -0,0 +1,145 @@
+package ghere
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v48/github"
+)
+
+// graphQLRequest is the body of a request to GitHub's GraphQL API.
+type graphQLRequest struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables,omitempty"`
+}
+
+// graphQLResponse is the body of a response from GitHub's GraphQL API.
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors graphQLErrors   `json:"errors,omitempty"`
+}
+
+type graphQLError struct {
+	Type    string `json:"type,omitempty"`
+	Message string `json:"message"`
+}
+
+// graphQLErrors are returned by GitHub's GraphQL API along with a successful
+// HTTP status code.
+type graphQLErrors []graphQLError
+
+// The type of error returned by GitHub's GraphQL API when the primary rate
+// limit has been exceeded.
+const graphQLErrorTypeRateLimited string = "RATE_LIMITED"
+
+func (e graphQLErrors) isRateLimited() bool {
+	for _, err := range e {
+		if err.Type == graphQLErrorTypeRateLimited {
+			return true
+		}
+	}
+	return false
+}
+
+func (e graphQLErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Message)
+	}
+	return "GraphQL request failed: " + strings.Join(msgs, "; ")
+}
+
+// graphQLPageInfo provides the information necessary to paginate through a
+// GraphQL connection.
+type graphQLPageInfo struct {
+	HasNextPage bool   `json:"hasNextPage"`
+	EndCursor   string `json:"endCursor"`
+}
+
+// nextCursor returns the cursor from which to fetch the next page, or an
+// empty string if there are no more pages.
+func (p graphQLPageInfo) nextCursor() string {
+	if !p.HasNextPage {
+		return ""
+	}
+	return p.EndCursor
+}
+
+// graphQLURL derives the URL of the GraphQL API endpoint from the base URL of
+// the REST API. GitHub Enterprise Server instances serve the REST API from
+// "/api/v3/" and the GraphQL API from "/api/graphql".
+func graphQLURL(baseURL *url.URL) string {
+	if strings.HasSuffix(baseURL.Path, "/api/v3/") {
+		u := *baseURL
+		u.Path = strings.TrimSuffix(u.Path, "v3/") + "graphql"
+		return u.String()
+	}
+	return baseURL.ResolveReference(&url.URL{Path: "graphql"}).String()
+}
+
+// graphQL executes the given GraphQL query, decoding the response's data into
+// result. Requests are subject to the same rate limiting and retry handling as
+// REST API requests, but count towards the GraphQL API's own primary rate
+// limit.
+func (c *githubClient) graphQL(ctx context.Context, endpoint, query string, vars map[string]interface{}, result interface{}) error {
+	return c.callRateLimitedResource(ctx, rateLimitResourceGraphQL, endpoint, func(cx context.Context, client *github.Client) (*github.Response, error) {
+		req, err := client.NewRequest(http.MethodPost, graphQLURL(client.BaseURL), &graphQLRequest{
+			Query:     query,
+			Variables: vars,
+		})
+		if err != nil {
+			return nil, err
+		}
+		// We bypass client.Do, which would otherwise record the GraphQL API's
+		// rate limit status as that of the REST API, and subsequently refuse
+		// to make REST API requests once the GraphQL API's quota is exhausted.
+		httpRes, err := client.Client().Do(req.WithContext(cx))
+		if err != nil {
+			return nil, err
+		}
+		defer httpRes.Body.Close()
+		res := &github.Response{Response: httpRes, Rate: parseRate(httpRes)}
+		if err := github.CheckResponse(httpRes); err != nil {
+			return res, err
+		}
+		var body graphQLResponse
+		if err := json.NewDecoder(httpRes.Body).Decode(&body); err != nil {
+			return res, fmt.Errorf("failed to decode GraphQL response: %v", err)
+		}
+		if len(body.Errors) > 0 {
+			// Hitting the GraphQL API's primary rate limit results in a
+			// successful HTTP status code.
+			if body.Errors.isRateLimited() {
+				return res, &github.RateLimitError{Rate: res.Rate, Response: httpRes, Message: body.Errors.Error()}
+			}
+			return res, body.Errors
+		}
+		if err := json.Unmarshal(body.Data, result); err != nil {
+			return res, fmt.Errorf("failed to decode GraphQL response: %v", err)
+		}
+		return res, nil
+	})
+}
+
+// parseRate extracts the rate limit status from the given response's headers.
+func parseRate(res *http.Response) github.Rate {
+	var rate github.Rate
+	if limit := res.Header.Get("X-RateLimit-Limit"); len(limit) > 0 {
+		rate.Limit, _ = strconv.Atoi(limit)
+	}
+	if remaining := res.Header.Get("X-RateLimit-Remaining"); len(remaining) > 0 {
+		rate.Remaining, _ = strconv.Atoi(remaining)
+	}
+	if reset := res.Header.Get("X-RateLimit-Reset"); len(reset) > 0 {
+		if secs, err := strconv.ParseInt(reset, 10, 64); err == nil && secs != 0 {
+			rate.Reset = github.Timestamp{Time: time.Unix(secs, 0)}
+		}
+	}
+	return rate
+}
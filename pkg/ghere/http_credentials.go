@@ -0,0 +1,130 @@
+package ghere
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"golang.org/x/oauth2"
+)
+
+const (
+	DEFAULT_GITHUB_HOST string = "github.com"
+	// The username to use for HTTP(S) basic authentication when no username is
+	// specified. GitHub ignores the username when authenticating using a
+	// token.
+	DEFAULT_TOKEN_USERNAME string = "x-access-token"
+)
+
+// HTTPCredentialConfig configures the way in which HTTP(S) credentials are
+// obtained for cloning/pulling Git repositories. Sources of credentials are
+// attempted in the following order:
+//
+//  1. The GITHUB_PASSWORD environment variable.
+//  2. The token provided by TokenSource (e.g. the GitHub API token).
+//  3. Git credential helpers (via "git credential fill"), if enabled.
+type HTTPCredentialConfig struct {
+	// Host is the host for which to obtain credentials from Git credential
+	// helpers. Defaults to DEFAULT_GITHUB_HOST.
+	Host string
+	// Username is the username to use alongside passwords/tokens. It is
+	// required when using GITHUB_PASSWORD, and defaults to
+	// DEFAULT_TOKEN_USERNAME when using a token.
+	Username string
+	// TokenSource, if not nil, provides the token to use as a password when
+	// GITHUB_PASSWORD is not set.
+	TokenSource oauth2.TokenSource
+	// UseCredentialHelper, if true, will attempt to obtain credentials from
+	// the user's configured Git credential helpers.
+	UseCredentialHelper bool
+	// Log, if not nil, is used to log why credentials could not be obtained
+	// from Git credential helpers.
+	Log Logger
+}
+
+// getBasicAuth returns the first set of usable HTTP(S) credentials, or nil if
+// no usable credentials could be found.
+func (cfg *HTTPCredentialConfig) getBasicAuth(ctx context.Context) (*http.BasicAuth, error) {
+	if password := os.Getenv(GITHUB_PASSWORD_ENVVAR); len(password) > 0 {
+		if len(cfg.Username) == 0 {
+			return nil, fmt.Errorf("a username must be specified (e.g. via --login) when authenticating using %s", GITHUB_PASSWORD_ENVVAR)
+		}
+		return &http.BasicAuth{
+			Username: cfg.Username,
+			Password: password,
+		}, nil
+	}
+	username := cfg.Username
+	if len(username) == 0 {
+		username = DEFAULT_TOKEN_USERNAME
+	}
+	if cfg.TokenSource != nil {
+		token, err := cfg.TokenSource.Token()
+		if err != nil {
+			return nil, fmt.Errorf("failed to obtain token for HTTP(S) authentication: %v", err)
+		}
+		if len(token.AccessToken) > 0 {
+			return &http.BasicAuth{
+				Username: username,
+				Password: token.AccessToken,
+			}, nil
+		}
+	}
+	if cfg.UseCredentialHelper {
+		host := cfg.Host
+		if len(host) == 0 {
+			host = DEFAULT_GITHUB_HOST
+		}
+		log := cfg.Log
+		if log == nil {
+			log = NewNoopLogger()
+		}
+		return gitCredentialFill(ctx, host, log), nil
+	}
+	return nil, nil
+}
+
+// gitCredentialFill attempts to obtain credentials for the given host from
+// the user's Git credential helpers. Returns nil if no credentials could be
+// obtained.
+func gitCredentialFill(ctx context.Context, host string, log Logger) *http.BasicAuth {
+	cmd := exec.CommandContext(ctx, "git", "credential", "fill")
+	cmd.Stdin = strings.NewReader("protocol=https\nhost=" + host + "\n\n")
+	// Prevent Git from interactively prompting the user for credentials.
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_ASKPASS=")
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			log.Debug("Failed to obtain credentials from Git credential helpers", "host", host, "err", err, "stderr", strings.TrimSpace(string(exitErr.Stderr)))
+		} else {
+			log.Debug("Failed to obtain credentials from Git credential helpers", "host", host, "err", err)
+		}
+		return nil
+	}
+	auth := &http.BasicAuth{}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), "=")
+		if !found {
+			continue
+		}
+		switch key {
+		case "username":
+			auth.Username = value
+		case "password":
+			auth.Password = value
+		}
+	}
+	if len(auth.Password) == 0 {
+		log.Debug("Git credential helpers provided no password", "host", host)
+		return nil
+	}
+	return auth
+}
@@ -0,0 +1,90 @@
+package ghere_test
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/informalsystems/ghere/pkg/ghere"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestHTTPCredentialPrecedence(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	// Isolate Git from the user's configuration, configuring a credential
+	// helper that only provides credentials for github.com.
+	tmpDir := t.TempDir()
+	helper := filepath.Join(tmpDir, "helper.sh")
+	require.NoError(t, os.WriteFile(helper, []byte(`#!/bin/sh
+test "$1" = get || exit 0
+grep -q '^host=github.com$' && printf 'username=helper\npassword=helper-secret\n'
+exit 0
+`), 0o755))
+	gitConfig := filepath.Join(tmpDir, "gitconfig")
+	require.NoError(t, os.WriteFile(gitConfig, []byte("[credential]\n\thelper = "+helper+"\n"), 0o644))
+	t.Setenv("HOME", tmpDir)
+	t.Setenv("GIT_CONFIG_GLOBAL", gitConfig)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	token := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "token"})
+	emptyToken := oauth2.StaticTokenSource(&oauth2.Token{})
+	testCases := []struct {
+		name      string
+		password  string
+		cfg       *ghere.HTTPCredentialConfig
+		expected  *http.BasicAuth
+		expectErr bool
+	}{
+		{
+			name:     "password",
+			password: "password",
+			cfg:      &ghere.HTTPCredentialConfig{Username: "user", TokenSource: token, UseCredentialHelper: true},
+			expected: &http.BasicAuth{Username: "user", Password: "password"},
+		},
+		{
+			name:      "password without username",
+			password:  "password",
+			cfg:       &ghere.HTTPCredentialConfig{TokenSource: token, UseCredentialHelper: true},
+			expectErr: true,
+		},
+		{
+			name:     "token",
+			cfg:      &ghere.HTTPCredentialConfig{TokenSource: token, UseCredentialHelper: true},
+			expected: &http.BasicAuth{Username: ghere.DEFAULT_TOKEN_USERNAME, Password: "token"},
+		},
+		{
+			name:     "credential helper",
+			cfg:      &ghere.HTTPCredentialConfig{TokenSource: emptyToken, UseCredentialHelper: true},
+			expected: &http.BasicAuth{Username: "helper", Password: "helper-secret"},
+		},
+		{
+			name: "credential helper disabled",
+			cfg:  &ghere.HTTPCredentialConfig{TokenSource: emptyToken},
+		},
+		{
+			name: "no credentials from helper",
+			cfg:  &ghere.HTTPCredentialConfig{Host: "ghe.example.com", UseCredentialHelper: true},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv(ghere.GITHUB_PASSWORD_ENVVAR, tc.password)
+			cp, err := ghere.NewGitHubSSHCredentialProvider(&ghere.SSHCredentialConfig{}, tc.cfg)
+			require.NoError(t, err)
+			creds, err := cp.GetGitHubCredentials(context.Background(), "org")
+			if tc.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, creds.BasicAuth)
+		})
+	}
+}
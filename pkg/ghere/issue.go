@@ -14,6 +14,12 @@ type Issue struct {
 
 	LastDetailFetch   time.Time `json:"last_detail_fetch"`
 	LastCommentsFetch time.Time `json:"last_comments_fetch"`
+	LastEventsFetch   time.Time `json:"last_events_fetch"`
+	// Reactions optionally lists the individual reactions to the issue.
+	Reactions []*github.Reaction `json:"reactions,omitempty"`
+	// Deleted is true if the issue was deleted on GitHub (as reported by a
+	// webhook event).
+	Deleted bool `json:"deleted,omitempty"`
 }
 
 func LoadIssue(rootPath string, repo *Repository, issueNum int, mustExist bool) (*Issue, error) {
@@ -52,53 +58,74 @@ func (i *Issue) MustUpdateComments() bool {
 	return !i.Issue.IsPullRequest() && i.Issue.GetUpdatedAt().After(i.LastCommentsFetch)
 }
 
+// MustFetchEvents returns whether the issue's timeline events must be fetched.
+// The timelines of pull requests are fetched along with the pull requests
+// themselves.
+func (i *Issue) MustFetchEvents() bool {
+	return !i.Issue.IsPullRequest() && i.Issue.GetUpdatedAt().After(i.LastEventsFetch)
+}
+
 type issuesFetcher struct {
 	rootPath string
 	repo     *Repository
+	// Whether to fetch issues' timeline events.
+	timelines bool
 }
 
 var _ fetcher = (*issuesFetcher)(nil)
+var _ planner = (*issuesFetcher)(nil)
 
-func newIssuesFetcher(rootPath string, repo *Repository) *issuesFetcher {
+func newIssuesFetcher(rootPath string, repo *Repository, timelines bool) *issuesFetcher {
 	return &issuesFetcher{
-		rootPath: rootPath,
-		repo:     repo,
+		rootPath:  rootPath,
+		repo:      repo,
+		timelines: timelines,
 	}
 }
 
+func (f *issuesFetcher) resource() string {
+	return RESOURCE_ISSUES
+}
+
 func (f *issuesFetcher) fetch(ctx context.Context, cfg *FetchConfig, log Logger) ([]fetcher, error) {
-	issuesPath := repoIssuesPath(f.rootPath, f.repo.GetOwner(), f.repo.GetName())
-	pattern := filepath.Join(issuesPath, "*", DETAIL_FILENAME)
-	startPage, err := paginatedItemsStartPage(pattern, func(fn string) (bool, error) {
-		issue, err := LoadIssueDirect(fn, true)
-		if err != nil {
-			return false, err
-		}
-		repoUpdatedRecently := f.repo.Repository.GetUpdatedAt().After(issue.LastDetailFetch)
-		fetchedMoreThan24hAgo := time.Since(issue.LastDetailFetch) > 24*time.Hour
-		return repoUpdatedRecently && fetchedMoreThan24hAgo, nil
-	})
-	if err != nil {
-		return nil, err
-	}
-	done := false
-	for page := startPage; !done; page++ {
-		var issues []*github.Issue
-		issues, done, err = cfg.Client.ListRepositoryIssues(ctx, f.repo.GetOwner(), f.repo.GetName(), page)
-		if err != nil {
-			return nil, err
-		}
-		for _, ghIssue := range issues {
+	seen := make(map[int64]bool)
+	fromStart, err := fetchPaginated(
+		f.rootPath,
+		f.repo,
+		"issues",
+		cfg,
+		log,
+		func(page int) ([]*github.Issue, bool, error) {
+			return cfg.Client.ListRepositoryIssues(ctx, f.repo.GetOwner(), f.repo.GetName(), page)
+		},
+		func(ghIssue *github.Issue) int64 {
+			return int64(ghIssue.GetNumber())
+		},
+		func(ghIssue *github.Issue) error {
 			issue, err := LoadIssue(f.rootPath, f.repo, ghIssue.GetNumber(), false)
 			if err != nil {
-				return nil, err
+				return err
 			}
+			seen[int64(ghIssue.GetNumber())] = true
+			cfg.report.recordItem(RESOURCE_ISSUES, issue.Issue != nil, issue.Issue, ghIssue)
 			issue.Issue = ghIssue
 			issue.LastDetailFetch = time.Now()
-			if err := issue.Save(f.rootPath, f.repo, cfg.PrettyJSON); err != nil {
-				return nil, err
+			if err := issue.fetchReactions(ctx, cfg, f.repo); err != nil {
+				return err
 			}
+			return issue.Save(f.rootPath, f.repo, cfg.PrettyJSON)
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if fromStart {
+		issuesPath := repoIssuesPath(f.rootPath, f.repo.GetOwner(), f.repo.GetName())
+		deleted, err := countDeletedItems(filepath.Join(issuesPath, "*", DETAIL_FILENAME), seen, filepath.Dir)
+		if err != nil {
+			return nil, err
 		}
+		cfg.report.recordDeleted(RESOURCE_ISSUES, deleted)
 	}
 	log.Info("Fetched all issues' details", "repo", f.repo.String())
 
@@ -107,33 +134,78 @@ func (f *issuesFetcher) fetch(ctx context.Context, cfg *FetchConfig, log Logger)
 		return nil, err
 	}
 
-	return f.makeCommentsFetcher(log)
+	return f.makeCommentsAndEventsFetchers(log)
+}
+
+func (f *issuesFetcher) makeCommentsAndEventsFetchers(log Logger) ([]fetcher, error) {
+	log.Info("Computing which issues' comments and events should be fetched", "repo", f.repo.String())
+	fetchComments, fetchEvents, err := f.issuesWithStaleCommentsAndEvents()
+	if err != nil {
+		return nil, err
+	}
+
+	fetchers := []fetcher{}
+	if len(fetchComments) > 0 {
+		fetchers = append(fetchers, newIssueCommentsFetcher(f.rootPath, f.repo, fetchComments))
+	}
+	if len(fetchEvents) > 0 {
+		fetchers = append(fetchers, newIssueEventsFetcher(f.rootPath, f.repo, fetchEvents))
+	}
+
+	return fetchers, nil
 }
 
-func (f *issuesFetcher) makeCommentsFetcher(log Logger) ([]fetcher, error) {
-	log.Info("Computing which issues' comments should be fetched", "repo", f.repo.String())
+// issuesWithStaleCommentsAndEvents returns the local issues whose comments and
+// timeline events (respectively) must be fetched. No issues' timeline events
+// are returned if timelines are not being fetched.
+func (f *issuesFetcher) issuesWithStaleCommentsAndEvents() ([]*Issue, []*Issue, error) {
 	fetchComments := []*Issue{}
+	fetchEvents := []*Issue{}
 	issuesPath := repoIssuesPath(f.rootPath, f.repo.GetOwner(), f.repo.GetName())
 	pattern := filepath.Join(issuesPath, "*", DETAIL_FILENAME)
 	issueDetailFiles, err := filepath.Glob(pattern)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list issues' detail files from pattern %s: %v", pattern, err)
+		return nil, nil, fmt.Errorf("failed to list issues' detail files from pattern %s: %v", pattern, err)
 	}
 
 	for _, fn := range issueDetailFiles {
 		issue, err := LoadIssueDirect(fn, true)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		if issue.MustUpdateComments() {
 			fetchComments = append(fetchComments, issue)
 		}
+		if f.timelines && issue.MustFetchEvents() {
+			fetchEvents = append(fetchEvents, issue)
+		}
 	}
+	return fetchComments, fetchEvents, nil
+}
 
-	fetchers := []fetcher{}
-	if len(fetchComments) > 0 {
-		fetchers = append(fetchers, newIssueCommentsFetcher(f.rootPath, f.repo, fetchComments))
+func (f *issuesFetcher) plan(cfg *FetchConfig) (*FetcherPlan, error) {
+	issuesPath := repoIssuesPath(f.rootPath, f.repo.GetOwner(), f.repo.GetName())
+	count, err := countLocalItems(filepath.Join(issuesPath, "*", DETAIL_FILENAME))
+	if err != nil {
+		return nil, err
 	}
-
-	return fetchers, nil
+	cps, err := LoadFetchCheckpoints(f.rootPath, f.repo.GetOwner(), f.repo.GetName())
+	if err != nil {
+		return nil, err
+	}
+	pages := estimatePages(count, cps.fresh("issues"))
+	fetchComments, fetchEvents, err := f.issuesWithStaleCommentsAndEvents()
+	if err != nil {
+		return nil, err
+	}
+	return &FetcherPlan{
+		Resource: RESOURCE_ISSUES,
+		Description: fmt.Sprintf(
+			"list issues (~%d page(s)), then fetch comments for at least %d and events for at least %d issue(s)",
+			pages,
+			len(fetchComments),
+			len(fetchEvents),
+		),
+		EstimatedAPICalls: pages + len(fetchComments) + len(fetchEvents),
+	}, nil
 }
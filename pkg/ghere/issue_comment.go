@@ -10,6 +10,11 @@ import (
 
 type IssueComment struct {
 	Comment *github.IssueComment `json:"comment"`
+	// Reactions optionally lists the individual reactions to the comment.
+	Reactions []*github.Reaction `json:"reactions,omitempty"`
+	// Deleted is true if the comment was deleted on GitHub (as reported by a
+	// webhook event).
+	Deleted bool `json:"deleted,omitempty"`
 }
 
 func LoadIssueComment(rootPath string, repo *Repository, issueNum int, commentID int64, mustExist bool) (*IssueComment, error) {
@@ -55,6 +60,10 @@ func newIssueCommentsFetcher(rootPath string, repo *Repository, issues []*Issue)
 	}
 }
 
+func (f *issueCommentsFetcher) resource() string {
+	return RESOURCE_ISSUE_COMMENTS
+}
+
 func (f *issueCommentsFetcher) fetch(ctx context.Context, cfg *FetchConfig, log Logger) ([]fetcher, error) {
 	for _, issue := range f.issues {
 		done := false
@@ -72,8 +81,14 @@ func (f *issueCommentsFetcher) fetch(ctx context.Context, cfg *FetchConfig, log
 				return nil, err
 			}
 			for _, ghComment := range comments {
-				comment := &IssueComment{
-					Comment: ghComment,
+				comment, err := LoadIssueComment(f.rootPath, f.repo, issue.GetNumber(), ghComment.GetID(), false)
+				if err != nil {
+					return nil, err
+				}
+				cfg.report.recordItem(RESOURCE_ISSUE_COMMENTS, comment.Comment != nil, comment.Comment, ghComment)
+				comment.Comment = ghComment
+				if err := comment.fetchReactions(ctx, cfg, f.repo); err != nil {
+					return nil, err
 				}
 				if err := comment.Save(f.rootPath, f.repo, issue.GetNumber(), cfg.PrettyJSON); err != nil {
 					return nil, err
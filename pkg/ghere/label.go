@@ -3,6 +3,7 @@ package ghere
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 	"time"
 
 	"github.com/google/go-github/v48/github"
@@ -10,6 +11,9 @@ import (
 
 type Label struct {
 	Label *github.Label
+	// Deleted is true if the label was deleted on GitHub (as reported by a
+	// webhook event).
+	Deleted bool `json:"deleted,omitempty"`
 }
 
 func LoadLabel(rootPath string, repo *Repository, labelID int64, mustExist bool) (*Label, error) {
@@ -41,6 +45,7 @@ type labelsFetcher struct {
 }
 
 var _ fetcher = (*labelsFetcher)(nil)
+var _ planner = (*labelsFetcher)(nil)
 
 func newLabelsFetcher(rootPath string, repo *Repository) *labelsFetcher {
 	return &labelsFetcher{
@@ -49,9 +54,14 @@ func newLabelsFetcher(rootPath string, repo *Repository) *labelsFetcher {
 	}
 }
 
+func (f *labelsFetcher) resource() string {
+	return RESOURCE_LABELS
+}
+
 func (f *labelsFetcher) fetch(ctx context.Context, cfg *FetchConfig, log Logger) ([]fetcher, error) {
 	var labels []*github.Label
 	var err error
+	seen := make(map[int64]bool)
 	done := false
 	for page := 1; !done; page++ {
 		labels, done, err = cfg.Client.ListRepositoryLabels(
@@ -64,17 +74,41 @@ func (f *labelsFetcher) fetch(ctx context.Context, cfg *FetchConfig, log Logger)
 			return nil, err
 		}
 		for _, ghLabel := range labels {
-			label := &Label{
-				Label: ghLabel,
+			label, err := LoadLabel(f.rootPath, f.repo, ghLabel.GetID(), false)
+			if err != nil {
+				return nil, err
 			}
+			seen[ghLabel.GetID()] = true
+			cfg.report.recordItem(RESOURCE_LABELS, label.Label != nil, label.Label, ghLabel)
+			label.Label = ghLabel
 			if err := label.Save(f.rootPath, f.repo, cfg.PrettyJSON); err != nil {
 				return nil, err
 			}
 		}
 	}
+	labelsPath := repoLabelsPath(f.rootPath, f.repo.GetOwner(), f.repo.GetName())
+	deleted, err := countDeletedItems(filepath.Join(labelsPath, "*.json"), seen, func(path string) string { return path })
+	if err != nil {
+		return nil, err
+	}
+	cfg.report.recordDeleted(RESOURCE_LABELS, deleted)
 	f.repo.LastLabelsFetch = time.Now()
 	if err := f.repo.Save(f.rootPath, cfg.PrettyJSON); err != nil {
 		return nil, err
 	}
 	return nil, nil
 }
+
+func (f *labelsFetcher) plan(cfg *FetchConfig) (*FetcherPlan, error) {
+	labelsPath := repoLabelsPath(f.rootPath, f.repo.GetOwner(), f.repo.GetName())
+	count, err := countLocalItems(filepath.Join(labelsPath, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	pages := estimatePages(count, nil)
+	return &FetcherPlan{
+		Resource:          RESOURCE_LABELS,
+		Description:       fmt.Sprintf("list labels (~%d page(s))", pages),
+		EstimatedAPICalls: pages,
+	}, nil
+}
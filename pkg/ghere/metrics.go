@@ -0,0 +1,231 @@
+package ghere
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Upper bounds (in seconds) of the buckets of the repository fetch duration
+// histogram.
+var fetchDurationBuckets = []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800, 3600}
+
+// Metrics collects metrics about fetch operations and the GitHub clients used
+// to perform them, and exposes them in the Prometheus text exposition format,
+// either via HTTP (it implements [http.Handler]) or by writing them to a file
+// for consumption by the node exporter's textfile collector.
+type Metrics struct {
+	mtx     sync.Mutex
+	clients map[string]GitHubClientStatsProvider
+	// Keyed by repository, then resource type.
+	itemsWritten map[string]map[string]float64
+	// Keyed by repository, then error type.
+	fetchFailures  map[string]map[string]float64
+	fetchDurations map[string]*histogram
+	// Keyed by repository.
+	lastSuccess map[string]float64
+}
+
+type histogram struct {
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+var _ http.Handler = (*Metrics)(nil)
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		clients:        make(map[string]GitHubClientStatsProvider),
+		itemsWritten:   make(map[string]map[string]float64),
+		fetchFailures:  make(map[string]map[string]float64),
+		fetchDurations: make(map[string]*histogram),
+		lastSuccess:    make(map[string]float64),
+	}
+}
+
+// RegisterClient registers a GitHub client whose request statistics must be
+// exposed, labelled with the given host. Clients that do not keep track of
+// statistics are ignored.
+func (m *Metrics) RegisterClient(host string, client GitHubClient) {
+	sp, ok := client.(GitHubClientStatsProvider)
+	if !ok {
+		return
+	}
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.clients[host] = sp
+}
+
+// observeRepository updates the metrics from the report of a fetch of a
+// single repository. Does nothing on nil metrics.
+func (m *Metrics) observeRepository(report *RepositoryReport) {
+	if m == nil {
+		return
+	}
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	repo := report.Repository
+	if _, exists := m.itemsWritten[repo]; !exists {
+		m.itemsWritten[repo] = make(map[string]float64)
+	}
+	for resource, rr := range report.Resources {
+		m.itemsWritten[repo][resource] += float64(rr.Created + rr.Updated)
+	}
+	h, exists := m.fetchDurations[repo]
+	if !exists {
+		h = &histogram{
+			buckets: fetchDurationBuckets,
+			counts:  make([]uint64, len(fetchDurationBuckets)),
+		}
+		m.fetchDurations[repo] = h
+	}
+	h.observe(report.DurationSeconds)
+	if len(report.Error) > 0 {
+		if _, exists := m.fetchFailures[repo]; !exists {
+			m.fetchFailures[repo] = make(map[string]float64)
+		}
+		m.fetchFailures[repo][report.ErrorType]++
+	} else {
+		m.lastSuccess[repo] = float64(report.FinishedAt.Unix())
+	}
+}
+
+func (h *histogram) observe(v float64) {
+	for i, upperBound := range h.buckets {
+		if v <= upperBound {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// ServeHTTP implements http.Handler.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if _, err := m.WriteTo(w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// WriteTextfile atomically writes the metrics to the given file, e.g. for
+// consumption by the node exporter's textfile collector.
+func (m *Metrics) WriteTextfile(path string) error {
+	buf := &bytes.Buffer{}
+	if _, err := m.WriteTo(buf); err != nil {
+		return err
+	}
+	return writeFile(path, buf.Bytes())
+}
+
+// WriteTo writes the metrics to the given writer in the Prometheus text
+// exposition format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	e := &expositionWriter{}
+
+	hosts := sortedKeys(m.clients)
+	stats := make(map[string]GitHubClientStats, len(hosts))
+	for _, host := range hosts {
+		stats[host] = m.clients[host].Stats()
+	}
+	e.header("ghere_github_requests_total", "counter", "Total number of requests made to the GitHub API, including retries.")
+	for _, host := range hosts {
+		for _, endpoint := range sortedKeys(stats[host].RequestsByEndpoint) {
+			e.sample("ghere_github_requests_total", labels("host", host, "endpoint", endpoint), float64(stats[host].RequestsByEndpoint[endpoint]))
+		}
+	}
+	e.header("ghere_github_retries_total", "counter", "Total number of retried requests to the GitHub API.")
+	for _, host := range hosts {
+		for _, endpoint := range sortedKeys(stats[host].RetriesByEndpoint) {
+			e.sample("ghere_github_retries_total", labels("host", host, "endpoint", endpoint), float64(stats[host].RetriesByEndpoint[endpoint]))
+		}
+	}
+	e.header("ghere_github_rate_limit", "gauge", "Total primary rate limit across all tokens.")
+	for _, host := range hosts {
+		e.sample("ghere_github_rate_limit", labels("host", host), float64(stats[host].RateLimit))
+	}
+	e.header("ghere_github_rate_limit_remaining", "gauge", "Total remaining primary rate limit quota across all tokens.")
+	for _, host := range hosts {
+		e.sample("ghere_github_rate_limit_remaining", labels("host", host), float64(stats[host].RateLimitRemaining))
+	}
+
+	repos := sortedKeys(m.itemsWritten)
+	e.header("ghere_items_written_total", "counter", "Total number of items created or updated, by repository and resource type.")
+	for _, repo := range repos {
+		for _, resource := range sortedKeys(m.itemsWritten[repo]) {
+			e.sample("ghere_items_written_total", labels("repo", repo, "resource", resource), m.itemsWritten[repo][resource])
+		}
+	}
+	e.header("ghere_repository_fetch_failures_total", "counter", "Total number of failed repository fetches, by repository and error type.")
+	for _, repo := range sortedKeys(m.fetchFailures) {
+		for _, errorType := range sortedKeys(m.fetchFailures[repo]) {
+			e.sample("ghere_repository_fetch_failures_total", labels("repo", repo, "error_type", errorType), m.fetchFailures[repo][errorType])
+		}
+	}
+	e.header("ghere_repository_fetch_duration_seconds", "histogram", "Duration of repository fetches.")
+	for _, repo := range sortedKeys(m.fetchDurations) {
+		h := m.fetchDurations[repo]
+		for i, upperBound := range h.buckets {
+			e.sample("ghere_repository_fetch_duration_seconds_bucket", labels("repo", repo, "le", formatFloat(upperBound)), float64(h.counts[i]))
+		}
+		e.sample("ghere_repository_fetch_duration_seconds_bucket", labels("repo", repo, "le", "+Inf"), float64(h.count))
+		e.sample("ghere_repository_fetch_duration_seconds_sum", labels("repo", repo), h.sum)
+		e.sample("ghere_repository_fetch_duration_seconds_count", labels("repo", repo), float64(h.count))
+	}
+	e.header("ghere_repository_last_successful_fetch_timestamp_seconds", "gauge", "Unix timestamp of the last successful fetch of each repository.")
+	for _, repo := range sortedKeys(m.lastSuccess) {
+		e.sample("ghere_repository_last_successful_fetch_timestamp_seconds", labels("repo", repo), m.lastSuccess[repo])
+	}
+
+	n, err := w.Write(e.buf.Bytes())
+	return int64(n), err
+}
+
+type expositionWriter struct {
+	buf bytes.Buffer
+}
+
+func (e *expositionWriter) header(name, metricType, help string) {
+	fmt.Fprintf(&e.buf, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+func (e *expositionWriter) sample(name, labels string, value float64) {
+	fmt.Fprintf(&e.buf, "%s{%s} %s\n", name, labels, formatFloat(value))
+}
+
+// labels formats the given label name/value pairs.
+func labels(nameValues ...string) string {
+	pairs := make([]string, 0, len(nameValues)/2)
+	for i := 0; i+1 < len(nameValues); i += 2 {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", nameValues[i], escapeLabelValue(nameValues[i+1])))
+	}
+	return strings.Join(pairs, ",")
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(v string) string {
+	return labelValueEscaper.Replace(v)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -3,8 +3,13 @@ package ghere
 import (
 	"fmt"
 	"path/filepath"
+	"time"
 )
 
+func hostRootPath(rootPath, host string) string {
+	return filepath.Join(rootPath, host)
+}
+
 func repoPath(rootPath, owner, name string) string {
 	return filepath.Join(rootPath, owner, name)
 }
@@ -13,6 +18,10 @@ func repoDetailPath(rootPath, owner, name string) string {
 	return filepath.Join(repoPath(rootPath, owner, name), DETAIL_FILENAME)
 }
 
+func repoCheckpointsPath(rootPath, owner, name string) string {
+	return filepath.Join(repoPath(rootPath, owner, name), CHECKPOINTS_FILENAME)
+}
+
 func repoPullRequestsPath(rootPath, owner, name string) string {
 	return filepath.Join(repoPath(rootPath, owner, name), "pull-requests")
 }
@@ -33,6 +42,34 @@ func repoLabelPath(rootPath, owner, name string, labelID int64) string {
 	return filepath.Join(repoLabelsPath(rootPath, owner, name), fmt.Sprintf("%d.json", labelID))
 }
 
+func repoReleasesPath(rootPath, owner, name string) string {
+	return filepath.Join(repoPath(rootPath, owner, name), "releases")
+}
+
+func releasePath(rootPath, owner, name string, releaseID int64) string {
+	return filepath.Join(repoReleasesPath(rootPath, owner, name), fmt.Sprintf("%d.json", releaseID))
+}
+
+func repoDiscussionsPath(rootPath, owner, name string) string {
+	return filepath.Join(repoPath(rootPath, owner, name), "discussions")
+}
+
+func discussionCategoryPath(rootPath, owner, name, categoryID string) string {
+	return filepath.Join(repoDiscussionsPath(rootPath, owner, name), "categories", categoryID+".json")
+}
+
+func discussionPath(rootPath, owner, name string, discussionNum int) string {
+	return filepath.Join(repoDiscussionsPath(rootPath, owner, name), fmt.Sprintf("%.6d", discussionNum))
+}
+
+func discussionDetailPath(rootPath, owner, name string, discussionNum int) string {
+	return filepath.Join(discussionPath(rootPath, owner, name, discussionNum), DETAIL_FILENAME)
+}
+
+func discussionCommentPath(rootPath, owner, name string, discussionNum int, commentID int64) string {
+	return filepath.Join(discussionPath(rootPath, owner, name, discussionNum), "comments", fmt.Sprintf("%d.json", commentID))
+}
+
 func pullRequestPath(rootPath, owner, name string, prNum int) string {
 	return filepath.Join(repoPullRequestsPath(rootPath, owner, name), fmt.Sprintf("%.6d", prNum))
 }
@@ -86,3 +123,115 @@ func issueCommentsPath(rootPath, owner, name string, issueNum int) string {
 func issueCommentPath(rootPath, owner, name string, issueNum int, commentID int64) string {
 	return filepath.Join(issueCommentsPath(rootPath, owner, name, issueNum), fmt.Sprintf("%d.json", commentID))
 }
+
+func issueEventsPath(rootPath, owner, name string, issueNum int) string {
+	return filepath.Join(issuePath(rootPath, owner, name, issueNum), "events")
+}
+
+func issueEventPath(rootPath, owner, name string, issueNum int, key string) string {
+	return filepath.Join(issueEventsPath(rootPath, owner, name, issueNum), key+".json")
+}
+
+func pullRequestEventsPath(rootPath, owner, name string, prNum int) string {
+	return filepath.Join(pullRequestPath(rootPath, owner, name, prNum), "events")
+}
+
+func pullRequestEventPath(rootPath, owner, name string, prNum int, key string) string {
+	return filepath.Join(pullRequestEventsPath(rootPath, owner, name, prNum), key+".json")
+}
+
+func repoActionsPath(rootPath, owner, name string) string {
+	return filepath.Join(repoPath(rootPath, owner, name), "actions")
+}
+
+func workflowPath(rootPath, owner, name string, workflowID int64) string {
+	return filepath.Join(repoActionsPath(rootPath, owner, name), "workflows", fmt.Sprintf("%d.json", workflowID))
+}
+
+func workflowRunsPath(rootPath, owner, name string) string {
+	return filepath.Join(repoActionsPath(rootPath, owner, name), "runs")
+}
+
+func workflowRunPath(rootPath, owner, name string, runID int64) string {
+	return filepath.Join(workflowRunsPath(rootPath, owner, name), fmt.Sprintf("%d", runID))
+}
+
+func workflowRunDetailPath(rootPath, owner, name string, runID int64) string {
+	return filepath.Join(workflowRunPath(rootPath, owner, name, runID), DETAIL_FILENAME)
+}
+
+func workflowJobPath(rootPath, owner, name string, runID, jobID int64) string {
+	return filepath.Join(workflowRunPath(rootPath, owner, name, runID), "jobs", fmt.Sprintf("%d.json", jobID))
+}
+
+func workflowRunLogsPath(rootPath, owner, name string, runID int64) string {
+	return filepath.Join(workflowRunPath(rootPath, owner, name, runID), "logs")
+}
+
+func workflowArtifactPath(rootPath, owner, name string, runID, artifactID int64) string {
+	return filepath.Join(workflowRunPath(rootPath, owner, name, runID), "artifacts", fmt.Sprintf("%d.json", artifactID))
+}
+
+// workflowArtifactContentsPath is the directory into which an artifact's
+// archive is extracted.
+func workflowArtifactContentsPath(rootPath, owner, name string, runID, artifactID int64) string {
+	return filepath.Join(workflowRunPath(rootPath, owner, name, runID), "artifacts", fmt.Sprintf("%d", artifactID))
+}
+
+func repoChecksPath(rootPath, owner, name string) string {
+	return filepath.Join(repoPath(rootPath, owner, name), "checks")
+}
+
+func commitChecksPath(rootPath, owner, name, sha string) string {
+	return filepath.Join(repoChecksPath(rootPath, owner, name), sha+".json")
+}
+
+// orgPath is the path at which organization-level resources (i.e. those that
+// do not belong to any particular repository) are stored.
+func orgPath(rootPath, owner string) string {
+	return filepath.Join(rootPath, owner, "_org")
+}
+
+// projectPath is the path at which a project is stored, depending on whether
+// it is addressed via a repository (if name is not empty) or at the
+// organization level.
+func projectPath(rootPath, owner, name string, number int) string {
+	if len(name) == 0 {
+		return filepath.Join(orgPath(rootPath, owner), "projects", fmt.Sprintf("%.6d", number))
+	}
+	return filepath.Join(repoPath(rootPath, owner, name), "projects", fmt.Sprintf("%.6d", number))
+}
+
+func projectDetailPath(rootPath, owner, name string, number int) string {
+	return filepath.Join(projectPath(rootPath, owner, name, number), DETAIL_FILENAME)
+}
+
+func projectFieldPath(rootPath, owner, name string, number int, fieldID string) string {
+	return filepath.Join(projectPath(rootPath, owner, name, number), "fields", fieldID+".json")
+}
+
+func projectItemPath(rootPath, owner, name string, number int, itemID string) string {
+	return filepath.Join(projectPath(rootPath, owner, name, number), "items", itemID+".json")
+}
+
+func teamsPath(rootPath, owner string) string {
+	return filepath.Join(orgPath(rootPath, owner), "teams")
+}
+
+func teamPath(rootPath, owner, slug string) string {
+	return filepath.Join(teamsPath(rootPath, owner), slug)
+}
+
+func teamDetailPath(rootPath, owner, slug string) string {
+	return filepath.Join(teamPath(rootPath, owner, slug), DETAIL_FILENAME)
+}
+
+// teamSnapshotPath is the path at which the snapshot of a team taken at the
+// given time is stored.
+func teamSnapshotPath(rootPath, owner, slug string, t time.Time) string {
+	return filepath.Join(teamPath(rootPath, owner, slug), "history", t.UTC().Format("20060102T150405Z")+".json")
+}
+
+func bundleFilePath(outputDir, host, owner, name string) string {
+	return filepath.Join(outputDir, host, owner, name+".bundle")
+}
@@ -0,0 +1,105 @@
+package ghere
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+)
+
+// FetchPlan describes the work that a fetch would do, without actually doing
+// it.
+type FetchPlan struct {
+	Repositories []*RepositoryFetchPlan `json:"repositories"`
+}
+
+// RepositoryFetchPlan describes the work that a fetch would do for a specific
+// repository (or project or organization). API call estimates are based on
+// what is known locally, so more calls may be required if new items are
+// discovered while fetching.
+type RepositoryFetchPlan struct {
+	Repository             string         `json:"repository"`
+	Fetchers               []*FetcherPlan `json:"fetchers"`
+	EstimatedAPICalls      int            `json:"estimated_api_calls"`
+	EstimatedGitOperations int            `json:"estimated_git_operations"`
+	Error                  string         `json:"error,omitempty"`
+}
+
+// FetcherPlan describes the work a single fetcher would do.
+type FetcherPlan struct {
+	// Resource is the type of resource that would be fetched (e.g. "issues").
+	Resource          string   `json:"resource"`
+	Description       string   `json:"description"`
+	EstimatedAPICalls int      `json:"estimated_api_calls"`
+	GitOperations     []string `json:"git_operations,omitempty"`
+}
+
+// planner is implemented by fetchers that are able to estimate the work they
+// would do without doing it.
+type planner interface {
+	plan(cfg *FetchConfig) (*FetcherPlan, error)
+}
+
+// targetPlanner is implemented by the root fetchers of fetch targets, which
+// may need to fetch the target's details from GitHub (without saving them) in
+// order to determine which of its resources would be fetched.
+type targetPlanner interface {
+	plan(ctx context.Context, cfg *FetchConfig) (*RepositoryFetchPlan, error)
+}
+
+// Plan computes which resources of each repository, project and organization
+// in the collection are stale and therefore would be fetched by
+// [LocalCollection.Fetch], without writing anything. Only each repository's
+// and project's details are fetched from GitHub. Organizations' teams are
+// always fetched.
+func (c *LocalCollection) Plan(ctx context.Context, cfg *FetchConfig, log Logger) (*FetchPlan, error) {
+	targets, err := c.selectTargets(cfg)
+	if err != nil {
+		return nil, err
+	}
+	plan := &FetchPlan{
+		Repositories: make([]*RepositoryFetchPlan, 0, len(targets)),
+	}
+	for _, target := range targets {
+		f, rootResource := target.rootFetcher(c, cfg)
+		p := f.(targetPlanner)
+		var targetPlan *RepositoryFetchPlan
+		hostCfg, err := cfg.forHost(target.hostName())
+		if err == nil {
+			targetPlan, err = p.plan(ctx, hostCfg)
+		}
+		if err != nil {
+			if cfg.FailFast {
+				return nil, err
+			}
+			log.Error("Failed to plan fetch for "+rootResource, "path", target.String(), "err", err)
+			targetPlan = &RepositoryFetchPlan{Error: err.Error()}
+		}
+		targetPlan.Repository = target.String()
+		plan.Repositories = append(plan.Repositories, targetPlan)
+	}
+	return plan, nil
+}
+
+// countLocalItems counts the number of local files matching the given
+// pattern.
+func countLocalItems(pattern string) (int, error) {
+	filenames, err := filepath.Glob(pattern)
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up local items %s: %v", pattern, err)
+	}
+	return len(filenames), nil
+}
+
+// estimatePages estimates the number of pages that need to be fetched to list
+// the given number of items, accounting for any checkpoint left behind by a
+// prior interrupted fetch.
+func estimatePages(itemCount int, cp *FetchCheckpoint) int {
+	pages := itemCount/DEFAULT_PER_PAGE + 1
+	if cp != nil {
+		pages -= cp.resumePage() - 1
+	}
+	if pages < 1 {
+		return 1
+	}
+	return pages
+}
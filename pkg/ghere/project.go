@@ -0,0 +1,331 @@
+package ghere
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// GitHubProjectV2 is a (v2) project, as provided by GitHub's GraphQL API.
+type GitHubProjectV2 struct {
+	ID               string       `json:"id"`
+	Number           int          `json:"number"`
+	Title            string       `json:"title"`
+	ShortDescription string       `json:"shortDescription,omitempty"`
+	Readme           string       `json:"readme,omitempty"`
+	URL              string       `json:"url"`
+	Closed           bool         `json:"closed"`
+	Public           bool         `json:"public"`
+	Creator          *GitHubActor `json:"creator"`
+	CreatedAt        time.Time    `json:"createdAt"`
+	UpdatedAt        time.Time    `json:"updatedAt"`
+}
+
+// GitHubProjectV2Field is the definition of one of a project's (possibly
+// custom) fields.
+type GitHubProjectV2Field struct {
+	Typename  string    `json:"__typename"`
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	DataType  string    `json:"dataType"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	// Options are the options of single select fields.
+	Options []*GitHubProjectV2FieldOption `json:"options,omitempty"`
+	// Configuration is the configuration of iteration fields.
+	Configuration *GitHubProjectV2IterationConfiguration `json:"configuration,omitempty"`
+}
+
+type GitHubProjectV2FieldOption struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type GitHubProjectV2IterationConfiguration struct {
+	Duration            int                         `json:"duration"`
+	StartDay            int                         `json:"startDay"`
+	Iterations          []*GitHubProjectV2Iteration `json:"iterations"`
+	CompletedIterations []*GitHubProjectV2Iteration `json:"completedIterations"`
+}
+
+type GitHubProjectV2Iteration struct {
+	ID        string `json:"id"`
+	Title     string `json:"title"`
+	StartDate string `json:"startDate"`
+	Duration  int    `json:"duration"`
+}
+
+// GitHubProjectV2Item is an item in a project, along with the values of its
+// fields.
+type GitHubProjectV2Item struct {
+	ID          string                           `json:"id"`
+	Type        string                           `json:"type"`
+	IsArchived  bool                             `json:"isArchived"`
+	CreatedAt   time.Time                        `json:"createdAt"`
+	UpdatedAt   time.Time                        `json:"updatedAt"`
+	Content     *GitHubProjectV2ItemContent      `json:"content"`
+	FieldValues []*GitHubProjectV2ItemFieldValue `json:"fieldValues"`
+}
+
+// GitHubProjectV2ItemContent is the issue, pull request or draft issue that a
+// project item represents.
+type GitHubProjectV2ItemContent struct {
+	Typename   string               `json:"__typename"`
+	Number     int                  `json:"number,omitempty"`
+	Title      string               `json:"title"`
+	URL        string               `json:"url,omitempty"`
+	Body       string               `json:"body,omitempty"`
+	Repository *GitHubRepositoryRef `json:"repository,omitempty"`
+}
+
+// GitHubRepositoryRef refers to a repository.
+type GitHubRepositoryRef struct {
+	NameWithOwner string `json:"nameWithOwner"`
+}
+
+// GitHubProjectV2ItemFieldValue is the value of one of a project item's
+// fields. Which of the value fields is set depends on the type of the field.
+type GitHubProjectV2ItemFieldValue struct {
+	Typename    string                   `json:"__typename"`
+	Field       *GitHubProjectV2FieldRef `json:"field"`
+	Text        *string                  `json:"text,omitempty"`
+	Number      *float64                 `json:"number,omitempty"`
+	Date        *string                  `json:"date,omitempty"`
+	Name        *string                  `json:"name,omitempty"`
+	OptionID    *string                  `json:"optionId,omitempty"`
+	Title       *string                  `json:"title,omitempty"`
+	IterationID *string                  `json:"iterationId,omitempty"`
+	StartDate   *string                  `json:"startDate,omitempty"`
+	Duration    *int                     `json:"duration,omitempty"`
+}
+
+// GitHubProjectV2FieldRef refers to a project field.
+type GitHubProjectV2FieldRef struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type Project struct {
+	Project *GitHubProjectV2 `json:"project"`
+
+	LastDetailFetch time.Time `json:"last_detail_fetch"`
+	LastItemsFetch  time.Time `json:"last_items_fetch"`
+}
+
+func LoadProject(rootPath, owner, name string, number int, mustExist bool) (*Project, error) {
+	var err error
+	project := &Project{}
+	path := projectDetailPath(rootPath, owner, name, number)
+	if mustExist {
+		err = readJSONFile(path, project)
+	} else {
+		err = readJSONFileOrEmpty(path, project)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read project detail file: %v", err)
+	}
+	return project, nil
+}
+
+// Save writes the project's details to the project's directory, which depends
+// on whether the project is addressed via a repository (if name is not empty)
+// or at the organization level.
+func (p *Project) Save(rootPath, owner, name string, prettyJSON bool) error {
+	path := projectDetailPath(rootPath, owner, name, p.Project.Number)
+	if err := writeJSONFile(path, p, prettyJSON); err != nil {
+		return fmt.Errorf("failed to write project detail file: %v", err)
+	}
+	return nil
+}
+
+func (p *Project) MustFetchItems() bool {
+	return p.Project.UpdatedAt.After(p.LastItemsFetch)
+}
+
+type ProjectField struct {
+	Field *GitHubProjectV2Field `json:"field"`
+}
+
+type ProjectItem struct {
+	Item *GitHubProjectV2Item `json:"item"`
+}
+
+// LocalPath returns the path to the local copy of the issue or pull request
+// represented by the item, relative to the root of the collection (or of the
+// item's GitHub Enterprise Server host). Returns an empty string for draft
+// issues.
+func (i *ProjectItem) LocalPath() string {
+	content := i.Item.Content
+	if content == nil || content.Repository == nil {
+		return ""
+	}
+	parts := strings.SplitN(content.Repository.NameWithOwner, "/", 2)
+	if len(parts) != 2 {
+		return ""
+	}
+	switch content.Typename {
+	case "Issue":
+		return filepath.ToSlash(issuePath("", parts[0], parts[1], content.Number))
+	case "PullRequest":
+		return filepath.ToSlash(pullRequestPath("", parts[0], parts[1], content.Number))
+	}
+	return ""
+}
+
+// projectFetcher fetches a project's details and field definitions.
+type projectFetcher struct {
+	rootPath string
+	owner    string
+	// The name of the repository via which the project is addressed, if any.
+	name    string
+	number  int
+	project *Project
+}
+
+var (
+	_ fetcher       = (*projectFetcher)(nil)
+	_ targetPlanner = (*projectFetcher)(nil)
+)
+
+func newProjectFetcher(rootPath, owner, name string, number int) *projectFetcher {
+	return &projectFetcher{
+		rootPath: rootPath,
+		owner:    owner,
+		name:     name,
+		number:   number,
+	}
+}
+
+func (f *projectFetcher) resource() string {
+	return RESOURCE_PROJECTS
+}
+
+func (f *projectFetcher) fetch(ctx context.Context, cfg *FetchConfig, log Logger) ([]fetcher, error) {
+	var err error
+	f.project, err = LoadProject(f.rootPath, f.owner, f.name, f.number, false)
+	if err != nil {
+		return nil, err
+	}
+	ghProject, fields, err := cfg.Client.GetProject(ctx, f.owner, f.name, f.number)
+	if err != nil {
+		return nil, err
+	}
+	cfg.report.recordItem(RESOURCE_PROJECTS, f.project.Project != nil, f.project.Project, ghProject)
+	f.project.Project = ghProject
+	f.project.LastDetailFetch = time.Now()
+	if err := f.project.Save(f.rootPath, f.owner, f.name, cfg.PrettyJSON); err != nil {
+		return nil, err
+	}
+	for _, ghField := range fields {
+		path := projectFieldPath(f.rootPath, f.owner, f.name, f.number, ghField.ID)
+		field := &ProjectField{}
+		if err := readJSONFileOrEmpty(path, field); err != nil {
+			return nil, fmt.Errorf("failed to read project field file: %v", err)
+		}
+		cfg.report.recordItem(RESOURCE_PROJECT_FIELDS, field.Field != nil, field.Field, ghField)
+		field.Field = ghField
+		if err := writeJSONFile(path, field, cfg.PrettyJSON); err != nil {
+			return nil, fmt.Errorf("failed to write project field file: %v", err)
+		}
+	}
+	log.Info("Fetched project details", "project", f.String(), "fields", len(fields))
+
+	if !f.project.MustFetchItems() {
+		return nil, nil
+	}
+	return []fetcher{newProjectItemsFetcher(f)}, nil
+}
+
+// plan fetches the project's details (without saving them) and computes
+// whether its items would be fetched.
+func (f *projectFetcher) plan(ctx context.Context, cfg *FetchConfig) (*RepositoryFetchPlan, error) {
+	var err error
+	f.project, err = LoadProject(f.rootPath, f.owner, f.name, f.number, false)
+	if err != nil {
+		return nil, err
+	}
+	f.project.Project, _, err = cfg.Client.GetProject(ctx, f.owner, f.name, f.number)
+	if err != nil {
+		return nil, err
+	}
+	projectPlan := &RepositoryFetchPlan{
+		Fetchers:          []*FetcherPlan{},
+		EstimatedAPICalls: 1,
+	}
+	if !f.project.MustFetchItems() {
+		return projectPlan, nil
+	}
+	itemsPath := filepath.Join(projectPath(f.rootPath, f.owner, f.name, f.number), "items")
+	count, err := countLocalItems(filepath.Join(itemsPath, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	pages := estimatePages(count, nil)
+	projectPlan.Fetchers = append(projectPlan.Fetchers, &FetcherPlan{
+		Resource:          RESOURCE_PROJECT_ITEMS,
+		Description:       fmt.Sprintf("list project items (~%d page(s))", pages),
+		EstimatedAPICalls: pages,
+	})
+	projectPlan.EstimatedAPICalls += pages
+	return projectPlan, nil
+}
+
+func (f *projectFetcher) String() string {
+	if len(f.name) > 0 {
+		return fmt.Sprintf("%s/%s/projects/%d", f.owner, f.name, f.number)
+	}
+	return fmt.Sprintf("%s/projects/%d", f.owner, f.number)
+}
+
+// projectItemsFetcher fetches all of a project's items, along with their
+// field values.
+type projectItemsFetcher struct {
+	*projectFetcher
+}
+
+var _ fetcher = (*projectItemsFetcher)(nil)
+
+func newProjectItemsFetcher(pf *projectFetcher) *projectItemsFetcher {
+	return &projectItemsFetcher{projectFetcher: pf}
+}
+
+func (f *projectItemsFetcher) resource() string {
+	return RESOURCE_PROJECT_ITEMS
+}
+
+func (f *projectItemsFetcher) fetch(ctx context.Context, cfg *FetchConfig, log Logger) ([]fetcher, error) {
+	fetchStarted := time.Now()
+	count := 0
+	done := false
+	for cursor := ""; !done; {
+		var items []*GitHubProjectV2Item
+		var err error
+		items, cursor, err = cfg.Client.ListProjectItems(ctx, f.project.Project.ID, cursor)
+		if err != nil {
+			return nil, err
+		}
+		done = len(cursor) == 0
+		for _, ghItem := range items {
+			path := projectItemPath(f.rootPath, f.owner, f.name, f.number, ghItem.ID)
+			item := &ProjectItem{}
+			if err := readJSONFileOrEmpty(path, item); err != nil {
+				return nil, fmt.Errorf("failed to read project item file: %v", err)
+			}
+			cfg.report.recordItem(RESOURCE_PROJECT_ITEMS, item.Item != nil, item.Item, ghItem)
+			item.Item = ghItem
+			if err := writeJSONFile(path, item, cfg.PrettyJSON); err != nil {
+				return nil, fmt.Errorf("failed to write project item file: %v", err)
+			}
+			count++
+		}
+	}
+	log.Info("Fetched project items", "project", f.String(), "items", count)
+
+	f.project.LastItemsFetch = fetchStarted
+	if err := f.project.Save(f.rootPath, f.owner, f.name, cfg.PrettyJSON); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
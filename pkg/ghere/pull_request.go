@@ -15,6 +15,7 @@ type PullRequest struct {
 	LastDetailFetch   time.Time `json:"last_detail_fetch"`
 	LastReviewsFetch  time.Time `json:"last_reviews_fetch"`
 	LastCommentsFetch time.Time `json:"last_comments_fetch"`
+	LastEventsFetch   time.Time `json:"last_events_fetch"`
 }
 
 func LoadPullRequest(rootPath string, repo *Repository, prNum int, mustExist bool) (*PullRequest, error) {
@@ -50,6 +51,10 @@ func (pr *PullRequest) MustFetchComments() bool {
 	return pr.PullRequest.GetUpdatedAt().After(pr.LastCommentsFetch)
 }
 
+func (pr *PullRequest) MustFetchEvents() bool {
+	return pr.PullRequest.GetUpdatedAt().After(pr.LastEventsFetch)
+}
+
 func (pr *PullRequest) Save(rootPath string, repo *Repository, prettyJSON bool) error {
 	path := pullRequestDetailPath(rootPath, repo.GetOwner(), repo.GetName(), pr.GetNumber())
 	if err := writeJSONFile(path, pr, prettyJSON); err != nil {
@@ -62,51 +67,61 @@ func (pr *PullRequest) Save(rootPath string, repo *Repository, prettyJSON bool)
 type pullRequestsFetcher struct {
 	rootPath string
 	repo     *Repository
+	// Whether to fetch pull requests' timeline events.
+	timelines bool
 }
 
 var _ fetcher = (*pullRequestsFetcher)(nil)
+var _ planner = (*pullRequestsFetcher)(nil)
 
-func newPullRequestsFetcher(rootPath string, repo *Repository) *pullRequestsFetcher {
+func newPullRequestsFetcher(rootPath string, repo *Repository, timelines bool) *pullRequestsFetcher {
 	return &pullRequestsFetcher{
-		rootPath: rootPath,
-		repo:     repo,
+		rootPath:  rootPath,
+		repo:      repo,
+		timelines: timelines,
 	}
 }
 
-func (pf *pullRequestsFetcher) fetch(ctx context.Context, cfg *FetchConfig, log Logger) ([]fetcher, error) {
-	prsPath := repoPullRequestsPath(pf.rootPath, pf.repo.GetOwner(), pf.repo.GetName())
-	pattern := filepath.Join(prsPath, "*", DETAIL_FILENAME)
-	startPage, err := paginatedItemsStartPage(pattern, func(fn string) (bool, error) {
-		pr, err := LoadPullRequestDirect(fn, true)
-		if err != nil {
-			return false, err
-		}
-		repoUpdatedRecently := pf.repo.Repository.GetUpdatedAt().After(pr.LastDetailFetch)
-		fetchedMoreThan24hAgo := time.Since(pr.LastDetailFetch) > 24*time.Hour
-		return repoUpdatedRecently && fetchedMoreThan24hAgo, nil
-	})
-	if err != nil {
-		return nil, err
-	}
+func (pf *pullRequestsFetcher) resource() string {
+	return RESOURCE_PULL_REQUESTS
+}
 
-	var pulls []*github.PullRequest
-	done := false
-	for page := startPage; !done; page++ {
-		pulls, done, err = cfg.Client.ListRepositoryPullRequests(ctx, pf.repo.GetOwner(), pf.repo.GetName(), page)
-		if err != nil {
-			return nil, err
-		}
-		for _, ghPull := range pulls {
+func (pf *pullRequestsFetcher) fetch(ctx context.Context, cfg *FetchConfig, log Logger) ([]fetcher, error) {
+	seen := make(map[int64]bool)
+	fromStart, err := fetchPaginated(
+		pf.rootPath,
+		pf.repo,
+		"pull_requests",
+		cfg,
+		log,
+		func(page int) ([]*github.PullRequest, bool, error) {
+			return cfg.Client.ListRepositoryPullRequests(ctx, pf.repo.GetOwner(), pf.repo.GetName(), page)
+		},
+		func(ghPull *github.PullRequest) int64 {
+			return int64(ghPull.GetNumber())
+		},
+		func(ghPull *github.PullRequest) error {
 			pull, err := LoadPullRequest(pf.rootPath, pf.repo, ghPull.GetNumber(), false)
 			if err != nil {
-				return nil, err
+				return err
 			}
+			seen[int64(ghPull.GetNumber())] = true
+			cfg.report.recordItem(RESOURCE_PULL_REQUESTS, pull.PullRequest != nil, pull.PullRequest, ghPull)
 			pull.PullRequest = ghPull
 			pull.LastDetailFetch = time.Now()
-			if err := pull.Save(pf.rootPath, pf.repo, cfg.PrettyJSON); err != nil {
-				return nil, err
-			}
+			return pull.Save(pf.rootPath, pf.repo, cfg.PrettyJSON)
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if fromStart {
+		prsPath := repoPullRequestsPath(pf.rootPath, pf.repo.GetOwner(), pf.repo.GetName())
+		deleted, err := countDeletedItems(filepath.Join(prsPath, "*", DETAIL_FILENAME), seen, filepath.Dir)
+		if err != nil {
+			return nil, err
 		}
+		cfg.report.recordDeleted(RESOURCE_PULL_REQUESTS, deleted)
 	}
 	log.Info("Fetched all pull requests' details", "repo", pf.repo.String())
 
@@ -115,25 +130,49 @@ func (pf *pullRequestsFetcher) fetch(ctx context.Context, cfg *FetchConfig, log
 		return nil, err
 	}
 
-	return pf.makeReviewsAndCommentsFetchers(log)
+	return pf.makeReviewsCommentsAndEventsFetchers(log)
 }
 
-func (pf *pullRequestsFetcher) makeReviewsAndCommentsFetchers(log Logger) ([]fetcher, error) {
-	log.Info("Computing which pull requests' reviews and comments should be fetched", "repo", pf.repo.String())
-	// Pull requests whose reviews and comments must be fetched.
+func (pf *pullRequestsFetcher) makeReviewsCommentsAndEventsFetchers(log Logger) ([]fetcher, error) {
+	log.Info("Computing which pull requests' reviews, comments and events should be fetched", "repo", pf.repo.String())
+	fetchReviews, fetchComments, fetchEvents, err := pf.pullRequestsWithStaleReviewsCommentsAndEvents()
+	if err != nil {
+		return nil, err
+	}
+
+	fetchers := []fetcher{}
+	if len(fetchReviews) > 0 {
+		fetchers = append(fetchers, newPullRequestReviewsFetcher(pf.rootPath, pf.repo, fetchReviews))
+	}
+	if len(fetchComments) > 0 {
+		fetchers = append(fetchers, newPullRequestCommentsFetcher(pf.rootPath, pf.repo, fetchComments))
+	}
+	if len(fetchEvents) > 0 {
+		fetchers = append(fetchers, newPullRequestEventsFetcher(pf.rootPath, pf.repo, fetchEvents))
+	}
+
+	return fetchers, nil
+}
+
+// pullRequestsWithStaleReviewsCommentsAndEvents returns the local pull
+// requests whose reviews, comments and timeline events (respectively) must be
+// fetched. No pull requests' timeline events are returned if timelines are not
+// being fetched.
+func (pf *pullRequestsFetcher) pullRequestsWithStaleReviewsCommentsAndEvents() ([]*PullRequest, []*PullRequest, []*PullRequest, error) {
 	fetchReviews := []*PullRequest{}
 	fetchComments := []*PullRequest{}
+	fetchEvents := []*PullRequest{}
 	prsPath := repoPullRequestsPath(pf.rootPath, pf.repo.GetOwner(), pf.repo.GetName())
 	pattern := filepath.Join(prsPath, "*", DETAIL_FILENAME)
 	pullRequestDetailsFiles, err := filepath.Glob(pattern)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list pull requests' detail files from pattern %s: %v", pattern, err)
+		return nil, nil, nil, fmt.Errorf("failed to list pull requests' detail files from pattern %s: %v", pattern, err)
 	}
 
 	for _, fn := range pullRequestDetailsFiles {
 		pr, err := LoadPullRequestDirect(fn, true)
 		if err != nil {
-			return nil, err
+			return nil, nil, nil, err
 		}
 		if pr.MustFetchReviews() {
 			fetchReviews = append(fetchReviews, pr)
@@ -141,15 +180,42 @@ func (pf *pullRequestsFetcher) makeReviewsAndCommentsFetchers(log Logger) ([]fet
 		if pr.MustFetchComments() {
 			fetchComments = append(fetchComments, pr)
 		}
+		if pf.timelines && pr.MustFetchEvents() {
+			fetchEvents = append(fetchEvents, pr)
+		}
 	}
+	return fetchReviews, fetchComments, fetchEvents, nil
+}
 
-	fetchers := []fetcher{}
-	if len(fetchReviews) > 0 {
-		fetchers = append(fetchers, newPullRequestReviewsFetcher(pf.rootPath, pf.repo, fetchReviews))
+func (pf *pullRequestsFetcher) plan(cfg *FetchConfig) (*FetcherPlan, error) {
+	prsPath := repoPullRequestsPath(pf.rootPath, pf.repo.GetOwner(), pf.repo.GetName())
+	count, err := countLocalItems(filepath.Join(prsPath, "*", DETAIL_FILENAME))
+	if err != nil {
+		return nil, err
 	}
-	if len(fetchComments) > 0 {
-		fetchers = append(fetchers, newPullRequestCommentsFetcher(pf.rootPath, pf.repo, fetchComments))
+	cps, err := LoadFetchCheckpoints(pf.rootPath, pf.repo.GetOwner(), pf.repo.GetName())
+	if err != nil {
+		return nil, err
 	}
-
-	return fetchers, nil
+	pages := estimatePages(count, cps.fresh("pull_requests"))
+	fetchReviews, fetchComments, fetchEvents, err := pf.pullRequestsWithStaleReviewsCommentsAndEvents()
+	if err != nil {
+		return nil, err
+	}
+	fetchReviewComments, err := reviewsWithStaleComments(pf.rootPath, pf.repo)
+	if err != nil {
+		return nil, err
+	}
+	return &FetcherPlan{
+		Resource: RESOURCE_PULL_REQUESTS,
+		Description: fmt.Sprintf(
+			"list pull requests (~%d page(s)), then fetch reviews for at least %d, comments for at least %d and events for at least %d pull request(s), and review comments for at least %d review(s)",
+			pages,
+			len(fetchReviews),
+			len(fetchComments),
+			len(fetchEvents),
+			len(fetchReviewComments),
+		),
+		EstimatedAPICalls: pages + len(fetchReviews) + len(fetchComments) + len(fetchEvents) + len(fetchReviewComments),
+	}, nil
 }
@@ -10,6 +10,8 @@ import (
 
 type PullRequestComment struct {
 	Comment *github.PullRequestComment `json:"comment"`
+	// Reactions optionally lists the individual reactions to the comment.
+	Reactions []*github.Reaction `json:"reactions,omitempty"`
 }
 
 func LoadPullRequestComment(rootPath string, repo *Repository, prNum int, commentID int64, mustExist bool) (*PullRequestComment, error) {
@@ -94,6 +96,10 @@ func newPullRequestCommentsFetcher(rootPath string, repo *Repository, pullReques
 	}
 }
 
+func (cf *pullRequestCommentsFetcher) resource() string {
+	return RESOURCE_PULL_REQUEST_COMMENTS
+}
+
 func (cf *pullRequestCommentsFetcher) fetch(ctx context.Context, cfg *FetchConfig, log Logger) ([]fetcher, error) {
 	for _, pr := range cf.pullRequests {
 		var err error
@@ -111,8 +117,14 @@ func (cf *pullRequestCommentsFetcher) fetch(ctx context.Context, cfg *FetchConfi
 				return nil, err
 			}
 			for _, ghComment := range comments {
-				comment := &PullRequestComment{
-					Comment: ghComment,
+				comment, err := LoadPullRequestComment(cf.rootPath, cf.repo, pr.GetNumber(), ghComment.GetID(), false)
+				if err != nil {
+					return nil, err
+				}
+				cfg.report.recordItem(RESOURCE_PULL_REQUEST_COMMENTS, comment.Comment != nil, comment.Comment, ghComment)
+				comment.Comment = ghComment
+				if err := comment.fetchReactions(ctx, cfg, cf.repo); err != nil {
+					return nil, err
 				}
 				if err := comment.Save(cf.rootPath, cf.repo, pr.GetNumber(), cfg.PrettyJSON); err != nil {
 					return nil, err
@@ -143,6 +155,10 @@ func newPullRequestReviewCommentsFetcher(rootPath string, repo *Repository, revi
 	}
 }
 
+func (cf *pullRequestReviewCommentsFetcher) resource() string {
+	return RESOURCE_PULL_REQUEST_REVIEW_COMMENTS
+}
+
 func (cf *pullRequestReviewCommentsFetcher) fetch(ctx context.Context, cfg *FetchConfig, log Logger) ([]fetcher, error) {
 	for _, review := range cf.reviews {
 		var err error
@@ -161,8 +177,14 @@ func (cf *pullRequestReviewCommentsFetcher) fetch(ctx context.Context, cfg *Fetc
 				return nil, err
 			}
 			for _, ghComment := range comments {
-				comment := &PullRequestComment{
-					Comment: ghComment,
+				comment, err := LoadPullRequestReviewComment(cf.rootPath, cf.repo, review.PullRequestNumber, review.Review.GetID(), ghComment.GetID(), false)
+				if err != nil {
+					return nil, err
+				}
+				cfg.report.recordItem(RESOURCE_PULL_REQUEST_REVIEW_COMMENTS, comment.Comment != nil, comment.Comment, ghComment)
+				comment.Comment = ghComment
+				if err := comment.fetchReactions(ctx, cfg, cf.repo); err != nil {
+					return nil, err
 				}
 				if err := comment.SaveForReview(cf.rootPath, cf.repo, review.PullRequestNumber, review.Review.GetID(), cfg.PrettyJSON); err != nil {
 					return nil, err
@@ -64,42 +64,39 @@ func newPullRequestReviewsFetcher(rootPath string, repo *Repository, pullRequest
 	}
 }
 
+func (rf *pullRequestReviewsFetcher) resource() string {
+	return RESOURCE_PULL_REQUEST_REVIEWS
+}
+
 func (rf *pullRequestReviewsFetcher) fetch(ctx context.Context, cfg *FetchConfig, log Logger) ([]fetcher, error) {
 	for _, pr := range rf.pullRequests {
-		prReviewsPath := pullRequestReviewsPath(rf.rootPath, rf.repo.GetOwner(), rf.repo.GetName(), pr.GetNumber())
-		pattern := filepath.Join(prReviewsPath, "*", DETAIL_FILENAME)
-		startPage, err := paginatedItemsStartPage(pattern, func(fn string) (bool, error) {
-			review, err := LoadPullRequestReviewDirect(fn, true)
-			if err != nil {
-				return false, err
-			}
-			// If the pull request was last updated after this review was last
-			// fetched, consider it outdated.
-			return pr.PullRequest.GetUpdatedAt().After(review.LastDetailFetch), nil
-		})
-		if err != nil {
-			return nil, err
-		}
-
-		done := false
-		for page := startPage; !done; page++ {
-			var reviews []*github.PullRequestReview
-			reviews, done, err = cfg.Client.ListPullRequestReviews(ctx, rf.repo.GetOwner(), rf.repo.GetName(), pr.GetNumber(), page)
-			if err != nil {
-				return nil, err
-			}
-			for _, ghReview := range reviews {
+		pr := pr
+		_, err := fetchPaginated(
+			rf.rootPath,
+			rf.repo,
+			fmt.Sprintf("pull_requests/%d/reviews", pr.GetNumber()),
+			cfg,
+			log,
+			func(page int) ([]*github.PullRequestReview, bool, error) {
+				return cfg.Client.ListPullRequestReviews(ctx, rf.repo.GetOwner(), rf.repo.GetName(), pr.GetNumber(), page)
+			},
+			func(ghReview *github.PullRequestReview) int64 {
+				return ghReview.GetID()
+			},
+			func(ghReview *github.PullRequestReview) error {
 				review, err := LoadPullRequestReview(rf.rootPath, rf.repo, pr.GetNumber(), ghReview.GetID(), false)
 				if err != nil {
-					return nil, err
+					return err
 				}
+				cfg.report.recordItem(RESOURCE_PULL_REQUEST_REVIEWS, review.Review != nil, review.Review, ghReview)
 				review.Review = ghReview
 				review.PullRequestNumber = pr.GetNumber()
 				review.LastDetailFetch = time.Now()
-				if err := review.Save(rf.rootPath, rf.repo, cfg.PrettyJSON); err != nil {
-					return nil, err
-				}
-			}
+				return review.Save(rf.rootPath, rf.repo, cfg.PrettyJSON)
+			},
+		)
+		if err != nil {
+			return nil, err
 		}
 		pr.LastReviewsFetch = time.Now()
 		if err := pr.Save(rf.rootPath, rf.repo, cfg.PrettyJSON); err != nil {
@@ -110,12 +107,23 @@ func (rf *pullRequestReviewsFetcher) fetch(ctx context.Context, cfg *FetchConfig
 }
 
 func (rf *pullRequestReviewsFetcher) makeReviewCommentsFetcher(log Logger) ([]fetcher, error) {
-	// A list of the pull request reviews for which we should be fetching
-	// comments.
+	fetchReviewComments, err := reviewsWithStaleComments(rf.rootPath, rf.repo)
+	if err != nil {
+		return nil, err
+	}
+	fetchers := []fetcher{
+		newPullRequestReviewCommentsFetcher(rf.rootPath, rf.repo, fetchReviewComments),
+	}
+	return fetchers, nil
+}
+
+// reviewsWithStaleComments returns the local pull request reviews for which
+// we should be fetching comments.
+func reviewsWithStaleComments(rootPath string, repo *Repository) ([]*PullRequestReview, error) {
 	fetchReviewComments := []*PullRequestReview{}
 	// First we need to run through all pull requests, so we know when they were
 	// last updated.
-	prsPath := repoPullRequestsPath(rf.rootPath, rf.repo.GetOwner(), rf.repo.GetName())
+	prsPath := repoPullRequestsPath(rootPath, repo.GetOwner(), repo.GetName())
 	pattern := filepath.Join(prsPath, "*", DETAIL_FILENAME)
 	prFiles, err := filepath.Glob(pattern)
 	if err != nil {
@@ -128,7 +136,7 @@ func (rf *pullRequestReviewsFetcher) makeReviewCommentsFetcher(log Logger) ([]fe
 			return nil, err
 		}
 		// Now we scan all pull request reviews for this pull request
-		prReviewsPath := pullRequestReviewsPath(rf.rootPath, rf.repo.GetOwner(), rf.repo.GetName(), pr.GetNumber())
+		prReviewsPath := pullRequestReviewsPath(rootPath, repo.GetOwner(), repo.GetName(), pr.GetNumber())
 		pat := filepath.Join(prReviewsPath, "*", DETAIL_FILENAME)
 		reviewFiles, err := filepath.Glob(pat)
 		if err != nil {
@@ -144,9 +152,5 @@ func (rf *pullRequestReviewsFetcher) makeReviewCommentsFetcher(log Logger) ([]fe
 			}
 		}
 	}
-
-	fetchers := []fetcher{
-		newPullRequestReviewCommentsFetcher(rf.rootPath, rf.repo, fetchReviewComments),
-	}
-	return fetchers, nil
+	return fetchReviewComments, nil
 }
@@ -0,0 +1,267 @@
+package ghere
+
+import (
+	"context"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/google/go-github/v48/github"
+)
+
+// mustFetchReactions returns whether the per-user reactions stored for an item
+// are out of date with respect to the given summary of the item's reactions,
+// as provided by GitHub along with the item itself.
+//
+// Since GitHub does not bump an item's update time when reactions to it
+// change, we compare the number of reactions of each type instead.
+func mustFetchReactions(summary *github.Reactions, stored []*github.Reaction) bool {
+	counts := make(map[string]int)
+	for _, reaction := range stored {
+		counts[reaction.GetContent()]++
+	}
+	return summary.GetTotalCount() != len(stored) ||
+		summary.GetPlusOne() != counts["+1"] ||
+		summary.GetMinusOne() != counts["-1"] ||
+		summary.GetLaugh() != counts["laugh"] ||
+		summary.GetConfused() != counts["confused"] ||
+		summary.GetHeart() != counts["heart"] ||
+		summary.GetHooray() != counts["hooray"] ||
+		summary.GetRocket() != counts["rocket"] ||
+		summary.GetEyes() != counts["eyes"]
+}
+
+// listAllReactions fetches all pages of reactions using the given listing
+// function.
+func listAllReactions(list func(page int) ([]*github.Reaction, bool, error)) ([]*github.Reaction, error) {
+	all := []*github.Reaction{}
+	done := false
+	for page := 1; !done; page++ {
+		var reactions []*github.Reaction
+		var err error
+		reactions, done, err = list(page)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, reactions...)
+	}
+	return all, nil
+}
+
+// fetchReactions updates the issue's per-user reactions if reaction fetching
+// is enabled and they are out of date.
+func (i *Issue) fetchReactions(ctx context.Context, cfg *FetchConfig, repo *Repository) error {
+	if !cfg.FetchReactions || !mustFetchReactions(i.Issue.GetReactions(), i.Reactions) {
+		return nil
+	}
+	reactions, err := listAllReactions(func(page int) ([]*github.Reaction, bool, error) {
+		return cfg.Client.ListIssueReactions(ctx, repo.GetOwner(), repo.GetName(), i.GetNumber(), page)
+	})
+	if err != nil {
+		return err
+	}
+	i.Reactions = reactions
+	return nil
+}
+
+// fetchReactions updates the comment's per-user reactions if reaction
+// fetching is enabled and they are out of date.
+func (c *IssueComment) fetchReactions(ctx context.Context, cfg *FetchConfig, repo *Repository) error {
+	if !cfg.FetchReactions || !mustFetchReactions(c.Comment.GetReactions(), c.Reactions) {
+		return nil
+	}
+	reactions, err := listAllReactions(func(page int) ([]*github.Reaction, bool, error) {
+		return cfg.Client.ListIssueCommentReactions(ctx, repo.GetOwner(), repo.GetName(), c.Comment.GetID(), page)
+	})
+	if err != nil {
+		return err
+	}
+	c.Reactions = reactions
+	return nil
+}
+
+// fetchReactions updates the release's per-user reactions if reaction fetching
+// is enabled and they are out of date according to the given summary.
+func (r *Release) fetchReactions(ctx context.Context, cfg *FetchConfig, repo *Repository, summary *github.Reactions) error {
+	if !cfg.FetchReactions || !mustFetchReactions(summary, r.Reactions) {
+		return nil
+	}
+	reactions, err := listAllReactions(func(page int) ([]*github.Reaction, bool, error) {
+		return cfg.Client.ListReleaseReactions(ctx, repo.GetOwner(), repo.GetName(), r.Release.GetID(), page)
+	})
+	if err != nil {
+		return err
+	}
+	cfg.report.recordItem(RESOURCE_RELEASE_REACTIONS, true, r.Reactions, reactions)
+	r.Reactions = reactions
+	return nil
+}
+
+// fetchReactions updates the comment's per-user reactions if reaction
+// fetching is enabled and they are out of date.
+func (c *PullRequestComment) fetchReactions(ctx context.Context, cfg *FetchConfig, repo *Repository) error {
+	if !cfg.FetchReactions || !mustFetchReactions(c.Comment.GetReactions(), c.Reactions) {
+		return nil
+	}
+	reactions, err := listAllReactions(func(page int) ([]*github.Reaction, bool, error) {
+		return cfg.Client.ListPullRequestCommentReactions(ctx, repo.GetOwner(), repo.GetName(), c.Comment.GetID(), page)
+	})
+	if err != nil {
+		return err
+	}
+	c.Reactions = reactions
+	return nil
+}
+
+// commentReactionsFetcher refreshes the per-user reactions to all locally
+// stored issue comments and pull request review comments whose reactions
+// changed. Since reactions bump neither the update time of comments nor that
+// of the issues and pull requests they belong to, we list all of the
+// repository's comments to obtain their latest reaction summaries. As this is
+// costly for large repositories, it is done at most once per
+// FetchConfig.CommentReactionsInterval.
+type commentReactionsFetcher struct {
+	rootPath     string
+	repo         *Repository
+	issues       bool
+	pullRequests bool
+}
+
+var _ fetcher = (*commentReactionsFetcher)(nil)
+
+func newCommentReactionsFetcher(rootPath string, repo *Repository, issues, pullRequests bool) *commentReactionsFetcher {
+	return &commentReactionsFetcher{
+		rootPath:     rootPath,
+		repo:         repo,
+		issues:       issues,
+		pullRequests: pullRequests,
+	}
+}
+
+func (f *commentReactionsFetcher) resource() string {
+	return RESOURCE_COMMENT_REACTIONS
+}
+
+func (f *commentReactionsFetcher) fetch(ctx context.Context, cfg *FetchConfig, log Logger) ([]fetcher, error) {
+	fetchTime := time.Now()
+	updated := 0
+	if f.issues {
+		done := false
+		for page := 1; !done; page++ {
+			var comments []*github.IssueComment
+			var err error
+			comments, done, err = cfg.Client.ListRepositoryIssueComments(ctx, f.repo.GetOwner(), f.repo.GetName(), page)
+			if err != nil {
+				return nil, err
+			}
+			for _, ghComment := range comments {
+				ok, err := f.updateIssueComment(ctx, cfg, ghComment)
+				if err != nil {
+					return nil, err
+				}
+				if ok {
+					updated++
+				}
+			}
+		}
+	}
+	if f.pullRequests {
+		done := false
+		for page := 1; !done; page++ {
+			var comments []*github.PullRequestComment
+			var err error
+			comments, done, err = cfg.Client.ListRepositoryPullRequestComments(ctx, f.repo.GetOwner(), f.repo.GetName(), page)
+			if err != nil {
+				return nil, err
+			}
+			for _, ghComment := range comments {
+				ok, err := f.updatePullRequestComment(ctx, cfg, ghComment)
+				if err != nil {
+					return nil, err
+				}
+				if ok {
+					updated++
+				}
+			}
+		}
+	}
+	log.Info("Refreshed comments' reactions", "repo", f.repo.String(), "comments", updated)
+	f.repo.LastCommentReactionsFetch = fetchTime
+	return nil, f.repo.Save(f.rootPath, cfg.PrettyJSON)
+}
+
+// updateIssueComment refreshes the reactions to the locally stored version of
+// the given comment, if there is one and its reactions are out of date.
+func (f *commentReactionsFetcher) updateIssueComment(ctx context.Context, cfg *FetchConfig, ghComment *github.IssueComment) (bool, error) {
+	issueNum, ok := numberFromURL(ghComment.GetIssueURL())
+	if !ok {
+		return false, nil
+	}
+	comment, err := LoadIssueComment(f.rootPath, f.repo, issueNum, ghComment.GetID(), false)
+	if err != nil {
+		return false, err
+	}
+	// Comments that have not been fetched yet (including comments on pull
+	// requests, which we do not track) are left to the comments fetchers.
+	if comment.Comment == nil || !mustFetchReactions(ghComment.GetReactions(), comment.Reactions) {
+		return false, nil
+	}
+	prev := comment.Reactions
+	comment.Comment = ghComment
+	if err := comment.fetchReactions(ctx, cfg, f.repo); err != nil {
+		return false, err
+	}
+	cfg.report.recordItem(RESOURCE_COMMENT_REACTIONS, true, prev, comment.Reactions)
+	return true, comment.Save(f.rootPath, f.repo, issueNum, cfg.PrettyJSON)
+}
+
+// updatePullRequestComment refreshes the reactions to the locally stored
+// versions of the given review comment, if there are any and their reactions
+// are out of date.
+func (f *commentReactionsFetcher) updatePullRequestComment(ctx context.Context, cfg *FetchConfig, ghComment *github.PullRequestComment) (bool, error) {
+	prNum, ok := numberFromURL(ghComment.GetPullRequestURL())
+	if !ok {
+		return false, nil
+	}
+	comment, err := LoadPullRequestComment(f.rootPath, f.repo, prNum, ghComment.GetID(), false)
+	if err != nil {
+		return false, err
+	}
+	if comment.Comment == nil || !mustFetchReactions(ghComment.GetReactions(), comment.Reactions) {
+		return false, nil
+	}
+	prev := comment.Reactions
+	comment.Comment = ghComment
+	if err := comment.fetchReactions(ctx, cfg, f.repo); err != nil {
+		return false, err
+	}
+	cfg.report.recordItem(RESOURCE_COMMENT_REACTIONS, true, prev, comment.Reactions)
+	if err := comment.Save(f.rootPath, f.repo, prNum, cfg.PrettyJSON); err != nil {
+		return false, err
+	}
+	// Review comments are also stored along with the reviews they belong to.
+	reviewID := ghComment.GetPullRequestReviewID()
+	if reviewID == 0 {
+		return true, nil
+	}
+	reviewComment, err := LoadPullRequestReviewComment(f.rootPath, f.repo, prNum, reviewID, ghComment.GetID(), false)
+	if err != nil {
+		return false, err
+	}
+	if reviewComment.Comment == nil {
+		return true, nil
+	}
+	reviewComment.Comment = ghComment
+	reviewComment.Reactions = comment.Reactions
+	return true, reviewComment.SaveForReview(f.rootPath, f.repo, prNum, reviewID, cfg.PrettyJSON)
+}
+
+// numberFromURL extracts the issue or pull request number from the given API
+// URL (e.g. ".../issues/123").
+func numberFromURL(u string) (int, bool) {
+	num, err := strconv.Atoi(path.Base(u))
+	if err != nil {
+		return 0, false
+	}
+	return num, true
+}
@@ -0,0 +1,121 @@
+package ghere
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/google/go-github/v48/github"
+)
+
+type Release struct {
+	Release *github.RepositoryRelease `json:"release"`
+	// Reactions optionally lists the individual reactions to the release.
+	Reactions []*github.Reaction `json:"reactions,omitempty"`
+	// Deleted is true if the release was deleted on GitHub (as reported by a
+	// webhook event).
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+func LoadRelease(rootPath string, repo *Repository, releaseID int64, mustExist bool) (*Release, error) {
+	path := releasePath(rootPath, repo.GetOwner(), repo.GetName(), releaseID)
+	return LoadReleaseDirect(path, mustExist)
+}
+
+func LoadReleaseDirect(path string, mustExist bool) (*Release, error) {
+	var err error
+	release := &Release{}
+	if mustExist {
+		err = readJSONFile(path, release)
+	} else {
+		err = readJSONFileOrEmpty(path, release)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read release file: %v", err)
+	}
+	return release, nil
+}
+
+func (r *Release) Save(rootPath string, repo *Repository, prettyJSON bool) error {
+	path := releasePath(rootPath, repo.GetOwner(), repo.GetName(), r.Release.GetID())
+	if err := writeJSONFile(path, r, prettyJSON); err != nil {
+		return fmt.Errorf("failed to write release file: %v", err)
+	}
+	return nil
+}
+
+// releasesFetcher lists all of a repository's releases, along with their
+// reactions (if enabled). Releases do not affect the repository's update
+// time, so they are listed on every fetch.
+type releasesFetcher struct {
+	rootPath string
+	repo     *Repository
+}
+
+var _ fetcher = (*releasesFetcher)(nil)
+var _ planner = (*releasesFetcher)(nil)
+
+func newReleasesFetcher(rootPath string, repo *Repository) *releasesFetcher {
+	return &releasesFetcher{
+		rootPath: rootPath,
+		repo:     repo,
+	}
+}
+
+func (f *releasesFetcher) resource() string {
+	return RESOURCE_RELEASES
+}
+
+func (f *releasesFetcher) fetch(ctx context.Context, cfg *FetchConfig, log Logger) ([]fetcher, error) {
+	var releases []*GitHubRelease
+	var err error
+	seen := make(map[int64]bool)
+	done := false
+	for page := 1; !done; page++ {
+		releases, done, err = cfg.Client.ListReleases(ctx, f.repo.GetOwner(), f.repo.GetName(), page)
+		if err != nil {
+			return nil, err
+		}
+		for _, ghRelease := range releases {
+			release, err := LoadRelease(f.rootPath, f.repo, ghRelease.GetID(), false)
+			if err != nil {
+				return nil, err
+			}
+			seen[ghRelease.GetID()] = true
+			cfg.report.recordItem(RESOURCE_RELEASES, release.Release != nil, release.Release, ghRelease.RepositoryRelease)
+			release.Release = ghRelease.RepositoryRelease
+			if err := release.fetchReactions(ctx, cfg, f.repo, ghRelease.Reactions); err != nil {
+				return nil, err
+			}
+			if err := release.Save(f.rootPath, f.repo, cfg.PrettyJSON); err != nil {
+				return nil, err
+			}
+		}
+	}
+	releasesPath := repoReleasesPath(f.rootPath, f.repo.GetOwner(), f.repo.GetName())
+	deleted, err := countDeletedItems(filepath.Join(releasesPath, "*.json"), seen, func(path string) string { return path })
+	if err != nil {
+		return nil, err
+	}
+	cfg.report.recordDeleted(RESOURCE_RELEASES, deleted)
+	log.Info("Fetched releases", "repo", f.repo.String(), "releases", len(seen))
+	return nil, nil
+}
+
+func (f *releasesFetcher) plan(cfg *FetchConfig) (*FetcherPlan, error) {
+	releasesPath := repoReleasesPath(f.rootPath, f.repo.GetOwner(), f.repo.GetName())
+	count, err := countLocalItems(filepath.Join(releasesPath, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	pages := estimatePages(count, nil)
+	description := fmt.Sprintf("list releases (~%d page(s))", pages)
+	if cfg.FetchReactions {
+		description += ", then fetch reactions to releases whose reactions changed"
+	}
+	return &FetcherPlan{
+		Resource:          RESOURCE_RELEASES,
+		Description:       description,
+		EstimatedAPICalls: pages,
+	}, nil
+}
@@ -0,0 +1,285 @@
+package ghere
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v48/github"
+)
+
+// The types of resources (in addition to the ones that can be selectively
+// fetched) for which item changes are reported.
+const (
+	RESOURCE_REPOSITORY                   string = "repository"
+	RESOURCE_PULL_REQUEST_REVIEWS         string = "pull_request_reviews"
+	RESOURCE_PULL_REQUEST_COMMENTS        string = "pull_request_comments"
+	RESOURCE_PULL_REQUEST_REVIEW_COMMENTS string = "pull_request_review_comments"
+	RESOURCE_PULL_REQUEST_EVENTS          string = "pull_request_events"
+	RESOURCE_ISSUE_COMMENTS               string = "issue_comments"
+	RESOURCE_ISSUE_EVENTS                 string = "issue_events"
+	RESOURCE_RELEASE_REACTIONS            string = "release_reactions"
+	RESOURCE_COMMENT_REACTIONS            string = "comment_reactions"
+	RESOURCE_DISCUSSION_CATEGORIES        string = "discussion_categories"
+	RESOURCE_DISCUSSION_COMMENTS          string = "discussion_comments"
+	RESOURCE_PROJECT_FIELDS               string = "project_fields"
+	RESOURCE_PROJECT_ITEMS                string = "project_items"
+	RESOURCE_WORKFLOWS                    string = "workflows"
+	RESOURCE_WORKFLOW_RUNS                string = "workflow_runs"
+	RESOURCE_WORKFLOW_JOBS                string = "workflow_jobs"
+	RESOURCE_WORKFLOW_ARTIFACTS           string = "workflow_artifacts"
+)
+
+// The types of failures that can occur when fetching a repository.
+const (
+	FETCH_ERROR_INTERRUPTED string = "interrupted"
+	FETCH_ERROR_RATE_LIMIT  string = "rate_limit"
+	FETCH_ERROR_AUTH        string = "auth"
+	FETCH_ERROR_NOT_FOUND   string = "not_found"
+	FETCH_ERROR_NETWORK     string = "network"
+	FETCH_ERROR_GIT         string = "git"
+	FETCH_ERROR_OTHER       string = "other"
+)
+
+// FetchErrorTypes lists all of the types of failures that can occur when
+// fetching a repository.
+var FetchErrorTypes = []string{
+	FETCH_ERROR_INTERRUPTED,
+	FETCH_ERROR_RATE_LIMIT,
+	FETCH_ERROR_AUTH,
+	FETCH_ERROR_NOT_FOUND,
+	FETCH_ERROR_NETWORK,
+	FETCH_ERROR_GIT,
+	FETCH_ERROR_OTHER,
+}
+
+// FetchReport is a machine-readable summary of a fetch operation on a
+// collection.
+type FetchReport struct {
+	StartedAt       time.Time           `json:"started_at"`
+	FinishedAt      time.Time           `json:"finished_at"`
+	DurationSeconds float64             `json:"duration_seconds"`
+	Repositories    []*RepositoryReport `json:"repositories"`
+}
+
+// Failed returns the reports of the repositories that could not be
+// completely fetched.
+func (r *FetchReport) Failed() []*RepositoryReport {
+	failed := []*RepositoryReport{}
+	for _, repoReport := range r.Repositories {
+		if len(repoReport.Error) > 0 {
+			failed = append(failed, repoReport)
+		}
+	}
+	return failed
+}
+
+// RepositoryReport summarizes the outcome of fetching a single repository.
+type RepositoryReport struct {
+	Repository      string    `json:"repository"`
+	StartedAt       time.Time `json:"started_at"`
+	FinishedAt      time.Time `json:"finished_at"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	// Resources summarizes the changes to items of each type of resource, as
+	// well as the time spent fetching them.
+	Resources map[string]*ResourceReport `json:"resources"`
+	// APICalls is the number of requests made to the GitHub API (including
+	// retries) while fetching this repository.
+	APICalls int `json:"api_calls"`
+	// Retries is the number of requests that were retried.
+	Retries int `json:"retries"`
+	// RateLimit and RateLimitRemaining capture the primary rate limit status
+	// (across all tokens) after fetching this repository.
+	RateLimit          int `json:"rate_limit"`
+	RateLimitRemaining int `json:"rate_limit_remaining"`
+	// GitRefsUpdated lists the Git references changed by updating the
+	// repository's code.
+	GitRefsUpdated []*GitRefUpdate `json:"git_refs_updated,omitempty"`
+	// Error, if not empty, is the error that prevented the repository from
+	// being completely fetched.
+	Error string `json:"error,omitempty"`
+	// ErrorType classifies Error as one of the FETCH_ERROR_* constants.
+	ErrorType string `json:"error_type,omitempty"`
+	// ErrorResource is the type of resource that was being fetched when the
+	// error occurred.
+	ErrorResource string `json:"error_resource,omitempty"`
+
+	mtx sync.Mutex
+}
+
+// ResourceReport summarizes the changes to items of a particular type of
+// resource of a repository.
+type ResourceReport struct {
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+	// Deleted counts the items that exist locally, but no longer exist on
+	// GitHub. Local copies of such items are retained. Only detected for
+	// labels, issues and pull requests, and only when they are listed in
+	// full.
+	Deleted         int     `json:"deleted"`
+	DurationSeconds float64 `json:"duration_seconds"`
+}
+
+func newRepositoryReport(repo string) *RepositoryReport {
+	return &RepositoryReport{
+		Repository: repo,
+		StartedAt:  time.Now(),
+		Resources:  make(map[string]*ResourceReport),
+	}
+}
+
+func (r *RepositoryReport) resource(resource string) *ResourceReport {
+	rr, exists := r.Resources[resource]
+	if !exists {
+		rr = &ResourceReport{}
+		r.Resources[resource] = rr
+	}
+	return rr
+}
+
+// recordItem records the change to a single item, given whether it existed
+// locally, its previous local version, and its latest version. All methods on
+// a nil report do nothing.
+func (r *RepositoryReport) recordItem(resource string, existed bool, prev, latest interface{}) {
+	if r == nil {
+		return
+	}
+	changed := true
+	if existed {
+		// Comparing serialized versions avoids false positives from in-memory
+		// representational differences (e.g. of timestamps).
+		prevJSON, prevErr := json.Marshal(prev)
+		latestJSON, latestErr := json.Marshal(latest)
+		changed = prevErr != nil || latestErr != nil || !bytes.Equal(prevJSON, latestJSON)
+	}
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	rr := r.resource(resource)
+	switch {
+	case !existed:
+		rr.Created++
+	case changed:
+		rr.Updated++
+	default:
+		rr.Unchanged++
+	}
+}
+
+func (r *RepositoryReport) recordDeleted(resource string, count int) {
+	if r == nil || count == 0 {
+		return
+	}
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.resource(resource).Deleted += count
+}
+
+func (r *RepositoryReport) recordDuration(resource string, d time.Duration) {
+	if r == nil {
+		return
+	}
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.resource(resource).DurationSeconds += d.Seconds()
+}
+
+func (r *RepositoryReport) recordGitRefs(updates []*GitRefUpdate) {
+	if r == nil {
+		return
+	}
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.GitRefsUpdated = append(r.GitRefsUpdated, updates...)
+}
+
+func (r *RepositoryReport) recordError(resource string, err error) {
+	if r == nil {
+		return
+	}
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	// Only the first (i.e. most specific) error is recorded.
+	if len(r.Error) > 0 {
+		return
+	}
+	r.Error = err.Error()
+	r.ErrorResource = resource
+	r.ErrorType = FetchErrorType(err)
+	if r.ErrorType == FETCH_ERROR_OTHER && resource == RESOURCE_CODE {
+		r.ErrorType = FETCH_ERROR_GIT
+	}
+}
+
+// finish records the end time of the fetch of the repository, as well as the
+// API usage recorded by the given request counter and the client's current
+// rate limit status.
+func (r *RepositoryReport) finish(client GitHubClient, counter *requestCounter) {
+	r.FinishedAt = time.Now()
+	r.DurationSeconds = r.FinishedAt.Sub(r.StartedAt).Seconds()
+	if counter != nil {
+		r.APICalls, r.Retries = counter.counts()
+	}
+	if sp, ok := client.(GitHubClientStatsProvider); ok {
+		stats := sp.Stats()
+		r.RateLimit = stats.RateLimit
+		r.RateLimitRemaining = stats.RateLimitRemaining
+	}
+}
+
+// countDeletedItems counts the local items matching the given pattern whose
+// keys were not seen in a complete listing of the items on GitHub. The key of
+// each item is obtained from the base name of the file or directory given by
+// keyPath.
+func countDeletedItems(pattern string, seen map[int64]bool, keyPath func(path string) string) (int, error) {
+	filenames, err := filepath.Glob(pattern)
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up local items %s: %v", pattern, err)
+	}
+	deleted := 0
+	for _, fn := range filenames {
+		base := filepath.Base(keyPath(fn))
+		key, err := strconv.ParseInt(strings.TrimSuffix(base, filepath.Ext(base)), 10, 64)
+		if err != nil {
+			continue
+		}
+		if !seen[key] {
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// FetchErrorType classifies the given error, which resulted from fetching a
+// repository, as one of the FETCH_ERROR_* constants.
+func FetchErrorType(err error) string {
+	var rateLimitErr *github.RateLimitError
+	var abuseErr *github.AbuseRateLimitError
+	var errRes *github.ErrorResponse
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.Canceled):
+		return FETCH_ERROR_INTERRUPTED
+	case errors.As(err, &rateLimitErr), errors.As(err, &abuseErr):
+		return FETCH_ERROR_RATE_LIMIT
+	case errors.As(err, &errRes) && errRes.Response != nil:
+		switch errRes.Response.StatusCode {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return FETCH_ERROR_AUTH
+		case http.StatusNotFound:
+			return FETCH_ERROR_NOT_FOUND
+		}
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr):
+		return FETCH_ERROR_NETWORK
+	}
+	return FETCH_ERROR_OTHER
+}
@@ -18,6 +18,25 @@ type Repository struct {
 	LastIssuesFetch              time.Time `json:"last_issues_fetch"`
 	LastIssueCommentsFetch       time.Time `json:"last_issue_comments_fetch"`
 	LastLabelsFetch              time.Time `json:"last_labels_fetch"`
+	LastDiscussionsFetch         time.Time `json:"last_discussions_fetch"`
+	// LastWorkflowRunID is the ID of the most recent workflow run fetched, and
+	// PendingWorkflowRunIDs the IDs of fetched workflow runs that had not yet
+	// completed at the time.
+	LastWorkflowRunID     int64   `json:"last_workflow_run_id,omitempty"`
+	PendingWorkflowRunIDs []int64 `json:"pending_workflow_run_ids,omitempty"`
+	// LastChecksFetch is the time at which the statuses and checks of the
+	// default branch's latest commits were last fetched.
+	LastChecksFetch time.Time `json:"last_checks_fetch"`
+	// LastEventID is the ID of the most recent event from the repository's
+	// events feed whose changes have been fetched.
+	LastEventID string `json:"last_event_id,omitempty"`
+	// LastCommentReactionsFetch is the time at which the reactions to all of
+	// the repository's comments were last refreshed.
+	LastCommentReactionsFetch time.Time `json:"last_comment_reactions_fetch"`
+
+	// MissingSubmodules records the submodule commits that could not be
+	// retrieved during the last code fetch.
+	MissingSubmodules []*MissingSubmodule `json:"missing_submodules,omitempty"`
 }
 
 func LoadRepository(rootPath, owner, name string, mustExist bool) (*Repository, error) {
@@ -59,6 +78,12 @@ func (r *Repository) MustFetchLabels() bool {
 	return r.Repository.GetUpdatedAt().After(r.LastLabelsFetch)
 }
 
+// MustFetchCommentReactions returns whether the reactions to the
+// repository's comments were last refreshed at least the given interval ago.
+func (r *Repository) MustFetchCommentReactions(interval time.Duration) bool {
+	return time.Since(r.LastCommentReactionsFetch) >= interval
+}
+
 func (r *Repository) String() string {
 	return r.GetOwner() + "/" + r.GetName()
 }
@@ -80,54 +105,192 @@ func (r *Repository) Save(rootPath string, prettyJSON bool) error {
 }
 
 type repoFetcher struct {
-	rootPath string
-	owner    string
-	name     string
-	repo     *Repository
+	rootPath  string
+	owner     string
+	name      string
+	gitOpts   *GitOptions
+	resources resourceSet
+	repo      *Repository
 }
 
-var _ fetcher = (*repoFetcher)(nil)
+var (
+	_ fetcher       = (*repoFetcher)(nil)
+	_ targetPlanner = (*repoFetcher)(nil)
+)
 
-func newRepoFetcher(rootPath, owner, name string) *repoFetcher {
+func newRepoFetcher(rootPath, owner, name string, gitOpts *GitOptions, resources resourceSet) *repoFetcher {
 	return &repoFetcher{
-		rootPath: rootPath,
-		owner:    owner,
-		name:     name,
+		rootPath:  rootPath,
+		owner:     owner,
+		name:      name,
+		gitOpts:   gitOpts,
+		resources: resources,
 	}
 }
 
+func (rf *repoFetcher) resource() string {
+	return RESOURCE_REPOSITORY
+}
+
 func (rf *repoFetcher) fetch(ctx context.Context, cfg *FetchConfig, log Logger) ([]fetcher, error) {
+	if cfg.Restart {
+		if err := removeFetchCheckpoints(repoCheckpointsPath(rf.rootPath, rf.owner, rf.name)); err != nil {
+			return nil, err
+		}
+	}
 	var err error
 	rf.repo, err = LoadRepository(rf.rootPath, rf.owner, rf.name, false)
 	if err != nil {
 		return nil, err
 	}
-	rf.repo.Repository, err = cfg.Client.GetRepository(ctx, rf.owner, rf.name)
+	ghRepo, err := cfg.Client.GetRepository(ctx, rf.owner, rf.name)
 	if err != nil {
 		return nil, err
 	}
+	cfg.report.recordItem(RESOURCE_REPOSITORY, rf.repo.Repository != nil, rf.repo.Repository, ghRepo)
+	rf.repo.Repository = ghRepo
 	rf.repo.LastDetailFetch = time.Now()
 	if err := rf.repo.Save(rf.rootPath, cfg.PrettyJSON); err != nil {
 		return nil, err
 	}
-	fetchers := []fetcher{newCodeFetcher(rf.rootPath, rf.repo)}
-	if rf.repo.MustFetchLabels() {
+	var changes *repositoryChanges
+	if cfg.UseEvents && (rf.resources[RESOURCE_ISSUES] || rf.resources[RESOURCE_PULL_REQUESTS]) {
+		changes, err = detectRepositoryChanges(ctx, cfg, rf.repo, log)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return rf.childFetchers(cfg, changes), nil
+}
+
+// childFetchers determines which of the selected resources of the repository
+// are stale and must therefore be fetched. If changes detected from the
+// repository's events feed are supplied, only the issues and pull requests
+// that changed are fetched, unless not all changes could be detected, in which
+// case all issues and pull requests are listed (regardless of whether the
+// repository appears to be stale, since its update time does not reflect most
+// changes to issues and pull requests).
+func (rf *repoFetcher) childFetchers(cfg *FetchConfig, changes *repositoryChanges) []fetcher {
+	fetchers := []fetcher{}
+	if rf.resources[RESOURCE_CODE] {
+		fetchers = append(fetchers, newCodeFetcher(rf.rootPath, rf.repo, rf.gitOpts))
+	}
+	if rf.resources[RESOURCE_LABELS] && rf.repo.MustFetchLabels() {
 		fetchers = append(fetchers, newLabelsFetcher(
 			rf.rootPath,
 			rf.repo,
 		))
 	}
-	if rf.repo.MustFetchPullRequests() {
-		fetchers = append(fetchers, newPullRequestsFetcher(
-			rf.rootPath,
-			rf.repo,
-		))
+	if rf.resources[RESOURCE_PULL_REQUESTS] {
+		switch {
+		case changes != nil && changes.complete:
+			if len(changes.pullRequests) > 0 {
+				fetchers = append(fetchers, newPullRequestUpdatesFetcher(
+					rf.rootPath,
+					rf.repo,
+					sortedNumbers(changes.pullRequests),
+					rf.resources[RESOURCE_TIMELINES],
+				))
+			}
+		case changes != nil || rf.repo.MustFetchPullRequests():
+			fetchers = append(fetchers, newPullRequestsFetcher(
+				rf.rootPath,
+				rf.repo,
+				rf.resources[RESOURCE_TIMELINES],
+			))
+		}
+	}
+	if rf.resources[RESOURCE_ISSUES] {
+		switch {
+		case changes != nil && changes.complete:
+			if len(changes.issues) > 0 {
+				fetchers = append(fetchers, newIssueUpdatesFetcher(
+					rf.rootPath,
+					rf.repo,
+					sortedNumbers(changes.issues),
+					rf.resources[RESOURCE_TIMELINES],
+				))
+			}
+		case changes != nil || rf.repo.MustFetchIssues():
+			fetchers = append(fetchers, newIssuesFetcher(
+				rf.rootPath,
+				rf.repo,
+				rf.resources[RESOURCE_TIMELINES],
+			))
+		}
+	}
+	if rf.resources[RESOURCE_DISCUSSIONS] {
+		fetchers = append(fetchers, newDiscussionsFetcher(rf.rootPath, rf.repo))
+	}
+	if rf.resources[RESOURCE_ACTIONS] {
+		fetchers = append(fetchers, newActionsFetcher(rf.rootPath, rf.repo))
 	}
-	if rf.repo.MustFetchIssues() {
-		fetchers = append(fetchers, newIssuesFetcher(
+	if rf.resources[RESOURCE_CHECKS] {
+		fetchers = append(fetchers, newChecksFetcher(rf.rootPath, rf.repo))
+	}
+	if rf.resources[RESOURCE_RELEASES] {
+		fetchers = append(fetchers, newReleasesFetcher(rf.rootPath, rf.repo))
+	}
+	if cfg.FetchReactions && (rf.resources[RESOURCE_ISSUES] || rf.resources[RESOURCE_PULL_REQUESTS]) && rf.repo.MustFetchCommentReactions(cfg.CommentReactionsInterval) {
+		fetchers = append(fetchers, newCommentReactionsFetcher(
 			rf.rootPath,
 			rf.repo,
+			rf.resources[RESOURCE_ISSUES],
+			rf.resources[RESOURCE_PULL_REQUESTS],
 		))
 	}
-	return fetchers, nil
+	// The events feed's changes to issues and pull requests are only all
+	// fetched if both are selected, so the cursor must otherwise not be
+	// advanced lest the changes to the unselected resource be skipped on
+	// subsequent fetches.
+	if changes != nil && len(changes.latestEventID) > 0 && rf.resources[RESOURCE_ISSUES] && rf.resources[RESOURCE_PULL_REQUESTS] {
+		fetchers = append(fetchers, newEventCursorFetcher(rf.rootPath, rf.repo, changes.latestEventID))
+	}
+	return fetchers
+}
+
+// plan fetches the repository's details (without saving them) and computes
+// which of its resources would be fetched.
+func (rf *repoFetcher) plan(ctx context.Context, cfg *FetchConfig) (*RepositoryFetchPlan, error) {
+	var err error
+	rf.repo, err = LoadRepository(rf.rootPath, rf.owner, rf.name, false)
+	if err != nil {
+		return nil, err
+	}
+	rf.repo.Repository, err = cfg.Client.GetRepository(ctx, rf.owner, rf.name)
+	if err != nil {
+		return nil, err
+	}
+	repoPlan := &RepositoryFetchPlan{
+		Fetchers:          []*FetcherPlan{},
+		EstimatedAPICalls: 1,
+	}
+	// Which issues and pull requests changed can only be determined by
+	// actually reading the events feed, so their plans assume that they would
+	// be listed in full (as when not all changes can be detected).
+	if cfg.UseEvents && (rf.resources[RESOURCE_ISSUES] || rf.resources[RESOURCE_PULL_REQUESTS]) {
+		repoPlan.Fetchers = append(repoPlan.Fetchers, &FetcherPlan{
+			Resource: RESOURCE_REPOSITORY,
+			Description: fmt.Sprintf(
+				"read up to %d page(s) of the events feed to detect changed issues and pull requests (not estimated: the plans below assume that issues and pull requests are listed in full)",
+				maxRepositoryEventPages,
+			),
+			EstimatedAPICalls: 1,
+		})
+		repoPlan.EstimatedAPICalls++
+	}
+	for _, f := range rf.childFetchers(cfg, nil) {
+		p, ok := f.(planner)
+		if !ok {
+			continue
+		}
+		fetcherPlan, err := p.plan(cfg)
+		if err != nil {
+			return nil, err
+		}
+		repoPlan.Fetchers = append(repoPlan.Fetchers, fetcherPlan)
+		repoPlan.EstimatedAPICalls += fetcherPlan.EstimatedAPICalls
+		repoPlan.EstimatedGitOperations += len(fetcherPlan.GitOperations)
+	}
+	return repoPlan, nil
 }
@@ -0,0 +1,141 @@
+package ghere
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The types of resources that can be selectively fetched for each repository.
+const (
+	RESOURCE_CODE          string = "code"
+	RESOURCE_LABELS        string = "labels"
+	RESOURCE_PULL_REQUESTS string = "pull_requests"
+	RESOURCE_ISSUES        string = "issues"
+	RESOURCE_DISCUSSIONS   string = "discussions"
+	RESOURCE_ACTIONS       string = "actions"
+	RESOURCE_CHECKS        string = "checks"
+	RESOURCE_RELEASES      string = "releases"
+	// Timelines are the timeline events of the issues and pull requests that
+	// are fetched.
+	RESOURCE_TIMELINES string = "timelines"
+	// Projects are fetched separately from repositories, and are therefore
+	// only selected/skipped for the projects in a collection.
+	RESOURCE_PROJECTS string = "projects"
+	// Teams are fetched separately from repositories, for the organizations
+	// in a collection.
+	RESOURCE_TEAMS string = "teams"
+)
+
+// AllResources lists all of the types of resources that can be fetched for
+// each repository. Only the DefaultResources are fetched unless others are
+// explicitly selected.
+var AllResources = []string{
+	RESOURCE_CODE,
+	RESOURCE_LABELS,
+	RESOURCE_PULL_REQUESTS,
+	RESOURCE_ISSUES,
+	RESOURCE_DISCUSSIONS,
+	RESOURCE_ACTIONS,
+	RESOURCE_CHECKS,
+	RESOURCE_RELEASES,
+	RESOURCE_TIMELINES,
+	RESOURCE_PROJECTS,
+	RESOURCE_TEAMS,
+}
+
+// DefaultResources lists the types of resources that are fetched unless
+// explicitly skipped. Discussions, actions, checks and timelines can be costly
+// to fetch, and are therefore only fetched when explicitly selected.
+var DefaultResources = []string{
+	RESOURCE_CODE,
+	RESOURCE_LABELS,
+	RESOURCE_PULL_REQUESTS,
+	RESOURCE_ISSUES,
+	RESOURCE_RELEASES,
+	RESOURCE_PROJECTS,
+	RESOURCE_TEAMS,
+}
+
+// ResourceSelection restricts which types of resources are fetched.
+type ResourceSelection struct {
+	// Include additionally selects these types of resources that are not
+	// fetched by default.
+	Include []string `json:"include,omitempty"`
+	// Only, if not empty, restricts fetching to these types of resources.
+	// Resources that are not fetched by default are also selected when listed
+	// here.
+	Only []string `json:"only,omitempty"`
+	// Skip excludes these types of resources from being fetched.
+	Skip []string `json:"skip,omitempty"`
+}
+
+// Validate ensures that only known resource types have been specified.
+func (s *ResourceSelection) Validate() error {
+	if s == nil {
+		return nil
+	}
+	for _, resources := range [][]string{s.Include, s.Only, s.Skip} {
+		for _, resource := range resources {
+			if !isKnownResource(resource) {
+				return fmt.Errorf("unknown resource type \"%s\" (must be one of: %s)", resource, strings.Join(AllResources, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+func isKnownResource(resource string) bool {
+	for _, r := range AllResources {
+		if r == resource {
+			return true
+		}
+	}
+	return false
+}
+
+// resourceSet is the set of types of resources to fetch.
+type resourceSet map[string]bool
+
+// selectResources computes the set of resources to fetch given one or more
+// selections (e.g. a repository's defaults, followed by those specified for a
+// particular fetch). Starting from the default resources, along with any other
+// resources explicitly included or listed in any of the selections, each
+// selection can only further restrict the resources selected by the ones
+// before it.
+func selectResources(selections ...*ResourceSelection) resourceSet {
+	set := make(resourceSet)
+	for _, r := range DefaultResources {
+		set[r] = true
+	}
+	for _, sel := range selections {
+		if sel == nil {
+			continue
+		}
+		for _, r := range sel.Include {
+			set[r] = true
+		}
+		for _, r := range sel.Only {
+			set[r] = true
+		}
+	}
+	for _, sel := range selections {
+		if sel == nil {
+			continue
+		}
+		if len(sel.Only) > 0 {
+			only := make(map[string]bool)
+			for _, r := range sel.Only {
+				only[r] = true
+			}
+			for r := range set {
+				if !only[r] {
+					delete(set, r)
+				}
+			}
+		}
+		for _, r := range sel.Skip {
+			delete(set, r)
+		}
+	}
+	return set
+}
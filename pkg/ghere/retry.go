@@ -0,0 +1,205 @@
+package ghere
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v48/github"
+)
+
+const (
+	DEFAULT_RETRY_MAX_ATTEMPTS     int           = 3
+	DEFAULT_RETRY_TIMEOUT          time.Duration = 20 * time.Second
+	DEFAULT_RETRY_INITIAL_BACKOFF  time.Duration = 1 * time.Second
+	DEFAULT_RETRY_MAX_BACKOFF      time.Duration = 2 * time.Minute
+	DEFAULT_RETRY_BACKOFF_MULTIPLE float64       = 2
+	DEFAULT_RETRY_JITTER           float64       = 0.5
+	// GitHub recommends waiting at least a minute before retrying after
+	// hitting a secondary rate limit if no Retry-After header is supplied.
+	// See https://docs.github.com/en/rest/overview/resources-in-the-rest-api#secondary-rate-limits
+	secondaryRateLimitMinWait time.Duration = time.Minute
+	// How long to wait at minimum before using a token again after it hit the
+	// primary rate limit, in case GitHub reports a reset time that has already
+	// passed (e.g. due to clock skew).
+	primaryRateLimitMinWait time.Duration = time.Minute
+)
+
+// RetryPolicy controls how requests to the GitHub API are retried when they
+// fail with retryable errors. Retryable errors are request timeouts, network
+// errors, secondary (abuse) rate limits, 429 responses, 403 responses with a
+// Retry-After header and 502/503/504 responses from GitHub. Any Retry-After
+// header supplied by GitHub takes precedence over the backoff.
+//
+// Hitting the primary rate limit does not count towards MaxAttempts: the client
+// simply waits until the rate limit is reset (or switches to another token).
+// Such attempts are, however, counted as retries, and the client gives up if
+// the primary rate limit is hit MaxAttempts times per token for the same
+// request.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times a request will be attempted
+	// before giving up.
+	MaxAttempts int
+	// Timeout is the timeout for each individual attempt.
+	Timeout time.Duration
+	// InitialBackoff is how long to wait before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the time to wait between retries.
+	MaxBackoff time.Duration
+	// Multiplier is the factor by which the backoff increases with each
+	// subsequent retry.
+	Multiplier float64
+	// Jitter is the fraction (between 0 and 1) of each backoff duration that
+	// is randomized, to prevent multiple clients from retrying in lockstep.
+	Jitter float64
+
+	mtx sync.Mutex
+	rnd *rand.Rand
+}
+
+// DefaultRetryPolicy returns a retry policy with sensible defaults.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:    DEFAULT_RETRY_MAX_ATTEMPTS,
+		Timeout:        DEFAULT_RETRY_TIMEOUT,
+		InitialBackoff: DEFAULT_RETRY_INITIAL_BACKOFF,
+		MaxBackoff:     DEFAULT_RETRY_MAX_BACKOFF,
+		Multiplier:     DEFAULT_RETRY_BACKOFF_MULTIPLE,
+		Jitter:         DEFAULT_RETRY_JITTER,
+	}
+}
+
+// Backoff computes how long to wait before the given retry (1-based), with
+// jitter applied.
+func (p *RetryPolicy) Backoff(retry int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	backoff := float64(p.InitialBackoff) * math.Pow(multiplier, float64(retry-1))
+	if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+	jitter := math.Max(0, math.Min(1, p.Jitter))
+	if jitter > 0 {
+		backoff -= backoff * jitter * p.random()
+	}
+	return time.Duration(backoff)
+}
+
+func (p *RetryPolicy) random() float64 {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if p.rnd == nil {
+		p.rnd = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return p.rnd.Float64()
+}
+
+type requestErrorKind int
+
+const (
+	requestErrorFatal requestErrorKind = iota
+	requestErrorRetryable
+	requestErrorPrimaryRateLimit
+)
+
+// classifyRequestError determines whether the given error, resulting from a
+// request to the GitHub API, warrants retrying the request. If the error
+// indicates how long to wait before retrying, the wait duration is also
+// returned. The given context is the overall (not per-attempt) context of the
+// request.
+func classifyRequestError(ctx context.Context, err error) (requestErrorKind, time.Duration) {
+	if ctx.Err() != nil {
+		return requestErrorFatal, 0
+	}
+	var rateLimitErr *github.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return requestErrorPrimaryRateLimit, 0
+	}
+	var abuseErr *github.AbuseRateLimitError
+	if errors.As(err, &abuseErr) {
+		if abuseErr.RetryAfter != nil {
+			return requestErrorRetryable, *abuseErr.RetryAfter
+		}
+		return requestErrorRetryable, secondaryRateLimitMinWait
+	}
+	var errRes *github.ErrorResponse
+	if errors.As(err, &errRes) {
+		if errRes.Response == nil {
+			return requestErrorFatal, 0
+		}
+		retryAfter, hasRetryAfter := parseRetryAfter(errRes.Response)
+		switch errRes.Response.StatusCode {
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return requestErrorRetryable, retryAfter
+		case http.StatusTooManyRequests:
+			if !hasRetryAfter {
+				retryAfter = secondaryRateLimitMinWait
+			}
+			return requestErrorRetryable, retryAfter
+		case http.StatusForbidden:
+			// Secondary rate limits are not always recognized as such, but
+			// are always accompanied by a Retry-After header.
+			if hasRetryAfter {
+				return requestErrorRetryable, retryAfter
+			}
+		}
+		return requestErrorFatal, 0
+	}
+	// The per-attempt timeout was hit.
+	if errors.Is(err, context.DeadlineExceeded) {
+		return requestErrorRetryable, 0
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return requestErrorRetryable, 0
+	}
+	return requestErrorFatal, 0
+}
+
+// parseRetryAfter extracts how long to wait before retrying from the given
+// response's Retry-After header, which can either specify a number of seconds
+// or an HTTP date.
+func parseRetryAfter(res *http.Response) (time.Duration, bool) {
+	header := res.Header.Get("Retry-After")
+	if header == "" {
+		return 0, false
+	}
+	if secs, err := strconv.ParseInt(header, 10, 64); err == nil {
+		if secs < 0 {
+			secs = 0
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if at, err := http.ParseTime(header); err == nil {
+		wait := time.Until(at)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+	return 0, false
+}
+
+// sleepContext waits for the given duration, or until the context is
+// cancelled (in which case the context's error is returned).
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
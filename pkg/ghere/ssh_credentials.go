@@ -0,0 +1,150 @@
+package ghere
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+const SSH_AUTH_SOCK_ENVVAR string = "SSH_AUTH_SOCK"
+
+// SSHCredentialConfig configures the way in which SSH credentials are
+// obtained by the provider constructed by [NewGitHubSSHCredentialProvider].
+type SSHCredentialConfig struct {
+	// UseAgent, if true, will attempt to authenticate using the SSH agent
+	// listening on the socket specified by the SSH_AUTH_SOCK environment
+	// variable.
+	UseAgent bool
+	// DefaultPrivKeyFile is the private key file to use for owners without a
+	// specific key file. Ignored if the file does not exist.
+	DefaultPrivKeyFile string
+	// OwnerPrivKeyFiles maps GitHub users/organizations to the private key
+	// files to use when fetching their repositories.
+	OwnerPrivKeyFiles map[string]string
+	// KnownHostsFiles, if specified, are the known_hosts files against which
+	// to verify remote hosts' keys. If not specified, go-git's defaults are
+	// used.
+	KnownHostsFiles []string
+}
+
+type sshCredentialProvider struct {
+	cfg             *SSHCredentialConfig
+	httpCfg         *HTTPCredentialConfig
+	hostKeyCallback gossh.HostKeyCallback
+
+	// The SSH agent connection is established on first use and shared by all
+	// subsequent credentials.
+	agentMtx  sync.Mutex
+	agentAuth *ssh.PublicKeysCallback
+}
+
+var _ GitHubCredentialProvider = (*sshCredentialProvider)(nil)
+
+// NewGitHubSSHCredentialProvider creates a [GitHubCredentialProvider] that
+// supports the use of an SSH agent, per-owner SSH keys and explicit known_hosts
+// files for host key verification. HTTP(S) credentials are obtained as per the
+// given HTTP(S) credential configuration.
+func NewGitHubSSHCredentialProvider(cfg *SSHCredentialConfig, httpCfg *HTTPCredentialConfig) (GitHubCredentialProvider, error) {
+	cp := &sshCredentialProvider{
+		cfg:     cfg,
+		httpCfg: httpCfg,
+	}
+	if len(cfg.KnownHostsFiles) > 0 {
+		for _, fn := range cfg.KnownHostsFiles {
+			exists, err := fileExists(fn)
+			if err != nil {
+				return nil, err
+			}
+			if !exists {
+				return nil, fmt.Errorf("known_hosts file does not exist: %s", fn)
+			}
+		}
+		var err error
+		cp.hostKeyCallback, err = ssh.NewKnownHostsCallback(cfg.KnownHostsFiles...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load known_hosts files %v: %v", cfg.KnownHostsFiles, err)
+		}
+	}
+	if cfg.UseAgent && len(os.Getenv(SSH_AUTH_SOCK_ENVVAR)) == 0 {
+		return nil, fmt.Errorf("SSH agent usage requested, but %s environment variable is not set", SSH_AUTH_SOCK_ENVVAR)
+	}
+	return cp, nil
+}
+
+func (cp *sshCredentialProvider) GetGitHubCredentials(ctx context.Context, owner string) (*GitHubCredentials, error) {
+	select {
+	case <-ctx.Done():
+		return nil, errors.New("credentials fetch cancelled")
+	default:
+	}
+	basicAuth, err := cp.httpCfg.getBasicAuth(ctx)
+	if err != nil {
+		return nil, err
+	}
+	creds := &GitHubCredentials{
+		SSHAuth:   []ssh.AuthMethod{},
+		BasicAuth: basicAuth,
+	}
+	if keyFile, ok := cp.ownerPrivKeyFile(owner); ok {
+		pubKeys, err := loadSSHKeys(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load SSH key for %s: %v", owner, err)
+		}
+		if pubKeys == nil {
+			return nil, fmt.Errorf("SSH key file configured for %s does not exist: %s", owner, keyFile)
+		}
+		pubKeys.HostKeyCallback = cp.hostKeyCallback
+		creds.SSHAuth = append(creds.SSHAuth, pubKeys)
+	}
+	if cp.cfg.UseAgent {
+		agentAuth, err := cp.getAgentAuth()
+		if err != nil {
+			return nil, err
+		}
+		creds.SSHAuth = append(creds.SSHAuth, agentAuth)
+	}
+	if len(cp.cfg.DefaultPrivKeyFile) > 0 {
+		pubKeys, err := loadSSHKeys(cp.cfg.DefaultPrivKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load SSH key: %v", err)
+		}
+		if pubKeys != nil {
+			pubKeys.HostKeyCallback = cp.hostKeyCallback
+			creds.SSHAuth = append(creds.SSHAuth, pubKeys)
+		}
+	}
+	return creds, nil
+}
+
+// getAgentAuth connects to the SSH agent if it has not yet been connected to,
+// reusing the existing connection otherwise.
+func (cp *sshCredentialProvider) getAgentAuth() (*ssh.PublicKeysCallback, error) {
+	cp.agentMtx.Lock()
+	defer cp.agentMtx.Unlock()
+	if cp.agentAuth != nil {
+		return cp.agentAuth, nil
+	}
+	agentAuth, err := ssh.NewSSHAgentAuth("git")
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to SSH agent: %v", err)
+	}
+	agentAuth.HostKeyCallback = cp.hostKeyCallback
+	cp.agentAuth = agentAuth
+	return agentAuth, nil
+}
+
+// GitHub logins are case-insensitive.
+func (cp *sshCredentialProvider) ownerPrivKeyFile(owner string) (string, bool) {
+	for o, keyFile := range cp.cfg.OwnerPrivKeyFiles {
+		if strings.EqualFold(o, owner) {
+			return keyFile, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,64 @@
+package ghere_test
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/informalsystems/ghere/pkg/ghere"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+func TestSSHCredentialProviderReusesAgent(t *testing.T) {
+	sockPath := filepath.Join(t.TempDir(), "agent.sock")
+	listener, err := net.Listen("unix", sockPath)
+	require.NoError(t, err)
+	defer listener.Close()
+	var mtx sync.Mutex
+	connections := 0
+	keyring := agent.NewKeyring()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			mtx.Lock()
+			connections++
+			mtx.Unlock()
+			go func() {
+				defer conn.Close()
+				_ = agent.ServeAgent(keyring, conn)
+			}()
+		}
+	}()
+	t.Setenv(ghere.SSH_AUTH_SOCK_ENVVAR, sockPath)
+	t.Setenv(ghere.GITHUB_PASSWORD_ENVVAR, "")
+
+	cp, err := ghere.NewGitHubSSHCredentialProvider(&ghere.SSHCredentialConfig{UseAgent: true}, &ghere.HTTPCredentialConfig{})
+	require.NoError(t, err)
+	var first *ghere.GitHubCredentials
+	for i := 0; i < 3; i++ {
+		creds, err := cp.GetGitHubCredentials(context.Background(), "org")
+		require.NoError(t, err)
+		require.Len(t, creds.SSHAuth, 1)
+		if first == nil {
+			first = creds
+		}
+		assert.Same(t, first.SSHAuth[0], creds.SSHAuth[0])
+	}
+	countConnections := func() int {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return connections
+	}
+	// Connections may only be accepted after the agent has been dialed.
+	require.Eventually(t, func() bool { return countConnections() > 0 }, time.Second, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 1, countConnections())
+}
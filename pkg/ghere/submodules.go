@@ -0,0 +1,246 @@
+package ghere
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// MissingSubmodule describes a submodule commit that could not be retrieved
+// when cloning or updating a repository.
+type MissingSubmodule struct {
+	// Path of the submodule relative to the root of the top-level repository.
+	Path string `json:"path"`
+	// URL is the last URL from which we attempted to fetch the submodule.
+	URL string `json:"url"`
+	// Commit is the commit hash expected by the parent repository.
+	Commit string `json:"commit"`
+	// Error is the last error encountered while fetching the submodule.
+	Error string `json:"error"`
+}
+
+// updateSubmodules recursively initializes and updates all of the submodules
+// of the Git repository in repoDir, whose remote URL is repoURL. Each
+// submodule is attempted with each of the given authentication methods in
+// turn. Submodules hosted on other hosts (to which none of the authentication
+// methods apply), or for which no authentication methods are available, are
+// fetched anonymously. Submodules that cannot be retrieved are returned,
+// instead of causing the whole operation to fail.
+func updateSubmodules(ctx context.Context, repoDir, relPath, repoURL string, authMethods []*githubAuthMethod, log Logger) ([]*MissingSubmodule, error) {
+	repo, err := git.PlainOpen(repoDir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open Git repository %s: %v", repoDir, err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("unable to open worktree for Git repository %s: %v", repoDir, err)
+	}
+	subs, err := wt.Submodules()
+	if err != nil {
+		return nil, fmt.Errorf("unable to load submodules for Git repository %s: %v", repoDir, err)
+	}
+	missing := []*MissingSubmodule{}
+	for _, sub := range subs {
+		subCfg := sub.Config()
+		subRelPath := path.Join(relPath, subCfg.Path)
+		status, err := sub.Status()
+		if err != nil {
+			return nil, fmt.Errorf("unable to obtain status of submodule %s in %s: %v", subCfg.Path, repoDir, err)
+		}
+		// The submodule's URL as specified by the parent repository
+		origURL := subCfg.URL
+		var subURL string
+		otherHost, subErr := isOnOtherHost(repoURL, origURL)
+		switch {
+		case subErr != nil:
+		case otherHost || len(authMethods) == 0:
+			subURL, _, subErr = resolveSubmoduleURL(repoURL, origURL, &githubAuthMethod{})
+			if subErr != nil {
+				break
+			}
+			subErr = updateSubmodule(ctx, sub, subRelPath, subURL, nil, status, log)
+			if isAuthError(subErr) {
+				log.Warn("Submodule requires authentication, but no credentials are available for it", "path", subRelPath, "url", subURL)
+			}
+		default:
+			for _, method := range authMethods {
+				var auth transport.AuthMethod
+				subURL, auth, subErr = resolveSubmoduleURL(repoURL, origURL, method)
+				if subErr != nil {
+					break
+				}
+				if subErr = updateSubmodule(ctx, sub, subRelPath, subURL, auth, status, log); subErr == nil {
+					break
+				}
+			}
+		}
+		if subErr != nil {
+			missing = append(missing, &MissingSubmodule{
+				Path:   subRelPath,
+				URL:    subURL,
+				Commit: status.Expected.String(),
+				Error:  subErr.Error(),
+			})
+			continue
+		}
+		nested, err := updateSubmodules(ctx, filepath.Join(repoDir, subCfg.Path), subRelPath, subURL, authMethods, log)
+		if err != nil {
+			return nil, err
+		}
+		missing = append(missing, nested...)
+	}
+	return missing, nil
+}
+
+// updateSubmodule attempts to initialize and update the given submodule from
+// the given URL using the given authentication method (which may be nil to
+// fetch the submodule anonymously).
+func updateSubmodule(ctx context.Context, sub *git.Submodule, subRelPath, subURL string, auth transport.AuthMethod, status *git.SubmoduleStatus, log Logger) error {
+	log.Info("Attempting to update submodule", "path", subRelPath, "url", subURL, "commit", status.Expected.String())
+	if err := setSubmoduleURL(sub, subURL); err != nil {
+		log.Warn("Failed to set submodule URL", "path", subRelPath, "url", subURL, "err", err)
+		return err
+	}
+	err := sub.UpdateContext(ctx, &git.SubmoduleUpdateOptions{
+		Init: true,
+		Auth: auth,
+	})
+	if err != nil {
+		log.Warn("Failed to update submodule", "path", subRelPath, "url", subURL, "err", err)
+	}
+	return err
+}
+
+// isAuthError returns whether the given error, resulting from fetching a Git
+// repository, indicates that authentication is required. Hosts commonly
+// report private repositories as not found to unauthenticated users.
+func isAuthError(err error) bool {
+	return errors.Is(err, transport.ErrAuthenticationRequired) ||
+		errors.Is(err, transport.ErrAuthorizationFailed) ||
+		errors.Is(err, transport.ErrRepositoryNotFound)
+}
+
+// isOnOtherHost returns whether the submodule with the given URL (which may be
+// relative to the parent repository's URL) is hosted on a different host to
+// the parent repository.
+func isOnOtherHost(parentURL, subURL string) (bool, error) {
+	if strings.HasPrefix(subURL, "./") || strings.HasPrefix(subURL, "../") {
+		return false, nil
+	}
+	parent, err := parseGitRemoteURL(parentURL)
+	if err != nil {
+		return false, err
+	}
+	sub, err := parseGitRemoteURL(subURL)
+	if err != nil {
+		return false, err
+	}
+	return !strings.EqualFold(sub.host, parent.host), nil
+}
+
+// setSubmoduleURL ensures that the given submodule will be fetched from the
+// given URL, even if it has already been initialized.
+func setSubmoduleURL(sub *git.Submodule, subURL string) error {
+	sub.Config().URL = subURL
+	repo, err := sub.Repository()
+	if err != nil {
+		if err == git.ErrSubmoduleNotInitialized {
+			return nil
+		}
+		return fmt.Errorf("unable to open submodule repository %s: %v", sub.Config().Path, err)
+	}
+	cfg, err := repo.Config()
+	if err != nil {
+		return fmt.Errorf("unable to load configuration for submodule %s: %v", sub.Config().Path, err)
+	}
+	remote, exists := cfg.Remotes[git.DefaultRemoteName]
+	if !exists {
+		return nil
+	}
+	remote.URLs = []string{subURL}
+	return repo.Storer.SetConfig(cfg)
+}
+
+// resolveSubmoduleURL resolves the given submodule URL (which may be relative
+// to the parent repository's URL) into an absolute URL appropriate for the
+// given authentication method. Submodules hosted on a different host to the
+// parent repository are fetched anonymously.
+func resolveSubmoduleURL(parentURL, subURL string, method *githubAuthMethod) (string, transport.AuthMethod, error) {
+	parent, err := parseGitRemoteURL(parentURL)
+	if err != nil {
+		return "", nil, err
+	}
+	var sub *gitRemoteURL
+	if strings.HasPrefix(subURL, "./") || strings.HasPrefix(subURL, "../") {
+		sub = &gitRemoteURL{
+			host: parent.host,
+			path: path.Join(parent.path, subURL),
+		}
+	} else {
+		sub, err = parseGitRemoteURL(subURL)
+		if err != nil {
+			return "", nil, err
+		}
+		if !strings.EqualFold(sub.host, parent.host) {
+			return subURL, nil, nil
+		}
+	}
+	sub.ssh = method.ssh
+	return sub.String(), method.auth, nil
+}
+
+// gitRemoteURL is a simplified representation of a Git remote URL.
+type gitRemoteURL struct {
+	ssh  bool
+	host string
+	// The path to the repository on the host, without a leading slash (e.g.
+	// "org/repo.git").
+	path string
+}
+
+func parseGitRemoteURL(rawURL string) (*gitRemoteURL, error) {
+	// SCP-like syntax, e.g. "git@github.com:org/repo.git"
+	if !strings.Contains(rawURL, "://") {
+		atIdx := strings.Index(rawURL, "@")
+		colonIdx := strings.Index(rawURL, ":")
+		if colonIdx <= atIdx+1 {
+			return nil, fmt.Errorf("unsupported Git remote URL: %s", rawURL)
+		}
+		return &gitRemoteURL{
+			ssh:  true,
+			host: rawURL[atIdx+1 : colonIdx],
+			path: strings.TrimPrefix(rawURL[colonIdx+1:], "/"),
+		}, nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Git remote URL %s: %v", rawURL, err)
+	}
+	r := &gitRemoteURL{
+		host: u.Hostname(),
+		path: strings.TrimPrefix(u.Path, "/"),
+	}
+	switch u.Scheme {
+	case "ssh":
+		r.ssh = true
+	case "http", "https", "git":
+	default:
+		return nil, fmt.Errorf("unsupported Git remote URL scheme: %s", rawURL)
+	}
+	return r, nil
+}
+
+// String renders the URL in a form usable by go-git.
+func (r *gitRemoteURL) String() string {
+	if r.ssh {
+		return "ssh://git@" + r.host + "/" + r.path
+	}
+	return "https://" + r.host + "/" + r.path
+}
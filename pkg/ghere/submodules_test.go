@@ -0,0 +1,28 @@
+package ghere
+
+import (
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// ResolveSubmoduleURL resolves the given submodule URL for an authentication
+// method using either SSH or HTTPS URLs, also returning whether the method's
+// credentials would be used to fetch the submodule.
+func ResolveSubmoduleURL(parentURL, subURL string, ssh bool) (string, bool, error) {
+	method := &githubAuthMethod{auth: &http.BasicAuth{}, ssh: ssh}
+	resolved, auth, err := resolveSubmoduleURL(parentURL, subURL, method)
+	return resolved, auth != nil, err
+}
+
+// ParseGitRemoteURL parses the given Git remote URL into whether it is an SSH
+// URL, its host and its path.
+func ParseGitRemoteURL(rawURL string) (bool, string, string, error) {
+	r, err := parseGitRemoteURL(rawURL)
+	if err != nil {
+		return false, "", "", err
+	}
+	return r.ssh, r.host, r.path, nil
+}
+
+func IsOnOtherHost(parentURL, subURL string) (bool, error) {
+	return isOnOtherHost(parentURL, subURL)
+}
@@ -0,0 +1,290 @@
+package ghere
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/google/go-github/v48/github"
+)
+
+// Team member roles, as understood by GitHub's API.
+const (
+	TEAM_ROLE_MAINTAINER string = "maintainer"
+	TEAM_ROLE_MEMBER     string = "member"
+)
+
+// teamPermissions lists the permissions a team can have on a repository, from
+// highest to lowest.
+var teamPermissions = []string{"admin", "maintain", "push", "triage", "pull"}
+
+// TeamSnapshot captures the state of a team (including its parent team, its
+// members and its permissions on repositories) at a particular point in time.
+type TeamSnapshot struct {
+	Team         *github.Team      `json:"team"`
+	Members      []*TeamMember     `json:"members"`
+	Repositories []*TeamRepository `json:"repositories"`
+	// Deleted is true if the team no longer exists on GitHub.
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+type TeamMember struct {
+	Login string `json:"login"`
+	ID    int64  `json:"id"`
+	// Role is one of the TEAM_ROLE_* constants.
+	Role string `json:"role"`
+}
+
+type TeamRepository struct {
+	FullName string `json:"full_name"`
+	ID       int64  `json:"id"`
+	// Permission is the highest of the team's permissions on the repository.
+	Permission  string          `json:"permission"`
+	Permissions map[string]bool `json:"permissions,omitempty"`
+}
+
+// Team is the latest snapshot of a team. Whenever a fetch reveals a change to
+// the team, a copy of the new snapshot is also added to the team's history.
+type Team struct {
+	TeamSnapshot
+
+	LastFetch time.Time `json:"last_fetch"`
+	// LastChange is the time at which the latest snapshot was added to the
+	// team's history.
+	LastChange time.Time `json:"last_change"`
+}
+
+func LoadTeam(rootPath, owner, slug string, mustExist bool) (*Team, error) {
+	return LoadTeamDirect(teamDetailPath(rootPath, owner, slug), mustExist)
+}
+
+func LoadTeamDirect(path string, mustExist bool) (*Team, error) {
+	var err error
+	team := &Team{}
+	if mustExist {
+		err = readJSONFile(path, team)
+	} else {
+		err = readJSONFileOrEmpty(path, team)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read team detail file: %v", err)
+	}
+	return team, nil
+}
+
+func (t *Team) Save(rootPath, owner string, prettyJSON bool) error {
+	path := teamDetailPath(rootPath, owner, t.Team.GetSlug())
+	if err := writeJSONFile(path, t, prettyJSON); err != nil {
+		return fmt.Errorf("failed to write team detail file: %v", err)
+	}
+	return nil
+}
+
+// update replaces the team's latest snapshot with the given one, adding it to
+// the team's history if it differs from the previous one.
+func (t *Team) update(rootPath, owner string, snapshot TeamSnapshot, fetchTime time.Time, prettyJSON bool) error {
+	changed := t.Team == nil
+	if !changed {
+		prevJSON, prevErr := json.Marshal(t.TeamSnapshot)
+		latestJSON, latestErr := json.Marshal(snapshot)
+		changed = prevErr != nil || latestErr != nil || !bytes.Equal(prevJSON, latestJSON)
+	}
+	t.TeamSnapshot = snapshot
+	t.LastFetch = fetchTime
+	if changed {
+		path := teamSnapshotPath(rootPath, owner, t.Team.GetSlug(), fetchTime)
+		if err := writeJSONFile(path, t.TeamSnapshot, prettyJSON); err != nil {
+			return fmt.Errorf("failed to write team snapshot file: %v", err)
+		}
+		t.LastChange = fetchTime
+	}
+	return t.Save(rootPath, owner, prettyJSON)
+}
+
+// teamsFetcher fetches all of an organization's teams.
+type teamsFetcher struct {
+	rootPath string
+	owner    string
+}
+
+var (
+	_ fetcher       = (*teamsFetcher)(nil)
+	_ targetPlanner = (*teamsFetcher)(nil)
+)
+
+func newTeamsFetcher(rootPath, owner string) *teamsFetcher {
+	return &teamsFetcher{
+		rootPath: rootPath,
+		owner:    owner,
+	}
+}
+
+func (f *teamsFetcher) resource() string {
+	return RESOURCE_TEAMS
+}
+
+func (f *teamsFetcher) fetch(ctx context.Context, cfg *FetchConfig, log Logger) ([]fetcher, error) {
+	fetchTime := time.Now()
+	teamFetchers := []fetcher{}
+	seen := make(map[string]bool)
+	done := false
+	for page := 1; !done; page++ {
+		var teams []*github.Team
+		var err error
+		teams, done, err = cfg.Client.ListOrganizationTeams(ctx, f.owner, page)
+		if err != nil {
+			return nil, err
+		}
+		for _, team := range teams {
+			seen[team.GetSlug()] = true
+			teamFetchers = append(teamFetchers, newTeamFetcher(f.rootPath, f.owner, team))
+		}
+	}
+	log.Info("Fetched organization teams", "org", f.owner, "teams", len(teamFetchers))
+
+	// Teams that no longer exist are retained locally, but marked as deleted.
+	pattern := filepath.Join(teamsPath(f.rootPath, f.owner), "*", DETAIL_FILENAME)
+	teamDetailFiles, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list teams' detail files from pattern %s: %v", pattern, err)
+	}
+	deleted := 0
+	for _, fn := range teamDetailFiles {
+		team, err := LoadTeamDirect(fn, true)
+		if err != nil {
+			return nil, err
+		}
+		if seen[team.Team.GetSlug()] || team.Deleted {
+			continue
+		}
+		snapshot := team.TeamSnapshot
+		snapshot.Deleted = true
+		if err := team.update(f.rootPath, f.owner, snapshot, fetchTime, cfg.PrettyJSON); err != nil {
+			return nil, err
+		}
+		deleted++
+	}
+	cfg.report.recordDeleted(RESOURCE_TEAMS, deleted)
+	return teamFetchers, nil
+}
+
+// plan estimates the API calls needed to fetch the organization's teams from
+// the teams already stored locally. Nothing is fetched from GitHub, since all
+// teams are fetched every time.
+func (f *teamsFetcher) plan(ctx context.Context, cfg *FetchConfig) (*RepositoryFetchPlan, error) {
+	count, err := countLocalItems(filepath.Join(teamsPath(f.rootPath, f.owner), "*", DETAIL_FILENAME))
+	if err != nil {
+		return nil, err
+	}
+	pages := estimatePages(count, nil)
+	// Each team's maintainers, members and repositories are listed
+	// separately.
+	calls := pages + 3*count
+	return &RepositoryFetchPlan{
+		Fetchers: []*FetcherPlan{{
+			Resource:          RESOURCE_TEAMS,
+			Description:       fmt.Sprintf("list teams (~%d page(s)), along with the members and repositories of ~%d team(s)", pages, count),
+			EstimatedAPICalls: calls,
+		}},
+		EstimatedAPICalls: calls,
+	}, nil
+}
+
+// teamFetcher fetches a team's members and repository permissions, recording
+// a new snapshot of the team if anything changed since the last fetch.
+type teamFetcher struct {
+	rootPath string
+	owner    string
+	team     *github.Team
+}
+
+var _ fetcher = (*teamFetcher)(nil)
+
+func newTeamFetcher(rootPath, owner string, team *github.Team) *teamFetcher {
+	return &teamFetcher{
+		rootPath: rootPath,
+		owner:    owner,
+		team:     team,
+	}
+}
+
+func (f *teamFetcher) resource() string {
+	return RESOURCE_TEAMS
+}
+
+func (f *teamFetcher) fetch(ctx context.Context, cfg *FetchConfig, log Logger) ([]fetcher, error) {
+	fetchTime := time.Now()
+	slug := f.team.GetSlug()
+	snapshot := TeamSnapshot{
+		Team:         f.team,
+		Members:      []*TeamMember{},
+		Repositories: []*TeamRepository{},
+	}
+	for _, role := range []string{TEAM_ROLE_MAINTAINER, TEAM_ROLE_MEMBER} {
+		done := false
+		for page := 1; !done; page++ {
+			var users []*github.User
+			var err error
+			users, done, err = cfg.Client.ListTeamMembers(ctx, f.owner, slug, role, page)
+			if err != nil {
+				return nil, err
+			}
+			for _, user := range users {
+				snapshot.Members = append(snapshot.Members, &TeamMember{
+					Login: user.GetLogin(),
+					ID:    user.GetID(),
+					Role:  role,
+				})
+			}
+		}
+	}
+	done := false
+	for page := 1; !done; page++ {
+		var repos []*github.Repository
+		var err error
+		repos, done, err = cfg.Client.ListTeamRepositories(ctx, f.owner, slug, page)
+		if err != nil {
+			return nil, err
+		}
+		for _, repo := range repos {
+			snapshot.Repositories = append(snapshot.Repositories, &TeamRepository{
+				FullName:    repo.GetFullName(),
+				ID:          repo.GetID(),
+				Permission:  highestTeamPermission(repo.Permissions),
+				Permissions: repo.Permissions,
+			})
+		}
+	}
+	// Sorting ensures that ordering differences between fetches are not
+	// mistaken for changes.
+	sort.Slice(snapshot.Members, func(i, j int) bool {
+		return snapshot.Members[i].Login < snapshot.Members[j].Login
+	})
+	sort.Slice(snapshot.Repositories, func(i, j int) bool {
+		return snapshot.Repositories[i].FullName < snapshot.Repositories[j].FullName
+	})
+
+	team, err := LoadTeam(f.rootPath, f.owner, slug, false)
+	if err != nil {
+		return nil, err
+	}
+	cfg.report.recordItem(RESOURCE_TEAMS, team.Team != nil, team.TeamSnapshot, snapshot)
+	if err := team.update(f.rootPath, f.owner, snapshot, fetchTime, cfg.PrettyJSON); err != nil {
+		return nil, err
+	}
+	log.Debug("Fetched team", "org", f.owner, "team", slug, "members", len(snapshot.Members), "repositories", len(snapshot.Repositories))
+	return nil, nil
+}
+
+func highestTeamPermission(perms map[string]bool) string {
+	for _, perm := range teamPermissions {
+		if perms[perm] {
+			return perm
+		}
+	}
+	return ""
+}
@@ -0,0 +1,189 @@
+package ghere
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v48/github"
+)
+
+// TimelineEvent is a single event from an issue's or pull request's timeline
+// (e.g. it being closed, labeled, assigned, renamed or cross-referenced).
+type TimelineEvent struct {
+	Event *github.Timeline `json:"event"`
+}
+
+// TimelineEventKey returns a key that uniquely identifies the given timeline
+// event within its issue/pull request's timeline, and which is used as the
+// name of the file in which the event is stored.
+//
+// Not all types of timeline events have IDs. Commits are identified by their
+// SHA, and the remaining events by their type, creation time, actor and, for
+// cross-references, the referencing issue/pull request. Since creation times
+// only have a resolution of one second, the actor distinguishes events of the
+// same type created by different users at the same time.
+func TimelineEventKey(event *github.Timeline) string {
+	if event.ID != nil {
+		return fmt.Sprintf("%d", event.GetID())
+	}
+	if event.SHA != nil {
+		return event.GetSHA()
+	}
+	parts := []string{event.GetEvent(), fmt.Sprintf("%d", event.GetCreatedAt().Unix())}
+	if event.Actor != nil {
+		parts = append(parts, fmt.Sprintf("%d", event.Actor.GetID()))
+	}
+	if event.Source != nil && event.Source.Issue != nil {
+		parts = append(parts, fmt.Sprintf("%d", event.Source.Issue.GetID()))
+	}
+	return strings.Join(parts, "-")
+}
+
+func LoadIssueEvent(rootPath string, repo *Repository, issueNum int, key string, mustExist bool) (*TimelineEvent, error) {
+	path := issueEventPath(rootPath, repo.GetOwner(), repo.GetName(), issueNum, key)
+	return LoadTimelineEventDirect(path, mustExist)
+}
+
+func LoadPullRequestEvent(rootPath string, repo *Repository, prNum int, key string, mustExist bool) (*TimelineEvent, error) {
+	path := pullRequestEventPath(rootPath, repo.GetOwner(), repo.GetName(), prNum, key)
+	return LoadTimelineEventDirect(path, mustExist)
+}
+
+func LoadTimelineEventDirect(path string, mustExist bool) (*TimelineEvent, error) {
+	var err error
+	event := &TimelineEvent{}
+	if mustExist {
+		err = readJSONFile(path, event)
+	} else {
+		err = readJSONFileOrEmpty(path, event)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read timeline event file: %v", err)
+	}
+	return event, nil
+}
+
+func (e *TimelineEvent) SaveForIssue(rootPath string, repo *Repository, issueNum int, prettyJSON bool) error {
+	path := issueEventPath(rootPath, repo.GetOwner(), repo.GetName(), issueNum, TimelineEventKey(e.Event))
+	if err := writeJSONFile(path, e, prettyJSON); err != nil {
+		return fmt.Errorf("failed to write issue event file: %v", err)
+	}
+	return nil
+}
+
+func (e *TimelineEvent) SaveForPullRequest(rootPath string, repo *Repository, prNum int, prettyJSON bool) error {
+	path := pullRequestEventPath(rootPath, repo.GetOwner(), repo.GetName(), prNum, TimelineEventKey(e.Event))
+	if err := writeJSONFile(path, e, prettyJSON); err != nil {
+		return fmt.Errorf("failed to write pull request event file: %v", err)
+	}
+	return nil
+}
+
+// fetchTimeline fetches all of the events in the timeline of the issue or pull
+// request with the given number, calling save for each one.
+func fetchTimeline(ctx context.Context, cfg *FetchConfig, repo *Repository, num int, save func(*github.Timeline) error) error {
+	done := false
+	for page := 1; !done; page++ {
+		var events []*github.Timeline
+		var err error
+		events, done, err = cfg.Client.ListIssueTimeline(ctx, repo.GetOwner(), repo.GetName(), num, page)
+		if err != nil {
+			return err
+		}
+		for _, ghEvent := range events {
+			if err := save(ghEvent); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// issueEventsFetcher fetches the timeline events of specific issues.
+type issueEventsFetcher struct {
+	rootPath string
+	repo     *Repository
+	issues   []*Issue
+}
+
+var _ fetcher = (*issueEventsFetcher)(nil)
+
+func newIssueEventsFetcher(rootPath string, repo *Repository, issues []*Issue) *issueEventsFetcher {
+	return &issueEventsFetcher{
+		rootPath: rootPath,
+		repo:     repo,
+		issues:   issues,
+	}
+}
+
+func (f *issueEventsFetcher) resource() string {
+	return RESOURCE_ISSUE_EVENTS
+}
+
+func (f *issueEventsFetcher) fetch(ctx context.Context, cfg *FetchConfig, log Logger) ([]fetcher, error) {
+	for _, issue := range f.issues {
+		err := fetchTimeline(ctx, cfg, f.repo, issue.GetNumber(), func(ghEvent *github.Timeline) error {
+			event, err := LoadIssueEvent(f.rootPath, f.repo, issue.GetNumber(), TimelineEventKey(ghEvent), false)
+			if err != nil {
+				return err
+			}
+			cfg.report.recordItem(RESOURCE_ISSUE_EVENTS, event.Event != nil, event.Event, ghEvent)
+			event.Event = ghEvent
+			return event.SaveForIssue(f.rootPath, f.repo, issue.GetNumber(), cfg.PrettyJSON)
+		})
+		if err != nil {
+			return nil, err
+		}
+		issue.LastEventsFetch = time.Now()
+		if err := issue.Save(f.rootPath, f.repo, cfg.PrettyJSON); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// pullRequestEventsFetcher fetches the timeline events of specific pull
+// requests.
+type pullRequestEventsFetcher struct {
+	rootPath     string
+	repo         *Repository
+	pullRequests []*PullRequest
+}
+
+var _ fetcher = (*pullRequestEventsFetcher)(nil)
+
+func newPullRequestEventsFetcher(rootPath string, repo *Repository, pullRequests []*PullRequest) *pullRequestEventsFetcher {
+	return &pullRequestEventsFetcher{
+		rootPath:     rootPath,
+		repo:         repo,
+		pullRequests: pullRequests,
+	}
+}
+
+func (f *pullRequestEventsFetcher) resource() string {
+	return RESOURCE_PULL_REQUEST_EVENTS
+}
+
+func (f *pullRequestEventsFetcher) fetch(ctx context.Context, cfg *FetchConfig, log Logger) ([]fetcher, error) {
+	for _, pr := range f.pullRequests {
+		err := fetchTimeline(ctx, cfg, f.repo, pr.GetNumber(), func(ghEvent *github.Timeline) error {
+			event, err := LoadPullRequestEvent(f.rootPath, f.repo, pr.GetNumber(), TimelineEventKey(ghEvent), false)
+			if err != nil {
+				return err
+			}
+			cfg.report.recordItem(RESOURCE_PULL_REQUEST_EVENTS, event.Event != nil, event.Event, ghEvent)
+			event.Event = ghEvent
+			return event.SaveForPullRequest(f.rootPath, f.repo, pr.GetNumber(), cfg.PrettyJSON)
+		})
+		if err != nil {
+			return nil, err
+		}
+		pr.LastEventsFetch = time.Now()
+		if err := pr.Save(f.rootPath, f.repo, cfg.PrettyJSON); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,472 @@
+package ghere
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v48/github"
+)
+
+// Maximum size of webhook payloads we accept. GitHub caps payloads at 25MB.
+const maxWebhookPayloadSize int64 = 25 * 1024 * 1024
+
+// WebhookConfig configures how GitHub webhook events are handled.
+type WebhookConfig struct {
+	// Secret is the webhook secret with which GitHub signs event payloads.
+	Secret     []byte
+	PrettyJSON bool
+}
+
+// WebhookHandler receives GitHub webhook events (as an http.Handler) for
+// repositories in a collection and applies them to the local copies of those
+// repositories. Events that cannot be applied directly (e.g. pushes) result in
+// targeted refetches of the affected resources, which are executed by
+// [WebhookHandler.Refetch].
+//
+// The following events are supported: issues, issue_comment, pull_request,
+// pull_request_review, label, push and release. Issues, comments, labels and
+// releases that are deleted are marked as such in the local store. Other
+// events, and events for repositories that are not part of the collection
+// (including those on other hosts), are ignored.
+//
+// GitHub does not guarantee the order in which events are delivered, and may
+// redeliver them, so events whose items are older than the locally stored
+// versions (or which pertain to items that were deleted) are ignored. Events
+// for the same repository are applied one at a time.
+type WebhookHandler struct {
+	coll *LocalCollection
+	cfg  *WebhookConfig
+	log  Logger
+
+	mtx sync.Mutex
+	// Resources to refetch, keyed by repository path.
+	pending map[string]resourceSet
+	notify  chan struct{}
+	// Locks serializing the application of events to each repository, keyed
+	// by repository path.
+	repoLocks map[string]*sync.Mutex
+}
+
+var _ http.Handler = (*WebhookHandler)(nil)
+
+func NewWebhookHandler(coll *LocalCollection, cfg *WebhookConfig, log Logger) *WebhookHandler {
+	return &WebhookHandler{
+		coll:      coll,
+		cfg:       cfg,
+		log:       log,
+		pending:   make(map[string]resourceSet),
+		notify:    make(chan struct{}, 1),
+		repoLocks: make(map[string]*sync.Mutex),
+	}
+}
+
+// ServeHTTP implements http.Handler.
+func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	payload, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookPayloadSize))
+	if err != nil {
+		http.Error(w, "failed to read payload", http.StatusBadRequest)
+		return
+	}
+	signature := r.Header.Get(github.SHA256SignatureHeader)
+	if len(signature) == 0 {
+		http.Error(w, fmt.Sprintf("missing %s header", github.SHA256SignatureHeader), http.StatusUnauthorized)
+		return
+	}
+	if err := github.ValidateSignature(signature, payload, h.cfg.Secret); err != nil {
+		h.log.Error("Rejecting webhook event with invalid signature", "delivery", github.DeliveryID(r), "err", err)
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+	eventType := github.WebHookType(r)
+	if err := h.HandleEvent(eventType, payload); err != nil {
+		var badPayload *errBadWebhookPayload
+		if errors.As(err, &badPayload) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.log.Error("Failed to handle webhook event", "event", eventType, "delivery", github.DeliveryID(r), "err", err)
+		http.Error(w, "failed to handle event", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type errBadWebhookPayload struct {
+	err error
+}
+
+func (e *errBadWebhookPayload) Error() string {
+	return fmt.Sprintf("failed to parse webhook payload: %v", e.err)
+}
+
+// The webhook events we handle.
+var supportedWebhookEvents = map[string]bool{
+	"issues":              true,
+	"issue_comment":       true,
+	"pull_request":        true,
+	"pull_request_review": true,
+	"label":               true,
+	"push":                true,
+	"release":             true,
+}
+
+// HandleEvent applies the given (already validated) webhook event to the
+// local store.
+func (h *WebhookHandler) HandleEvent(eventType string, payload []byte) error {
+	if !supportedWebhookEvents[eventType] {
+		h.log.Debug("Ignoring unsupported webhook event", "event", eventType)
+		return nil
+	}
+	event, err := github.ParseWebHook(eventType, payload)
+	if err != nil {
+		return &errBadWebhookPayload{err: err}
+	}
+	switch e := event.(type) {
+	case *github.IssuesEvent:
+		return h.handleIssuesEvent(e)
+	case *github.IssueCommentEvent:
+		return h.handleIssueCommentEvent(e)
+	case *github.PullRequestEvent:
+		return h.handlePullRequestEvent(e)
+	case *github.PullRequestReviewEvent:
+		return h.handlePullRequestReviewEvent(e)
+	case *github.LabelEvent:
+		return h.handleLabelEvent(e)
+	case *github.PushEvent:
+		return h.handlePushEvent(e)
+	case *github.ReleaseEvent:
+		return h.handleReleaseEvent(e)
+	}
+	return nil
+}
+
+// webhookTarget is the local copy of the repository to which a webhook event
+// pertains.
+type webhookTarget struct {
+	rootPath  string
+	local     *LocalRepository
+	repo      *Repository
+	resources resourceSet
+}
+
+// target finds the repository in the collection to which the event with the
+// given repository full name ("owner/name") and HTML URL pertains. The host
+// from the HTML URL must either be one of the collection's hosts, or the host
+// of repositories without a specific host (github.com, or the host of the
+// collection's base URL). Returns nil if the repository is not part of the
+// collection.
+func (h *WebhookHandler) target(fullName, htmlURL string) *webhookTarget {
+	u, err := url.Parse(htmlURL)
+	if err != nil || len(u.Hostname()) == 0 {
+		h.log.Debug("Ignoring webhook event without valid repository URL", "repo", fullName, "url", htmlURL)
+		return nil
+	}
+	var local *LocalRepository
+	host := u.Hostname()
+	if _, exists := h.coll.Hosts[host]; exists {
+		local = h.coll.findRepository(host + "/" + fullName)
+	} else if strings.EqualFold(host, h.defaultHost()) {
+		local = h.coll.findRepository(fullName)
+	}
+	if local == nil {
+		h.log.Debug("Ignoring webhook event for repository not in collection", "host", host, "repo", fullName)
+		return nil
+	}
+	return &webhookTarget{
+		rootPath: h.coll.repoRootPath(local),
+		local:    local,
+		repo: &Repository{
+			Repository: &github.Repository{
+				Owner: &github.User{Login: github.String(local.Owner)},
+				Name:  github.String(local.Name),
+			},
+		},
+		resources: selectResources(&local.ResourceSelection),
+	}
+}
+
+// defaultHost returns the host of repositories in the collection that are not
+// explicitly hosted on one of its hosts.
+func (h *WebhookHandler) defaultHost() string {
+	if len(h.coll.BaseURL) > 0 {
+		if u, err := url.Parse(h.coll.BaseURL); err == nil {
+			return u.Hostname()
+		}
+	}
+	return DEFAULT_GITHUB_HOST
+}
+
+// lockRepository acquires the lock serializing the application of events to
+// the repository with the given path, returning a function that releases it.
+func (h *WebhookHandler) lockRepository(repo string) func() {
+	h.mtx.Lock()
+	lock, exists := h.repoLocks[repo]
+	if !exists {
+		lock = &sync.Mutex{}
+		h.repoLocks[repo] = lock
+	}
+	h.mtx.Unlock()
+	lock.Lock()
+	return lock.Unlock
+}
+
+func isDeletion(action string) bool {
+	return action == "deleted"
+}
+
+// isOutdated returns whether an item from a webhook payload, last updated at
+// the given time, is older than the locally stored version of the item.
+func isOutdated(stored, latest time.Time) bool {
+	return !stored.IsZero() && !latest.IsZero() && latest.Before(stored)
+}
+
+func (h *WebhookHandler) handleIssuesEvent(e *github.IssuesEvent) error {
+	t := h.target(e.GetRepo().GetFullName(), e.GetRepo().GetHTMLURL())
+	if t == nil || !t.resources[RESOURCE_ISSUES] {
+		return nil
+	}
+	defer h.lockRepository(t.local.String())()
+	issue, err := LoadIssue(t.rootPath, t.repo, e.GetIssue().GetNumber(), false)
+	if err != nil {
+		return err
+	}
+	if issue.Deleted || isOutdated(issue.Issue.GetUpdatedAt(), e.GetIssue().GetUpdatedAt()) {
+		h.log.Debug("Ignoring outdated issue event", "repo", t.local.String(), "issue", e.GetIssue().GetNumber(), "action", e.GetAction())
+		return nil
+	}
+	if isDeletion(e.GetAction()) {
+		if issue.Issue == nil {
+			return nil
+		}
+		issue.Deleted = true
+	}
+	issue.Issue = e.GetIssue()
+	if err := issue.Save(t.rootPath, t.repo, h.cfg.PrettyJSON); err != nil {
+		return err
+	}
+	h.log.Info("Applied issue event", "repo", t.local.String(), "issue", issue.GetNumber(), "action", e.GetAction())
+	return nil
+}
+
+func (h *WebhookHandler) handleIssueCommentEvent(e *github.IssueCommentEvent) error {
+	t := h.target(e.GetRepo().GetFullName(), e.GetRepo().GetHTMLURL())
+	if t == nil || !t.resources[RESOURCE_ISSUES] {
+		return nil
+	}
+	// We only keep track of comments on issues, and not of the top-level
+	// comments on pull requests.
+	if e.GetIssue().IsPullRequest() {
+		h.log.Debug("Ignoring comment on pull request", "repo", t.local.String(), "pr", e.GetIssue().GetNumber())
+		return nil
+	}
+	defer h.lockRepository(t.local.String())()
+	comment, err := LoadIssueComment(t.rootPath, t.repo, e.GetIssue().GetNumber(), e.GetComment().GetID(), false)
+	if err != nil {
+		return err
+	}
+	if comment.Deleted || isOutdated(comment.Comment.GetUpdatedAt(), e.GetComment().GetUpdatedAt()) {
+		h.log.Debug("Ignoring outdated issue comment event", "repo", t.local.String(), "comment", e.GetComment().GetID(), "action", e.GetAction())
+		return nil
+	}
+	if isDeletion(e.GetAction()) {
+		if comment.Comment == nil {
+			return nil
+		}
+		comment.Deleted = true
+	}
+	comment.Comment = e.GetComment()
+	if err := comment.Save(t.rootPath, t.repo, e.GetIssue().GetNumber(), h.cfg.PrettyJSON); err != nil {
+		return err
+	}
+	h.log.Info("Applied issue comment event", "repo", t.local.String(), "issue", e.GetIssue().GetNumber(), "action", e.GetAction())
+	return nil
+}
+
+func (h *WebhookHandler) handlePullRequestEvent(e *github.PullRequestEvent) error {
+	t := h.target(e.GetRepo().GetFullName(), e.GetRepo().GetHTMLURL())
+	if t == nil || !t.resources[RESOURCE_PULL_REQUESTS] {
+		return nil
+	}
+	defer h.lockRepository(t.local.String())()
+	pr, err := LoadPullRequest(t.rootPath, t.repo, e.GetPullRequest().GetNumber(), false)
+	if err != nil {
+		return err
+	}
+	if isOutdated(pr.PullRequest.GetUpdatedAt(), e.GetPullRequest().GetUpdatedAt()) {
+		h.log.Debug("Ignoring outdated pull request event", "repo", t.local.String(), "pr", e.GetPullRequest().GetNumber(), "action", e.GetAction())
+		return nil
+	}
+	pr.PullRequest = e.GetPullRequest()
+	if err := pr.Save(t.rootPath, t.repo, h.cfg.PrettyJSON); err != nil {
+		return err
+	}
+	h.log.Info("Applied pull request event", "repo", t.local.String(), "pr", pr.GetNumber(), "action", e.GetAction())
+	return nil
+}
+
+func (h *WebhookHandler) handlePullRequestReviewEvent(e *github.PullRequestReviewEvent) error {
+	t := h.target(e.GetRepo().GetFullName(), e.GetRepo().GetHTMLURL())
+	if t == nil || !t.resources[RESOURCE_PULL_REQUESTS] {
+		return nil
+	}
+	defer h.lockRepository(t.local.String())()
+	prNum := e.GetPullRequest().GetNumber()
+	review, err := LoadPullRequestReview(t.rootPath, t.repo, prNum, e.GetReview().GetID(), false)
+	if err != nil {
+		return err
+	}
+	if isOutdated(review.Review.GetSubmittedAt(), e.GetReview().GetSubmittedAt()) {
+		h.log.Debug("Ignoring outdated pull request review event", "repo", t.local.String(), "pr", prNum, "review", e.GetReview().GetID(), "action", e.GetAction())
+		return nil
+	}
+	review.Review = e.GetReview()
+	if err := review.Save(t.rootPath, t.repo, h.cfg.PrettyJSON); err != nil {
+		return err
+	}
+	h.log.Info("Applied pull request review event", "repo", t.local.String(), "pr", prNum, "review", e.GetReview().GetID(), "action", e.GetAction())
+	return nil
+}
+
+func (h *WebhookHandler) handleLabelEvent(e *github.LabelEvent) error {
+	t := h.target(e.GetRepo().GetFullName(), e.GetRepo().GetHTMLURL())
+	if t == nil || !t.resources[RESOURCE_LABELS] {
+		return nil
+	}
+	defer h.lockRepository(t.local.String())()
+	label, err := LoadLabel(t.rootPath, t.repo, e.GetLabel().GetID(), false)
+	if err != nil {
+		return err
+	}
+	// Labels do not record when they were last updated, so we can only avoid
+	// resurrecting deleted labels.
+	if label.Deleted {
+		h.log.Debug("Ignoring event for deleted label", "repo", t.local.String(), "label", e.GetLabel().GetName(), "action", e.GetAction())
+		return nil
+	}
+	if isDeletion(e.GetAction()) {
+		if label.Label == nil {
+			return nil
+		}
+		label.Deleted = true
+	}
+	label.Label = e.GetLabel()
+	if err := label.Save(t.rootPath, t.repo, h.cfg.PrettyJSON); err != nil {
+		return err
+	}
+	h.log.Info("Applied label event", "repo", t.local.String(), "label", e.GetLabel().GetName(), "action", e.GetAction())
+	return nil
+}
+
+func (h *WebhookHandler) handlePushEvent(e *github.PushEvent) error {
+	t := h.target(e.GetRepo().GetFullName(), e.GetRepo().GetHTMLURL())
+	if t == nil || !t.resources[RESOURCE_CODE] {
+		return nil
+	}
+	h.enqueueRefetch(t.local.String(), RESOURCE_CODE)
+	h.log.Info("Enqueued code refetch as a result of push", "repo", t.local.String(), "ref", e.GetRef())
+	return nil
+}
+
+func (h *WebhookHandler) handleReleaseEvent(e *github.ReleaseEvent) error {
+	t := h.target(e.GetRepo().GetFullName(), e.GetRepo().GetHTMLURL())
+	if t == nil || !t.resources[RESOURCE_RELEASES] {
+		return nil
+	}
+	defer h.lockRepository(t.local.String())()
+	release, err := LoadRelease(t.rootPath, t.repo, e.GetRelease().GetID(), false)
+	if err != nil {
+		return err
+	}
+	// As with labels, releases do not record when they were last updated.
+	if release.Deleted {
+		h.log.Debug("Ignoring event for deleted release", "repo", t.local.String(), "release", e.GetRelease().GetTagName(), "action", e.GetAction())
+		return nil
+	}
+	if isDeletion(e.GetAction()) {
+		if release.Release == nil {
+			return nil
+		}
+		release.Deleted = true
+	}
+	release.Release = e.GetRelease()
+	if err := release.Save(t.rootPath, t.repo, h.cfg.PrettyJSON); err != nil {
+		return err
+	}
+	h.log.Info("Applied release event", "repo", t.local.String(), "release", e.GetRelease().GetTagName(), "action", e.GetAction())
+	return nil
+}
+
+func (h *WebhookHandler) enqueueRefetch(repo string, resources ...string) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	if _, exists := h.pending[repo]; !exists {
+		h.pending[repo] = make(resourceSet)
+	}
+	for _, r := range resources {
+		h.pending[repo][r] = true
+	}
+	select {
+	case h.notify <- struct{}{}:
+	default:
+	}
+}
+
+// PendingRefetches returns the resources that are waiting to be refetched,
+// keyed by repository path.
+func (h *WebhookHandler) PendingRefetches() map[string][]string {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	pending := make(map[string][]string, len(h.pending))
+	for repo, resources := range h.pending {
+		pending[repo] = sortedKeys(resources)
+	}
+	return pending
+}
+
+// Refetch performs the targeted refetches enqueued as a result of webhook
+// events, using the given fetch configuration, until the given context is
+// cancelled. Refetches of the same repository that are enqueued while it is
+// being refetched are coalesced.
+func (h *WebhookHandler) Refetch(ctx context.Context, cfg *FetchConfig) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-h.notify:
+		}
+		h.mtx.Lock()
+		pending := h.pending
+		h.pending = make(map[string]resourceSet)
+		h.mtx.Unlock()
+
+		repos := make([]string, 0, len(pending))
+		for repo := range pending {
+			repos = append(repos, repo)
+		}
+		sort.Strings(repos)
+		for _, repo := range repos {
+			repoCfg := *cfg
+			repoCfg.Repositories = []string{repo}
+			repoCfg.Resources = &ResourceSelection{Only: sortedKeys(pending[repo])}
+			h.log.Info("Refetching repository resources", "repo", repo, "resources", repoCfg.Resources.Only)
+			if _, err := h.coll.Fetch(ctx, &repoCfg, h.log); err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				h.log.Error("Failed to refetch repository resources", "repo", repo, "err", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package ghere_test
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-github/v48/github"
+	"github.com/informalsystems/ghere/pkg/ghere"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookHandler(t *testing.T) {
+	log := ghere.NewNoopLogger()
+	tmpDir := t.TempDir()
+	coll, err := ghere.LoadOrCreateLocalCollection(filepath.Join(tmpDir, ghere.CONFIG_FILE_NAME))
+	require.NoError(t, err)
+	_, err = coll.NewFromPath("org/repo")
+	require.NoError(t, err)
+
+	secret := []byte("s3cr3t")
+	handler := ghere.NewWebhookHandler(coll, &ghere.WebhookConfig{Secret: secret}, log)
+	readPayload := func(payloadFile string) []byte {
+		payload, err := os.ReadFile(filepath.Join("testdata", "webhooks", payloadFile))
+		require.NoError(t, err)
+		return payload
+	}
+	sendPayload := func(eventType string, payload []byte, secret []byte) int {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(payload)
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-GitHub-Event", eventType)
+		req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+	send := func(eventType, payloadFile string, secret []byte) int {
+		return sendPayload(eventType, readPayload(payloadFile), secret)
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, send("issues", "issues.json", []byte("wrong")))
+	assert.NoFileExists(t, filepath.Join(tmpDir, "org", "repo", "issues", "000012", ghere.DETAIL_FILENAME))
+
+	testCases := []struct {
+		eventType   string
+		payloadFile string
+		expectFile  string
+	}{
+		{"issues", "issues.json", "issues/000012/detail.json"},
+		{"issue_comment", "issue_comment.json", "issues/000012/comments/5001.json"},
+		{"pull_request", "pull_request.json", "pull-requests/000013/detail.json"},
+		{"pull_request_review", "pull_request_review.json", "pull-requests/000013/3001/detail.json"},
+		{"label", "label.json", "labels/208045947.json"},
+		{"release", "release.json", "releases/4001.json"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.eventType, func(t *testing.T) {
+			require.Equal(t, http.StatusNoContent, send(tc.eventType, tc.payloadFile, secret))
+			assert.FileExists(t, filepath.Join(tmpDir, "org", "repo", filepath.FromSlash(tc.expectFile)))
+		})
+	}
+
+	issue, err := ghere.LoadIssueDirect(filepath.Join(tmpDir, "org", "repo", "issues", "000012", ghere.DETAIL_FILENAME), true)
+	require.NoError(t, err)
+	assert.Equal(t, "closed", issue.Issue.GetState())
+
+	// Events delivered out of order must not overwrite newer data.
+	reopened := bytes.Replace(readPayload("issues.json"), []byte(`"action": "closed"`), []byte(`"action": "reopened"`), 1)
+	reopened = bytes.Replace(reopened, []byte(`"state": "closed"`), []byte(`"state": "open"`), 1)
+	reopened = bytes.Replace(reopened, []byte(`"updated_at": "2022-12-01T09:30:00Z"`), []byte(`"updated_at": "2022-12-02T08:00:00Z"`), 1)
+	require.Equal(t, http.StatusNoContent, sendPayload("issues", reopened, secret))
+	require.Equal(t, http.StatusNoContent, send("issues", "issues.json", secret))
+	issue, err = ghere.LoadIssueDirect(filepath.Join(tmpDir, "org", "repo", "issues", "000012", ghere.DETAIL_FILENAME), true)
+	require.NoError(t, err)
+	assert.Equal(t, "open", issue.Issue.GetState())
+	assert.True(t, issue.LastDetailFetch.IsZero(), "webhook events must not count as detail fetches")
+
+	// Events must not discard previously fetched reactions.
+	repoPath := filepath.Join(tmpDir, "org", "repo")
+	reactions := []*github.Reaction{{ID: github.Int64(1), Content: github.String("+1")}}
+	comment, err := ghere.LoadIssueCommentDirect(filepath.Join(repoPath, "issues", "000012", "comments", "5001.json"), true)
+	require.NoError(t, err)
+	comment.Reactions = reactions
+	require.NoError(t, ghere.WriteJSONFile(filepath.Join(repoPath, "issues", "000012", "comments", "5001.json"), comment))
+	release, err := ghere.LoadReleaseDirect(filepath.Join(repoPath, "releases", "4001.json"), true)
+	require.NoError(t, err)
+	release.Reactions = reactions
+	require.NoError(t, ghere.WriteJSONFile(filepath.Join(repoPath, "releases", "4001.json"), release))
+	require.Equal(t, http.StatusNoContent, send("issue_comment", "issue_comment.json", secret))
+	require.Equal(t, http.StatusNoContent, send("release", "release.json", secret))
+	comment, err = ghere.LoadIssueCommentDirect(filepath.Join(repoPath, "issues", "000012", "comments", "5001.json"), true)
+	require.NoError(t, err)
+	assert.Equal(t, reactions, comment.Reactions)
+	release, err = ghere.LoadReleaseDirect(filepath.Join(repoPath, "releases", "4001.json"), true)
+	require.NoError(t, err)
+	assert.Equal(t, reactions, release.Reactions)
+
+	// Comments on pull requests are not tracked.
+	require.Equal(t, http.StatusNoContent, send("issue_comment", "issue_comment_pr.json", secret))
+	assert.NoDirExists(t, filepath.Join(tmpDir, "org", "repo", "issues", "000013"))
+
+	// Deleted comments and labels must be marked as such.
+	for _, tc := range []struct {
+		eventType   string
+		payloadFile string
+		path        string
+	}{
+		{"issue_comment", "issue_comment.json", "issues/000012/comments/5001.json"},
+		{"label", "label.json", "labels/208045947.json"},
+	} {
+		payload := bytes.Replace(readPayload(tc.payloadFile), []byte(`"action": "created"`), []byte(`"action": "deleted"`), 1)
+		require.Equal(t, http.StatusNoContent, sendPayload(tc.eventType, payload, secret))
+		var item struct {
+			Deleted bool `json:"deleted"`
+		}
+		require.NoError(t, ghere.ReadJSONFile(filepath.Join(repoPath, filepath.FromSlash(tc.path)), &item))
+		assert.True(t, item.Deleted, tc.eventType)
+	}
+
+	// Events from other hosts must not be applied to repositories on
+	// github.com with the same name.
+	ghesPayload := bytes.ReplaceAll(readPayload("issues.json"), []byte("https://github.com/"), []byte("https://github.example.com/"))
+	ghesPayload = bytes.Replace(ghesPayload, []byte(`"number": 12`), []byte(`"number": 14`), 1)
+	require.Equal(t, http.StatusNoContent, sendPayload("issues", ghesPayload, secret))
+	assert.NoDirExists(t, filepath.Join(repoPath, "issues", "000014"))
+
+	// Pushes result in targeted refetches of the repository's code.
+	require.Equal(t, http.StatusNoContent, send("push", "push.json", secret))
+	assert.Equal(t, map[string][]string{"org/repo": {ghere.RESOURCE_CODE}}, handler.PendingRefetches())
+}
+
+func TestWebhookHandlerSkipsUnselectedResources(t *testing.T) {
+	log := ghere.NewNoopLogger()
+	tmpDir := t.TempDir()
+	coll, err := ghere.LoadOrCreateLocalCollection(filepath.Join(tmpDir, ghere.CONFIG_FILE_NAME))
+	require.NoError(t, err)
+	repo, err := coll.NewFromPath("org/repo")
+	require.NoError(t, err)
+	repo.Skip = []string{ghere.RESOURCE_RELEASES}
+
+	handler := ghere.NewWebhookHandler(coll, &ghere.WebhookConfig{}, log)
+	payload, err := os.ReadFile(filepath.Join("testdata", "webhooks", "release.json"))
+	require.NoError(t, err)
+	require.NoError(t, handler.HandleEvent("release", payload))
+	assert.NoDirExists(t, filepath.Join(tmpDir, "org", "repo", "releases"))
+}